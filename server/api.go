@@ -1,12 +1,14 @@
 package main
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -16,6 +18,7 @@ import (
 const (
 	// API V1
 	routeAPISettings = "/api/v1/settings"
+	routeAPIAudit    = "/api/v1/audit"
 
 	routeProfileImage = "/profile.png"
 )
@@ -39,10 +42,27 @@ func (p *Plugin) serveHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Req
 	switch path := r.URL.Path; path {
 	case routeAPISettings:
 		return p.handleRouteAPISettings(w, r)
+	case routeAPIAudit:
+		return p.handleRouteAPIAudit(w, r)
+	case routeAPIEligibleChannels:
+		return p.handleRouteAPIEligibleChannels(w, r)
+	case routeAPIDialogSubmit:
+		return p.handleRouteAPIDialogSubmit(w, r)
+	case routeAPIAutocompleteChannels:
+		return p.handleRouteAPIAutocompleteChannels(w, r)
+	case routeAPIAutocompleteMessages:
+		return p.handleRouteAPIAutocompleteMessages(w, r)
 	case routeProfileImage:
 		return p.handleProfileImage(w, r)
 	}
 
+	if route := p.getWranglerAPIRoute(r.URL.Path); route != nil {
+		return p.handleRouteAPIWrangler(w, r, route)
+	}
+	if postID := getThreadPreviewPostID(r.URL.Path); len(postID) != 0 {
+		return p.handleRouteAPIThreadPreview(w, r, postID)
+	}
+
 	return respondErr(w, http.StatusNotFound, errors.New("not found"))
 }
 
@@ -74,6 +94,91 @@ func (p *Plugin) handleRouteAPISettings(w http.ResponseWriter, r *http.Request)
 	)
 }
 
+func (p *Plugin) handleRouteAPIAudit(w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodGet {
+		return respondErr(w, http.StatusMethodNotAllowed,
+			errors.Errorf("method %s is not allowed, must be GET", r.Method))
+	}
+
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		return respondErr(w, http.StatusUnauthorized, errors.New("not authorized"))
+	}
+	if !p.authorizedPluginUser(mattermostUserID) {
+		return respondErr(w, http.StatusForbidden, errors.New("forbidden"))
+	}
+	user, appErr := p.API.GetUser(mattermostUserID)
+	if appErr != nil || !user.IsSystemAdmin() {
+		return respondErr(w, http.StatusForbidden, errors.New("forbidden"))
+	}
+
+	query := r.URL.Query()
+	since, err := parseAuditSince(query.Get("since"), time.Now())
+	if err != nil {
+		return respondErr(w, http.StatusBadRequest, err)
+	}
+
+	records, err := p.getAuditRecords(auditFilter{
+		UserID:    query.Get("user_id"),
+		ChannelID: query.Get("channel_id"),
+		Since:     since,
+	})
+	if err != nil {
+		return respondErr(w, http.StatusInternalServerError, errors.WithMessage(err, "failed to get audit records"))
+	}
+
+	switch format := query.Get("format"); format {
+	case "", "json":
+		if records == nil {
+			records = []*AuditRecord{}
+		}
+		return respondJSON(w, records)
+	case "csv":
+		return respondAuditCSV(w, records)
+	default:
+		return respondErr(w, http.StatusBadRequest, errors.Errorf("format %s is not supported, must be json or csv", format))
+	}
+}
+
+func respondAuditCSV(w http.ResponseWriter, records []*AuditRecord) (int, error) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="wrangler-audit.csv"`)
+
+	writer := csv.NewWriter(w)
+	rows := [][]string{{
+		"id", "create_at", "user_id", "command", "category", "args", "channel_id", "team_id",
+		"source_post_id", "source_channel_id", "target_post_id", "target_channel_id",
+		"message_count", "job_id", "result", "error",
+	}}
+	for _, record := range records {
+		rows = append(rows, []string{
+			record.ID,
+			time.Unix(0, record.CreateAt*int64(time.Millisecond)).UTC().Format(time.RFC3339),
+			record.UserID,
+			record.Command,
+			record.Category,
+			record.Args,
+			record.ChannelID,
+			record.TeamID,
+			record.SourcePostID,
+			record.SourceChannelID,
+			record.TargetPostID,
+			record.TargetChannelID,
+			strconv.Itoa(record.MessageCount),
+			record.JobID,
+			record.Result,
+			record.Error,
+		})
+	}
+
+	err := writer.WriteAll(rows)
+	if err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+
+	return http.StatusOK, nil
+}
+
 func (p *Plugin) handleProfileImage(w http.ResponseWriter, r *http.Request) (int, error) {
 	bundlePath, err := p.API.GetBundlePath()
 	if err != nil {
@@ -100,18 +205,23 @@ func respondErr(w http.ResponseWriter, code int, err error) (int, error) {
 }
 
 func respondJSON(w http.ResponseWriter, obj interface{}) (int, error) {
+	return respondJSONWithStatus(w, http.StatusOK, obj)
+}
+
+func respondJSONWithStatus(w http.ResponseWriter, code int, obj interface{}) (int, error) {
 	data, err := json.Marshal(obj)
 	if err != nil {
 		return respondErr(w, http.StatusInternalServerError, errors.WithMessage(err, "failed to marshal response"))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
 	_, err = w.Write(data)
 	if err != nil {
 		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
 	}
 
-	return http.StatusOK, nil
+	return code, nil
 }
 
 func decodeJSON(obj interface{}, body io.ReadCloser) error {
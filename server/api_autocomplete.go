@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	routeAPIAutocompleteChannels = "/api/v1/autocomplete/channels"
+	routeAPIAutocompleteMessages = "/api/v1/autocomplete/messages"
+
+	maxAutocompleteSuggestions    = 25
+	autocompleteMessageCount      = 20
+	autocompleteMessageTrimLength = 50
+)
+
+// getAutocompleteInput returns the argument that is being typed from the
+// query of a dynamic autocomplete request.
+func getAutocompleteInput(r *http.Request) string {
+	query := r.URL.Query()
+
+	return strings.TrimSpace(strings.TrimPrefix(query.Get("user_input"), query.Get("parsed")))
+}
+
+// handleRouteAPIAutocompleteChannels suggests the channels that the user has
+// joined across all teams. Channels are suggested as ~channel-name unless
+// the name is used in more than one of the user's teams, in which case they
+// are suggested as team-name:channel-name.
+func (p *Plugin) handleRouteAPIAutocompleteChannels(w http.ResponseWriter, r *http.Request) (int, error) {
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		return respondErr(w, http.StatusUnauthorized, errors.New("not authorized"))
+	}
+	if !p.authorizedPluginUser(mattermostUserID) {
+		return respondJSON(w, []model.AutocompleteListItem{})
+	}
+
+	input := strings.ToLower(getAutocompleteInput(r))
+
+	teams, appErr := p.API.GetTeamsForUser(mattermostUserID)
+	if appErr != nil {
+		return respondErr(w, http.StatusInternalServerError, errors.Wrap(appErr, "unable to get teams for user"))
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		return teams[i].DisplayName < teams[j].DisplayName
+	})
+
+	type teamChannel struct {
+		team    *model.Team
+		channel *model.Channel
+	}
+	var channels []teamChannel
+	nameCounts := make(map[string]int)
+	for _, team := range teams {
+		teamChannels, appErr := p.API.GetChannelsForTeamForUser(team.Id, mattermostUserID, false)
+		if appErr != nil {
+			return respondErr(w, http.StatusInternalServerError, errors.Wrapf(appErr, "unable to get channels for team %s", team.Id))
+		}
+		sort.Slice(teamChannels, func(i, j int) bool {
+			return teamChannels[i].DisplayName < teamChannels[j].DisplayName
+		})
+
+		for _, channel := range teamChannels {
+			if channel.IsGroupOrDirect() {
+				continue
+			}
+			channels = append(channels, teamChannel{team: team, channel: channel})
+			nameCounts[channel.Name]++
+		}
+	}
+
+	items := []model.AutocompleteListItem{}
+	for _, tc := range channels {
+		item := "~" + tc.channel.Name
+		if nameCounts[tc.channel.Name] > 1 {
+			item = fmt.Sprintf("%s:%s", tc.team.Name, tc.channel.Name)
+		}
+		if !strings.HasPrefix(item, input) {
+			continue
+		}
+
+		items = append(items, model.AutocompleteListItem{
+			Item:     item,
+			Hint:     tc.channel.DisplayName,
+			HelpText: tc.team.DisplayName,
+		})
+		if len(items) == maxAutocompleteSuggestions {
+			break
+		}
+	}
+
+	return respondJSON(w, items)
+}
+
+// handleRouteAPIAutocompleteMessages suggests the recent messages of the
+// channel the command is being typed in. Messages are matched by the start of
+// their ID or by the text they contain, ignoring case.
+func (p *Plugin) handleRouteAPIAutocompleteMessages(w http.ResponseWriter, r *http.Request) (int, error) {
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		return respondErr(w, http.StatusUnauthorized, errors.New("not authorized"))
+	}
+	if !p.authorizedPluginUser(mattermostUserID) {
+		return respondJSON(w, []model.AutocompleteListItem{})
+	}
+
+	channelID := r.URL.Query().Get("channel_id")
+	_, appErr := p.API.GetChannelMember(channelID, mattermostUserID)
+	if appErr != nil {
+		return respondErr(w, http.StatusForbidden, errors.New("forbidden"))
+	}
+
+	input := getAutocompleteInput(r)
+	search := strings.ToLower(input)
+
+	channelPosts, appErr := p.API.GetPostsForChannel(channelID, 0, autocompleteMessageCount)
+	if appErr != nil {
+		return respondErr(w, http.StatusInternalServerError, errors.Wrapf(appErr, "unable to get posts for channel %s", channelID))
+	}
+
+	items := []model.AutocompleteListItem{}
+	for _, post := range channelPosts.ToSlice() {
+		if post.IsSystemMessage() {
+			continue
+		}
+		if !strings.HasPrefix(post.Id, input) && !strings.Contains(strings.ToLower(post.Message), search) {
+			continue
+		}
+
+		hint := "message"
+		if len(post.RootId) != 0 {
+			hint = "reply"
+		}
+		items = append(items, model.AutocompleteListItem{
+			Item:     post.Id,
+			Hint:     fmt.Sprintf("%s by @%s", hint, p.getUsernameOrID(post.UserId)),
+			HelpText: cleanAndTrimMessage(post.Message, autocompleteMessageTrimLength),
+		})
+	}
+
+	return respondJSON(w, items)
+}
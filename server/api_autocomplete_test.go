@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAutocompleteData(t *testing.T) {
+	require.NoError(t, getAutocompleteData(true).IsValid())
+	require.NoError(t, getAutocompleteData(false).IsValid())
+}
+
+func TestAutocompleteAPI(t *testing.T) {
+	team1 := &model.Team{
+		Id:          model.NewId(),
+		Name:        "team-1",
+		DisplayName: "Team 1",
+	}
+	channel1 := &model.Channel{
+		Id:          model.NewId(),
+		TeamId:      team1.Id,
+		Name:        "town-square",
+		DisplayName: "Town Square",
+		Type:        model.CHANNEL_OPEN,
+	}
+	channel2 := &model.Channel{
+		Id:          model.NewId(),
+		TeamId:      team1.Id,
+		Name:        "off-topic",
+		DisplayName: "Off-Topic",
+		Type:        model.CHANNEL_OPEN,
+	}
+	team2 := &model.Team{
+		Id:          model.NewId(),
+		Name:        "team-2",
+		DisplayName: "Team 2",
+	}
+	channel3 := &model.Channel{
+		Id:          model.NewId(),
+		TeamId:      team2.Id,
+		Name:        "town-square",
+		DisplayName: "Town Square",
+		Type:        model.CHANNEL_OPEN,
+	}
+	direct := &model.Channel{
+		Id:   model.NewId(),
+		Name: "direct",
+		Type: model.CHANNEL_DIRECT,
+	}
+	user := &model.User{
+		Id:       model.NewId(),
+		Username: "user",
+	}
+	nonMember := &model.User{
+		Id:       model.NewId(),
+		Username: "non-member",
+	}
+
+	postList := mockGeneratePostList(3, channel1.Id, false)
+	for _, post := range postList.Posts {
+		post.UserId = user.Id
+	}
+	systemPost := &model.Post{
+		Id:        model.NewId(),
+		ChannelId: channel1.Id,
+		Type:      model.POST_SYSTEM_MESSAGE_PREFIX + "join_channel",
+	}
+	postList.AddPost(systemPost)
+	postList.AddOrder(systemPost.Id)
+
+	api := &plugintest.API{}
+	api.On("GetUser", user.Id).Return(user, nil)
+	api.On("GetUser", nonMember.Id).Return(nonMember, nil)
+	api.On("GetTeamsForUser", user.Id).Return([]*model.Team{team2, team1}, nil)
+	api.On("GetChannelsForTeamForUser", team1.Id, user.Id, false).Return([]*model.Channel{channel1, channel2, direct}, nil)
+	api.On("GetChannelsForTeamForUser", team2.Id, user.Id, false).Return([]*model.Channel{channel3}, nil)
+	api.On("GetChannelMember", channel1.Id, user.Id).Return(&model.ChannelMember{}, nil)
+	api.On("GetChannelMember", channel1.Id, nonMember.Id).Return(nil, model.NewAppError("where", model.NewId(), nil, "not found", 0))
+	api.On("GetPostsForChannel", channel1.Id, 0, autocompleteMessageCount).Return(postList, nil)
+	api.On("LogError",
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+	).Return(nil)
+
+	var p Plugin
+	p.SetAPI(api)
+	p.setConfiguration(&configuration{
+		PermittedWranglerUsers: permittedUserAllUsers,
+	})
+
+	request := func(route, userID string, query url.Values) (*httptest.ResponseRecorder, []model.AutocompleteListItem) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, route+"?"+query.Encode(), nil)
+		r.Header.Set("Mattermost-User-Id", userID)
+		p.ServeHTTP(&plugin.Context{}, w, r)
+
+		var items []model.AutocompleteListItem
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+		}
+		return w, items
+	}
+
+	t.Run("channels", func(t *testing.T) {
+		w, items := request(routeAPIAutocompleteChannels, user.Id, url.Values{
+			"user_input": {"wrangler move thread abc "},
+			"parsed":     {"wrangler move thread abc "},
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, items, 3)
+		assert.Equal(t, "~off-topic", items[0].Item)
+		assert.Equal(t, "Off-Topic", items[0].Hint)
+		assert.Equal(t, "Team 1", items[0].HelpText)
+		assert.Equal(t, "team-1:town-square", items[1].Item)
+		assert.Equal(t, "team-2:town-square", items[2].Item)
+	})
+
+	t.Run("channels filtered by the typed prefix", func(t *testing.T) {
+		w, items := request(routeAPIAutocompleteChannels, user.Id, url.Values{
+			"user_input": {"wrangler move thread abc ~Off"},
+			"parsed":     {"wrangler move thread abc "},
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, items, 1)
+		assert.Equal(t, "~off-topic", items[0].Item)
+	})
+
+	t.Run("messages", func(t *testing.T) {
+		w, items := request(routeAPIAutocompleteMessages, user.Id, url.Values{
+			"channel_id": {channel1.Id},
+			"user_input": {"wrangler move thread "},
+			"parsed":     {"wrangler move thread "},
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, items, 3)
+		for _, item := range items {
+			assert.Equal(t, "message by @user", item.Hint)
+			assert.Contains(t, item.HelpText, "This is message")
+		}
+	})
+
+	t.Run("messages filtered by the typed text", func(t *testing.T) {
+		// The system message is never suggested so a regular post is used.
+		post := postList.Posts[postList.Order[0]]
+
+		w, items := request(routeAPIAutocompleteMessages, user.Id, url.Values{
+			"channel_id": {channel1.Id},
+			"user_input": {"wrangler move thread " + post.Id[:10]},
+			"parsed":     {"wrangler move thread "},
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, items, 1)
+		assert.Equal(t, post.Id, items[0].Item)
+
+		w, items = request(routeAPIAutocompleteMessages, user.Id, url.Values{
+			"channel_id": {channel1.Id},
+			"user_input": {"wrangler move thread " + strings.ToUpper(post.Message)},
+			"parsed":     {"wrangler move thread "},
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, items, 1)
+		assert.Equal(t, post.Id, items[0].Item)
+
+		w, items = request(routeAPIAutocompleteMessages, user.Id, url.Values{
+			"channel_id": {channel1.Id},
+			"user_input": {"wrangler move thread nothing like this"},
+			"parsed":     {"wrangler move thread "},
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, items)
+	})
+
+	t.Run("messages in a channel the user is not a member of", func(t *testing.T) {
+		w, _ := request(routeAPIAutocompleteMessages, nonMember.Id, url.Values{
+			"channel_id": {channel1.Id},
+		})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
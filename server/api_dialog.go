@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const routeAPIDialogSubmit = "/api/v1/dialog/submit"
+
+// handleRouteAPIDialogSubmit runs the command of a submitted move or copy
+// dialog. Rejections are shown in the dialog, while the command response is
+// posted to the channel the dialog was opened from like it would be for the
+// slash command.
+func (p *Plugin) handleRouteAPIDialogSubmit(w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return respondErr(w, http.StatusMethodNotAllowed,
+			errors.Errorf("method %s is not allowed, must be POST", r.Method))
+	}
+
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		return respondErr(w, http.StatusUnauthorized, errors.New("not authorized"))
+	}
+
+	var request model.SubmitDialogRequest
+	err := decodeJSON(&request, r.Body)
+	if err != nil {
+		return respondErr(w, http.StatusBadRequest, errors.Wrap(err, "unable to decode dialog submission"))
+	}
+	if request.UserId != mattermostUserID {
+		return respondErr(w, http.StatusForbidden, errors.New("forbidden"))
+	}
+	if request.Cancelled {
+		return http.StatusOK, nil
+	}
+	if !p.authorizedPluginUser(mattermostUserID) {
+		return respondJSON(w, &model.SubmitDialogResponse{
+			Error: "Permission denied. Please talk to your system administrator to get access.",
+		})
+	}
+
+	var state wranglerDialogState
+	err = json.Unmarshal([]byte(request.State), &state)
+	if err != nil {
+		return respondErr(w, http.StatusBadRequest, errors.Wrap(err, "unable to decode dialog state"))
+	}
+	command := p.getWranglerDialogCommand(state.Command)
+	if command == nil {
+		return respondErr(w, http.StatusBadRequest, errors.Errorf("%s can't be run from a dialog", state.Command))
+	}
+
+	args := getDialogCommandArgs(command, request.Submission)
+	if len(args[0]) == 0 {
+		return respondJSON(w, &model.SubmitDialogResponse{
+			Errors: map[string]string{dialogElementPostID: "A message ID or link is required"},
+		})
+	}
+
+	extra := &model.CommandArgs{
+		UserId:    mattermostUserID,
+		ChannelId: request.ChannelId,
+		TeamId:    request.TeamId,
+		SiteURL:   *p.API.GetConfig().ServiceSettings.SiteURL,
+	}
+
+	audit := p.startCommandAudit(command.name, auditCategoryWrangle, args, extra)
+	resp, userError, err := command.handler(args, extra, audit)
+	p.finishCommandAudit(audit, resp, userError, err)
+
+	if err != nil {
+		p.API.LogError(err.Error())
+		if userError {
+			return respondJSON(w, &model.SubmitDialogResponse{Error: fmt.Sprintf("Error: %s", err.Error())})
+		}
+
+		return respondJSON(w, &model.SubmitDialogResponse{
+			Error: "An unknown error occurred. Please talk to your administrator for help.",
+		})
+	}
+	if userError {
+		return respondJSON(w, &model.SubmitDialogResponse{Error: responseText(resp)})
+	}
+
+	if resp != nil && len(resp.Text) != 0 {
+		if resp.ResponseType == model.COMMAND_RESPONSE_TYPE_IN_CHANNEL {
+			_, appErr := p.API.CreatePost(&model.Post{
+				UserId:    p.BotUserID,
+				ChannelId: extra.ChannelId,
+				Message:   resp.Text,
+			})
+			if appErr != nil {
+				p.API.LogError("Unable to post Wrangler summary",
+					"error", appErr.Error(),
+					"channel_id", extra.ChannelId,
+				)
+			}
+		} else {
+			p.API.SendEphemeralPost(extra.UserId, &model.Post{
+				UserId:    p.BotUserID,
+				ChannelId: extra.ChannelId,
+				Message:   resp.Text,
+			})
+		}
+	}
+
+	return respondJSON(w, &model.SubmitDialogResponse{})
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	routeAPIEligibleChannels = "/api/v1/channels/eligible"
+
+	defaultEligibleChannelsPerPage = 60
+	maxEligibleChannelsPerPage     = 200
+)
+
+// eligibleChannel is a channel that a thread can be moved or copied to.
+type eligibleChannel struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	DisplayName     string `json:"display_name"`
+	Type            string `json:"type"`
+	TeamID          string `json:"team_id"`
+	TeamName        string `json:"team_name"`
+	TeamDisplayName string `json:"team_display_name"`
+}
+
+// handleRouteAPIEligibleChannels returns the channels that the thread of the
+// source post can be moved or copied to by the caller. The results can be
+// searched with q, limited to a team with team_id and paginated with page and
+// per_page.
+func (p *Plugin) handleRouteAPIEligibleChannels(w http.ResponseWriter, r *http.Request) (int, error) {
+	if r.Method != http.MethodGet {
+		return respondWranglerAPIErr(w, http.StatusMethodNotAllowed, apiErrorMethodNotAllowed,
+			errors.Errorf("method %s is not allowed, must be GET", r.Method))
+	}
+
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		return respondWranglerAPIErr(w, http.StatusUnauthorized, apiErrorNotAuthorized, errors.New("not authorized"))
+	}
+	if !p.authorizedPluginUser(mattermostUserID) {
+		return respondWranglerAPIErr(w, http.StatusForbidden, apiErrorForbidden, errors.New("you are not permitted to use Wrangler"))
+	}
+
+	query := r.URL.Query()
+	sourcePostID := query.Get("source_post_id")
+	if !model.IsValidId(sourcePostID) {
+		return respondWranglerAPIErr(w, http.StatusBadRequest, apiErrorInvalidRequest, errors.New("source_post_id must be a valid post ID"))
+	}
+	page, err := parsePageQuery(query.Get("page"), 0, -1)
+	if err != nil {
+		return respondWranglerAPIErr(w, http.StatusBadRequest, apiErrorInvalidRequest, errors.Wrap(err, "invalid page"))
+	}
+	perPage, err := parsePageQuery(query.Get("per_page"), defaultEligibleChannelsPerPage, maxEligibleChannelsPerPage)
+	if err != nil {
+		return respondWranglerAPIErr(w, http.StatusBadRequest, apiErrorInvalidRequest, errors.Wrap(err, "invalid per_page"))
+	}
+
+	postListResponse, appErr := p.API.GetPostThread(sourcePostID)
+	if appErr != nil {
+		return respondWranglerAPIErr(w, http.StatusNotFound, apiErrorNotFound, errors.Errorf("unable to find post with ID %s", sourcePostID))
+	}
+	wpl := buildWranglerPostList(postListResponse)
+	if wpl.NumPosts() == 0 {
+		return respondWranglerAPIErr(w, http.StatusNotFound, apiErrorNotFound, errors.Errorf("unable to find post with ID %s", sourcePostID))
+	}
+
+	_, appErr = p.API.GetChannelMember(wpl.RootPost().ChannelId, mattermostUserID)
+	if appErr != nil {
+		return respondWranglerAPIErr(w, http.StatusForbidden, apiErrorForbidden, errors.New("you are not a member of the channel containing the post"))
+	}
+	sourceChannel, appErr := p.API.GetChannel(wpl.RootPost().ChannelId)
+	if appErr != nil {
+		return respondWranglerAPIErr(w, http.StatusInternalServerError, apiErrorInternal, errors.Wrapf(appErr, "unable to get channel with ID %s", wpl.RootPost().ChannelId))
+	}
+
+	channels, err := p.getEligibleChannels(wpl, sourceChannel, mattermostUserID, query.Get("team_id"), query.Get("q"))
+	if err != nil {
+		return respondWranglerAPIErr(w, http.StatusInternalServerError, apiErrorInternal, err)
+	}
+
+	// Pages past the end are empty. They are checked before the page is
+	// multiplied so that large page numbers can't overflow.
+	if perPage == 0 || page > len(channels)/perPage {
+		return respondJSON(w, []*eligibleChannel{})
+	}
+	start := page * perPage
+	end := start + perPage
+	if end > len(channels) {
+		end = len(channels)
+	}
+
+	return respondJSON(w, channels[start:end])
+}
+
+// getEligibleChannels returns the channels in the user's teams that the given
+// thread can be moved or copied to, sorted by team and channel display name.
+func (p *Plugin) getEligibleChannels(wpl *WranglerPostList, sourceChannel *model.Channel, userID, teamID, search string) ([]*eligibleChannel, error) {
+	eligible := []*eligibleChannel{}
+
+	config := p.getConfiguration()
+	if config.MaxThreadCountMoveSizeInt() != 0 && config.MaxThreadCountMoveSizeInt() < wpl.NumPosts() {
+		return eligible, nil
+	}
+
+	teams, appErr := p.API.GetTeamsForUser(userID)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "unable to get teams for user")
+	}
+
+	search = strings.ToLower(search)
+	for _, team := range teams {
+		if len(teamID) != 0 && team.Id != teamID {
+			continue
+		}
+
+		channels, appErr := p.API.GetChannelsForTeamForUser(team.Id, userID, false)
+		if appErr != nil {
+			return nil, errors.Wrapf(appErr, "unable to get channels for team %s", team.Id)
+		}
+
+		for _, channel := range channels {
+			if channel.IsGroupOrDirect() || channel.Id == sourceChannel.Id {
+				continue
+			}
+			if len(search) != 0 &&
+				!strings.Contains(strings.ToLower(channel.Name), search) &&
+				!strings.Contains(strings.ToLower(channel.DisplayName), search) {
+				continue
+			}
+			if len(p.getMovePolicyViolation(sourceChannel, channel)) != 0 {
+				continue
+			}
+			if !p.API.HasPermissionToChannel(userID, channel.Id, model.PERMISSION_CREATE_POST) {
+				continue
+			}
+
+			eligible = append(eligible, &eligibleChannel{
+				ID:              channel.Id,
+				Name:            channel.Name,
+				DisplayName:     channel.DisplayName,
+				Type:            channel.Type,
+				TeamID:          team.Id,
+				TeamName:        team.Name,
+				TeamDisplayName: team.DisplayName,
+			})
+		}
+	}
+
+	sort.SliceStable(eligible, func(i, j int) bool {
+		if eligible[i].TeamDisplayName != eligible[j].TeamDisplayName {
+			return eligible[i].TeamDisplayName < eligible[j].TeamDisplayName
+		}
+		return eligible[i].DisplayName < eligible[j].DisplayName
+	})
+
+	return eligible, nil
+}
+
+// parsePageQuery parses a non-negative pagination query parameter. Values
+// above max are capped unless max is negative.
+func parsePageQuery(value string, defaultValue, max int) (int, error) {
+	if len(value) == 0 {
+		return defaultValue, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 {
+		return 0, errors.Errorf("%d must not be negative", i)
+	}
+	if max >= 0 && i > max {
+		i = max
+	}
+
+	return i, nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEligibleChannelsAPI(t *testing.T) {
+	team1 := &model.Team{
+		Id:          model.NewId(),
+		Name:        "team-1",
+		DisplayName: "Team 1",
+	}
+	team2 := &model.Team{
+		Id:          model.NewId(),
+		Name:        "team-2",
+		DisplayName: "Team 2",
+	}
+	sourceChannel := &model.Channel{
+		Id:          model.NewId(),
+		TeamId:      team1.Id,
+		Name:        "source",
+		DisplayName: "Source",
+		Type:        model.CHANNEL_OPEN,
+	}
+	town := &model.Channel{
+		Id:          model.NewId(),
+		TeamId:      team1.Id,
+		Name:        "town-square",
+		DisplayName: "Town Square",
+		Type:        model.CHANNEL_OPEN,
+	}
+	announcements := &model.Channel{
+		Id:          model.NewId(),
+		TeamId:      team1.Id,
+		Name:        "announcements",
+		DisplayName: "Announcements",
+		Type:        model.CHANNEL_OPEN,
+	}
+	private := &model.Channel{
+		Id:          model.NewId(),
+		TeamId:      team1.Id,
+		Name:        "private",
+		DisplayName: "Private",
+		Type:        model.CHANNEL_PRIVATE,
+	}
+	direct := &model.Channel{
+		Id:   model.NewId(),
+		Name: "direct",
+		Type: model.CHANNEL_DIRECT,
+	}
+	otherTeamChannel := &model.Channel{
+		Id:          model.NewId(),
+		TeamId:      team2.Id,
+		Name:        "other-team",
+		DisplayName: "Other Team",
+		Type:        model.CHANNEL_OPEN,
+	}
+	user := &model.User{
+		Id:       model.NewId(),
+		Username: "user",
+	}
+
+	postList := mockGeneratePostList(3, sourceChannel.Id, false)
+	rootPostID := postList.Order[len(postList.Order)-1]
+
+	api := &plugintest.API{}
+	api.On("GetPostThread", rootPostID).Return(postList, nil)
+	api.On("GetPostThread", mock.AnythingOfType("string")).Return(nil, model.NewAppError("where", model.NewId(), nil, "not found", 0))
+	api.On("GetChannelMember", sourceChannel.Id, user.Id).Return(&model.ChannelMember{}, nil)
+	api.On("GetChannel", sourceChannel.Id).Return(sourceChannel, nil)
+	api.On("GetUser", user.Id).Return(user, nil)
+	api.On("GetTeamsForUser", user.Id).Return([]*model.Team{team2, team1}, nil)
+	api.On("GetChannelsForTeamForUser", team1.Id, user.Id, false).Return([]*model.Channel{sourceChannel, town, announcements, private, direct}, nil)
+	api.On("GetChannelsForTeamForUser", team2.Id, user.Id, false).Return([]*model.Channel{otherTeamChannel}, nil)
+	api.On("HasPermissionToChannel", user.Id, announcements.Id, model.PERMISSION_CREATE_POST).Return(false)
+	api.On("HasPermissionToChannel", user.Id, mock.AnythingOfType("string"), model.PERMISSION_CREATE_POST).Return(true)
+	api.On("LogError",
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+	).Return(nil)
+
+	var p Plugin
+	p.SetAPI(api)
+	setConfig := func(moveToAnotherTeam bool, maxThreadCount string) {
+		p.setConfiguration(&configuration{
+			PermittedWranglerUsers:        permittedUserAllUsers,
+			MoveThreadToAnotherTeamEnable: moveToAnotherTeam,
+			MoveThreadMaxCount:            maxThreadCount,
+		})
+	}
+
+	request := func(query string) (*httptest.ResponseRecorder, []string) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, routeAPIEligibleChannels+query, nil)
+		r.Header.Set("Mattermost-User-Id", user.Id)
+		p.ServeHTTP(&plugin.Context{}, w, r)
+
+		if w.Code != http.StatusOK {
+			return w, nil
+		}
+
+		var channels []*eligibleChannel
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &channels))
+		names := []string{}
+		for _, channel := range channels {
+			names = append(names, channel.TeamName+":"+channel.Name)
+		}
+		return w, names
+	}
+
+	t.Run("invalid source post", func(t *testing.T) {
+		setConfig(true, "")
+		w, _ := request("?source_post_id=invalid")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("source post not found", func(t *testing.T) {
+		setConfig(true, "")
+		w, _ := request("?source_post_id=" + model.NewId())
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("invalid pagination", func(t *testing.T) {
+		setConfig(true, "")
+		w, _ := request("?source_post_id=" + rootPostID + "&page=-1")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("all teams", func(t *testing.T) {
+		setConfig(true, "")
+		w, names := request("?source_post_id=" + rootPostID)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"team-1:private", "team-1:town-square", "team-2:other-team"}, names)
+	})
+
+	t.Run("moving to another team is disabled", func(t *testing.T) {
+		setConfig(false, "")
+		w, names := request("?source_post_id=" + rootPostID)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"team-1:private", "team-1:town-square"}, names)
+	})
+
+	t.Run("thread is too long", func(t *testing.T) {
+		setConfig(true, "2")
+		w, names := request("?source_post_id=" + rootPostID)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, names)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		setConfig(true, "")
+		w, names := request("?source_post_id=" + rootPostID + "&q=TOWN")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"team-1:town-square"}, names)
+	})
+
+	t.Run("team filter", func(t *testing.T) {
+		setConfig(true, "")
+		w, names := request("?source_post_id=" + rootPostID + "&team_id=" + team2.Id)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"team-2:other-team"}, names)
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		setConfig(true, "")
+		w, names := request("?source_post_id=" + rootPostID + "&page=1&per_page=2")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"team-2:other-team"}, names)
+
+		w, names = request("?source_post_id=" + rootPostID + "&page=5&per_page=2")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, names)
+
+		w, names = request("?source_post_id=" + rootPostID + "&page=9223372036854775807&per_page=2")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, names)
+
+		w, names = request("?source_post_id=" + rootPostID + "&per_page=0")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, names)
+	})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	routeAPIThreadPrefix        = "/api/v1/thread/"
+	routeAPIThreadPreviewSuffix = "/preview"
+
+	previewExcerptLength = 500
+)
+
+// threadPreview describes a thread that is about to be wrangled so that users
+// can check it before confirming.
+type threadPreview struct {
+	PostID          string                `json:"post_id"`
+	ChannelID       string                `json:"channel_id"`
+	RootMessage     string                `json:"root_message"`
+	MessageCount    int                   `json:"message_count"`
+	Participants    []*previewParticipant `json:"participants"`
+	AttachmentCount int64                 `json:"attachment_count"`
+	AttachmentBytes int64                 `json:"attachment_bytes"`
+	ReactionCount   int                   `json:"reaction_count"`
+	EarliestPostAt  int64                 `json:"earliest_post_at"`
+	LatestPostAt    int64                 `json:"latest_post_at"`
+}
+
+type previewParticipant struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// getThreadPreviewPostID returns the post ID of a thread preview route, or an
+// empty string if the path is not a thread preview route.
+func getThreadPreviewPostID(path string) string {
+	if !strings.HasPrefix(path, routeAPIThreadPrefix) || !strings.HasSuffix(path, routeAPIThreadPreviewSuffix) {
+		return ""
+	}
+
+	postID := strings.TrimSuffix(strings.TrimPrefix(path, routeAPIThreadPrefix), routeAPIThreadPreviewSuffix)
+	if strings.Contains(postID, "/") {
+		return ""
+	}
+
+	return postID
+}
+
+func (p *Plugin) handleRouteAPIThreadPreview(w http.ResponseWriter, r *http.Request, postID string) (int, error) {
+	if r.Method != http.MethodGet {
+		return respondWranglerAPIErr(w, http.StatusMethodNotAllowed, apiErrorMethodNotAllowed,
+			errors.Errorf("method %s is not allowed, must be GET", r.Method))
+	}
+
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		return respondWranglerAPIErr(w, http.StatusUnauthorized, apiErrorNotAuthorized, errors.New("not authorized"))
+	}
+	if !p.authorizedPluginUser(mattermostUserID) {
+		return respondWranglerAPIErr(w, http.StatusForbidden, apiErrorForbidden, errors.New("you are not permitted to use Wrangler"))
+	}
+	if !model.IsValidId(postID) {
+		return respondWranglerAPIErr(w, http.StatusBadRequest, apiErrorInvalidRequest, errors.New("the post ID is not valid"))
+	}
+
+	postListResponse, appErr := p.API.GetPostThread(postID)
+	if appErr != nil {
+		return respondWranglerAPIErr(w, http.StatusNotFound, apiErrorNotFound, errors.Errorf("unable to find post with ID %s", postID))
+	}
+	wpl := buildWranglerPostList(postListResponse)
+	if wpl.NumPosts() == 0 {
+		return respondWranglerAPIErr(w, http.StatusNotFound, apiErrorNotFound, errors.Errorf("unable to find post with ID %s", postID))
+	}
+
+	_, appErr = p.API.GetChannelMember(wpl.RootPost().ChannelId, mattermostUserID)
+	if appErr != nil {
+		return respondWranglerAPIErr(w, http.StatusForbidden, apiErrorForbidden, errors.New("you are not a member of the channel containing the post"))
+	}
+
+	summary, err := p.summarizeWranglerPostList(wpl)
+	if err != nil {
+		return respondWranglerAPIErr(w, http.StatusInternalServerError, apiErrorInternal, errors.Wrap(err, "unable to summarize thread"))
+	}
+
+	preview := &threadPreview{
+		PostID:          wpl.RootPost().Id,
+		ChannelID:       wpl.RootPost().ChannelId,
+		RootMessage:     cleanAndTrimMessage(wpl.RootPost().Message, previewExcerptLength),
+		MessageCount:    summary.MessageCount,
+		Participants:    []*previewParticipant{},
+		AttachmentCount: summary.AttachmentCount,
+		AttachmentBytes: summary.AttachmentBytes,
+		ReactionCount:   summary.ReactionCount,
+		EarliestPostAt:  wpl.EarlistPostTimestamp,
+		LatestPostAt:    wpl.LatestPostTimestamp,
+	}
+	// The participants are summarized in the same order as the thread users.
+	for i, username := range summary.Participants {
+		preview.Participants = append(preview.Participants, &previewParticipant{
+			UserID:   wpl.ThreadUserIDs[i],
+			Username: username,
+		})
+	}
+
+	return respondJSON(w, preview)
+}
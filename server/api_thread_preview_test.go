@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetThreadPreviewPostID(t *testing.T) {
+	postID := model.NewId()
+
+	assert.Equal(t, postID, getThreadPreviewPostID("/api/v1/thread/"+postID+"/preview"))
+	assert.Empty(t, getThreadPreviewPostID("/api/v1/thread/"+postID))
+	assert.Empty(t, getThreadPreviewPostID("/api/v1/thread/move"))
+	assert.Empty(t, getThreadPreviewPostID("/api/v1/thread/a/b/preview"))
+}
+
+func TestThreadPreviewAPI(t *testing.T) {
+	channelID := model.NewId()
+	user := &model.User{
+		Id:       model.NewId(),
+		Username: "user",
+	}
+	otherUser := &model.User{
+		Id:       model.NewId(),
+		Username: "other-user",
+	}
+	nonMember := &model.User{
+		Id:       model.NewId(),
+		Username: "non-member",
+	}
+
+	rootPost := &model.Post{
+		Id:        model.NewId(),
+		UserId:    user.Id,
+		ChannelId: channelID,
+		Message:   "# Root message\nwith two lines",
+		CreateAt:  1000,
+	}
+	reply := &model.Post{
+		Id:        model.NewId(),
+		UserId:    otherUser.Id,
+		ChannelId: channelID,
+		RootId:    rootPost.Id,
+		FileIds:   []string{model.NewId()},
+		CreateAt:  2000,
+	}
+	postList := model.NewPostList()
+	postList.AddPost(rootPost)
+	postList.AddOrder(rootPost.Id)
+	postList.AddPost(reply)
+	postList.AddOrder(reply.Id)
+
+	api := &plugintest.API{}
+	api.On("GetPostThread", rootPost.Id).Return(postList, nil)
+	api.On("GetPostThread", mock.AnythingOfType("string")).Return(nil, model.NewAppError("where", model.NewId(), nil, "not found", 0))
+	api.On("GetChannelMember", channelID, user.Id).Return(&model.ChannelMember{}, nil)
+	api.On("GetChannelMember", channelID, nonMember.Id).Return(nil, model.NewAppError("where", model.NewId(), nil, "not found", 0))
+	api.On("GetUser", user.Id).Return(user, nil)
+	api.On("GetUser", otherUser.Id).Return(otherUser, nil)
+	api.On("GetUser", nonMember.Id).Return(nonMember, nil)
+	api.On("GetFileInfo", reply.FileIds[0]).Return(&model.FileInfo{Size: 2048}, nil)
+	api.On("GetReactions", rootPost.Id).Return([]*model.Reaction{{}}, nil)
+	api.On("GetReactions", reply.Id).Return([]*model.Reaction{}, nil)
+	api.On("LogError",
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+	).Return(nil)
+
+	var p Plugin
+	p.SetAPI(api)
+	p.setConfiguration(&configuration{
+		PermittedWranglerUsers: permittedUserAllUsers,
+	})
+
+	request := func(method, postID, userID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, routeAPIThreadPrefix+postID+routeAPIThreadPreviewSuffix, nil)
+		if len(userID) != 0 {
+			r.Header.Set("Mattermost-User-Id", userID)
+		}
+		p.ServeHTTP(&plugin.Context{}, w, r)
+		return w
+	}
+
+	t.Run("method not allowed", func(t *testing.T) {
+		w := request(http.MethodPost, rootPost.Id, user.Id)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+
+	t.Run("not authenticated", func(t *testing.T) {
+		w := request(http.MethodGet, rootPost.Id, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("invalid post ID", func(t *testing.T) {
+		w := request(http.MethodGet, "invalid", user.Id)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("post not found", func(t *testing.T) {
+		w := request(http.MethodGet, model.NewId(), user.Id)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("not a channel member", func(t *testing.T) {
+		w := request(http.MethodGet, rootPost.Id, nonMember.Id)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		var apiErr wranglerAPIError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+		assert.Equal(t, apiErrorForbidden, apiErr.ID)
+	})
+
+	t.Run("preview", func(t *testing.T) {
+		w := request(http.MethodGet, rootPost.Id, user.Id)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var preview threadPreview
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &preview))
+		assert.Equal(t, rootPost.Id, preview.PostID)
+		assert.Equal(t, channelID, preview.ChannelID)
+		assert.Equal(t, "Root message | with two lines", preview.RootMessage)
+		assert.Equal(t, 2, preview.MessageCount)
+		require.Len(t, preview.Participants, 2)
+		assert.Equal(t, user.Id, preview.Participants[0].UserID)
+		assert.Equal(t, "user", preview.Participants[0].Username)
+		assert.Equal(t, "other-user", preview.Participants[1].Username)
+		assert.EqualValues(t, 1, preview.AttachmentCount)
+		assert.EqualValues(t, 2048, preview.AttachmentBytes)
+		assert.Equal(t, 1, preview.ReactionCount)
+		assert.EqualValues(t, 1000, preview.EarliestPostAt)
+		assert.EqualValues(t, 2000, preview.LatestPostAt)
+	})
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	routeAPIMoveThread    = "/api/v1/thread/move"
+	routeAPICopyThread    = "/api/v1/thread/copy"
+	routeAPIMergeThread   = "/api/v1/thread/merge"
+	routeAPIAttachMessage = "/api/v1/message/attach"
+
+	apiErrorNotAuthorized    = "not_authorized"
+	apiErrorForbidden        = "forbidden"
+	apiErrorMethodNotAllowed = "method_not_allowed"
+	apiErrorInvalidRequest   = "invalid_request"
+	apiErrorNotFound         = "not_found"
+	apiErrorRejected         = "rejected"
+	apiErrorInternal         = "internal_error"
+)
+
+// wranglerAPIRequest is the body of a request to one of the wrangler API
+// endpoints. Each endpoint uses the fields matching the arguments of its slash
+// command.
+type wranglerAPIRequest struct {
+	PostID                   string `json:"post_id"`
+	ChannelID                string `json:"channel_id,omitempty"`
+	TargetPostID             string `json:"target_post_id,omitempty"`
+	TeamID                   string `json:"team_id,omitempty"`
+	ShowRootMessageInSummary *bool  `json:"show_root_message_in_summary,omitempty"`
+	Silent                   bool   `json:"silent,omitempty"`
+	Notify                   string `json:"notify,omitempty"`
+}
+
+// wranglerAPIResult is the response of a wrangler API endpoint. When the
+// operation runs as a background job, only the job ID and the message are set.
+type wranglerAPIResult struct {
+	PostID       string `json:"post_id,omitempty"`
+	ChannelID    string `json:"channel_id,omitempty"`
+	Permalink    string `json:"permalink,omitempty"`
+	MessageCount int    `json:"message_count,omitempty"`
+	OperationID  string `json:"operation_id,omitempty"`
+	JobID        string `json:"job_id,omitempty"`
+	Message      string `json:"message"`
+}
+
+// wranglerAPIError is the response of a wrangler API endpoint when the
+// request fails. The ID is one of the apiError constants.
+type wranglerAPIError struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// wranglerAPIRoute describes how a wrangler API endpoint maps to the slash
+// command sharing its validation.
+type wranglerAPIRoute struct {
+	command string
+	handler func([]string, *model.CommandArgs, *AuditRecord) (*model.CommandResponse, *wranglerResult, bool, error)
+	args    func(request *wranglerAPIRequest) ([]string, error)
+
+	// teamRequired is set for commands that need a team even when the
+	// message is in a direct or group message channel.
+	teamRequired bool
+}
+
+func (p *Plugin) getWranglerAPIRoute(path string) *wranglerAPIRoute {
+	switch path {
+	case routeAPIMoveThread:
+		return &wranglerAPIRoute{
+			command: "move thread",
+			handler: p.runMoveThreadCommandWithResult,
+			args: func(request *wranglerAPIRequest) ([]string, error) {
+				if !model.IsValidId(request.ChannelID) {
+					return nil, errors.New("channel_id must be a valid channel ID")
+				}
+				showRootMessageInSummary := true
+				if request.ShowRootMessageInSummary != nil {
+					showRootMessageInSummary = *request.ShowRootMessageInSummary
+				}
+				args := []string{
+					request.PostID,
+					request.ChannelID,
+					"--" + flagMoveThreadShowMessageSummary + "=" + strconv.FormatBool(showRootMessageInSummary),
+					"--" + flagMoveThreadSilent + "=" + strconv.FormatBool(request.Silent),
+				}
+				if len(request.Notify) != 0 {
+					args = append(args, "--"+flagNotify+"="+request.Notify)
+				}
+				return args, nil
+			},
+		}
+	case routeAPICopyThread:
+		return &wranglerAPIRoute{
+			command: "copy thread",
+			handler: p.runCopyThreadCommandWithResult,
+			args: func(request *wranglerAPIRequest) ([]string, error) {
+				if !model.IsValidId(request.ChannelID) {
+					return nil, errors.New("channel_id must be a valid channel ID")
+				}
+				args := []string{request.PostID, request.ChannelID}
+				if len(request.Notify) != 0 {
+					args = append(args, "--"+flagNotify+"="+request.Notify)
+				}
+				return args, nil
+			},
+		}
+	case routeAPIMergeThread:
+		return &wranglerAPIRoute{
+			command: "merge thread",
+			handler: p.runMergeThreadCommandWithResult,
+			args: func(request *wranglerAPIRequest) ([]string, error) {
+				if !model.IsValidId(request.TargetPostID) {
+					return nil, errors.New("target_post_id must be a valid post ID")
+				}
+				return []string{request.PostID, request.TargetPostID}, nil
+			},
+		}
+	case routeAPIAttachMessage:
+		return &wranglerAPIRoute{
+			command: "attach message",
+			handler: p.runAttachMessageCommandWithResult,
+			args: func(request *wranglerAPIRequest) ([]string, error) {
+				if !model.IsValidId(request.TargetPostID) {
+					return nil, errors.New("target_post_id must be a valid post ID")
+				}
+				return []string{request.PostID, request.TargetPostID}, nil
+			},
+			teamRequired: true,
+		}
+	}
+
+	return nil
+}
+
+// handleRouteAPIWrangler runs a move, copy, merge or attach request through
+// the handler of the matching slash command as if the command had been run
+// from the channel containing the message, so both share their validation.
+func (p *Plugin) handleRouteAPIWrangler(w http.ResponseWriter, r *http.Request, route *wranglerAPIRoute) (int, error) {
+	if r.Method != http.MethodPost {
+		return respondWranglerAPIErr(w, http.StatusMethodNotAllowed, apiErrorMethodNotAllowed,
+			errors.Errorf("method %s is not allowed, must be POST", r.Method))
+	}
+
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	if mattermostUserID == "" {
+		return respondWranglerAPIErr(w, http.StatusUnauthorized, apiErrorNotAuthorized, errors.New("not authorized"))
+	}
+	if !p.authorizedPluginUser(mattermostUserID) {
+		return respondWranglerAPIErr(w, http.StatusForbidden, apiErrorForbidden, errors.New("you are not permitted to use Wrangler"))
+	}
+
+	var request wranglerAPIRequest
+	err := decodeJSON(&request, r.Body)
+	if err != nil {
+		return respondWranglerAPIErr(w, http.StatusBadRequest, apiErrorInvalidRequest, errors.Wrap(err, "unable to decode request"))
+	}
+	if !model.IsValidId(request.PostID) {
+		return respondWranglerAPIErr(w, http.StatusBadRequest, apiErrorInvalidRequest, errors.New("post_id must be a valid post ID"))
+	}
+	args, err := route.args(&request)
+	if err != nil {
+		return respondWranglerAPIErr(w, http.StatusBadRequest, apiErrorInvalidRequest, err)
+	}
+
+	post, appErr := p.API.GetPost(request.PostID)
+	if appErr != nil {
+		return respondWranglerAPIErr(w, http.StatusNotFound, apiErrorNotFound, errors.Errorf("unable to find post with ID %s", request.PostID))
+	}
+	_, appErr = p.API.GetChannelMember(post.ChannelId, mattermostUserID)
+	if appErr != nil {
+		return respondWranglerAPIErr(w, http.StatusForbidden, apiErrorForbidden, errors.New("you are not a member of the channel containing the post"))
+	}
+	channel, appErr := p.API.GetChannel(post.ChannelId)
+	if appErr != nil {
+		return respondWranglerAPIErr(w, http.StatusInternalServerError, apiErrorInternal, errors.Wrapf(appErr, "unable to get channel with ID %s", post.ChannelId))
+	}
+
+	teamID := channel.TeamId
+	if len(teamID) == 0 {
+		teamID = request.TeamID
+	}
+	if route.teamRequired && len(teamID) == 0 {
+		return respondWranglerAPIErr(w, http.StatusBadRequest, apiErrorInvalidRequest, errors.New("team_id is required for posts in direct and group message channels"))
+	}
+
+	extra := &model.CommandArgs{
+		UserId:    mattermostUserID,
+		ChannelId: post.ChannelId,
+		TeamId:    teamID,
+		SiteURL:   *p.API.GetConfig().ServiceSettings.SiteURL,
+	}
+
+	audit := p.startCommandAudit(route.command, auditCategoryWrangle, args, extra)
+	resp, result, userError, err := route.handler(args, extra, audit)
+	p.finishCommandAudit(audit, resp, userError, err)
+
+	if err != nil {
+		return respondWranglerAPIHandlerErr(w, userError, err)
+	}
+	if result == nil {
+		// The handler responded without wrangling anything, for example
+		// because the plugin configuration doesn't allow the request.
+		return respondWranglerAPIErr(w, http.StatusUnprocessableEntity, apiErrorRejected, errors.New(responseText(resp)))
+	}
+	if len(result.JobID) != 0 {
+		return respondJSONWithStatus(w, http.StatusAccepted, &wranglerAPIResult{
+			JobID:   result.JobID,
+			Message: responseText(resp),
+		})
+	}
+
+	if resp != nil && resp.ResponseType == model.COMMAND_RESPONSE_TYPE_IN_CHANNEL {
+		// The summary is posted to the channel just like the response of the
+		// slash command would be.
+		_, appErr = p.API.CreatePost(&model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: extra.ChannelId,
+			Message:   resp.Text,
+		})
+		if appErr != nil {
+			p.API.LogError("Unable to post Wrangler summary",
+				"error", appErr.Error(),
+				"channel_id", extra.ChannelId,
+			)
+		}
+	}
+
+	apiResult := &wranglerAPIResult{
+		PostID:       result.PostID,
+		ChannelID:    result.ChannelID,
+		MessageCount: result.MessageCount,
+		OperationID:  result.OperationID,
+		Message:      responseText(resp),
+	}
+	targetChannel, appErr := p.API.GetChannel(result.ChannelID)
+	if appErr == nil {
+		apiResult.Permalink, err = p.getPostLinkForChannel(targetChannel, teamID, result.PostID)
+	}
+	if appErr != nil || err != nil {
+		p.API.LogWarn("Unable to build permalink of wrangled post", "post_id", result.PostID)
+	}
+
+	return respondJSON(w, apiResult)
+}
+
+// respondWranglerAPIHandlerErr responds to a request that failed in the
+// command handler. Errors caused by the request are returned to the caller.
+// Other errors can contain internal details, so the caller only gets a generic
+// message while the error itself is logged.
+func respondWranglerAPIHandlerErr(w http.ResponseWriter, userError bool, err error) (int, error) {
+	code, id, message := http.StatusInternalServerError, apiErrorInternal, "an internal error occurred"
+
+	var appErr *model.AppError
+	switch {
+	case userError:
+		code, id, message = http.StatusBadRequest, apiErrorInvalidRequest, err.Error()
+	case errors.As(err, &appErr) && appErr.StatusCode == http.StatusNotFound:
+		code, id, message = http.StatusNotFound, apiErrorNotFound, appErr.Message
+	case errors.As(err, &appErr) && appErr.StatusCode == http.StatusForbidden:
+		code, id, message = http.StatusForbidden, apiErrorForbidden, appErr.Message
+	}
+
+	_, writeErr := respondJSONWithStatus(w, code, &wranglerAPIError{
+		ID:      id,
+		Message: message,
+	})
+	if writeErr != nil {
+		return http.StatusInternalServerError, writeErr
+	}
+
+	return code, err
+}
+
+func respondWranglerAPIErr(w http.ResponseWriter, code int, id string, err error) (int, error) {
+	_, writeErr := respondJSONWithStatus(w, code, &wranglerAPIError{
+		ID:      id,
+		Message: err.Error(),
+	})
+	if writeErr != nil {
+		return http.StatusInternalServerError, writeErr
+	}
+
+	return code, err
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWranglerAPI(t *testing.T) {
+	team1 := &model.Team{
+		Id:   model.NewId(),
+		Name: "team-1",
+	}
+	channel1 := &model.Channel{
+		Id:     model.NewId(),
+		TeamId: team1.Id,
+		Name:   "channel1",
+		Type:   model.CHANNEL_OPEN,
+	}
+	directChannel := &model.Channel{
+		Id:   model.NewId(),
+		Name: "direct1",
+		Type: model.CHANNEL_DIRECT,
+	}
+	user := &model.User{
+		Id:       model.NewId(),
+		Username: "user",
+	}
+	nonMember := &model.User{
+		Id:       model.NewId(),
+		Username: "non-member",
+	}
+	postToBeAttached := &model.Post{
+		Id:        model.NewId(),
+		UserId:    user.Id,
+		ChannelId: channel1.Id,
+	}
+	postToAttachTo := &model.Post{
+		Id:        model.NewId(),
+		UserId:    model.NewId(),
+		ChannelId: channel1.Id,
+	}
+	rootID := model.NewId()
+	postInThreadAlready := &model.Post{
+		Id:        model.NewId(),
+		ChannelId: channel1.Id,
+		RootId:    rootID,
+		ParentId:  rootID,
+	}
+	postInDirectChannel := &model.Post{
+		Id:        model.NewId(),
+		ChannelId: directChannel.Id,
+	}
+	channelInMissingTeam := &model.Channel{
+		Id:     model.NewId(),
+		TeamId: model.NewId(),
+		Name:   "channel2",
+		Type:   model.CHANNEL_OPEN,
+	}
+	postInMissingTeam := &model.Post{
+		Id:        model.NewId(),
+		ChannelId: channelInMissingTeam.Id,
+	}
+	otherPostInMissingTeam := &model.Post{
+		Id:        model.NewId(),
+		ChannelId: channelInMissingTeam.Id,
+	}
+
+	config := &model.Config{
+		ServiceSettings: model.ServiceSettings{
+			SiteURL: NewString("https://test.sampledomain.com"),
+		},
+	}
+
+	api := &plugintest.API{}
+	api.On("GetPost", postToBeAttached.Id).Return(postToBeAttached, nil)
+	api.On("GetPost", postToAttachTo.Id).Return(postToAttachTo, nil)
+	api.On("GetPost", postInThreadAlready.Id).Return(postInThreadAlready, nil)
+	api.On("GetPost", postInDirectChannel.Id).Return(postInDirectChannel, nil)
+	api.On("GetPost", postInMissingTeam.Id).Return(postInMissingTeam, nil)
+	api.On("GetPost", otherPostInMissingTeam.Id).Return(otherPostInMissingTeam, nil)
+	api.On("GetPost", mock.AnythingOfType("string")).Return(nil, model.NewAppError("where", model.NewId(), nil, "not found", 0))
+	api.On("GetChannelMember", mock.AnythingOfType("string"), user.Id).Return(&model.ChannelMember{}, nil)
+	api.On("GetChannelMember", mock.AnythingOfType("string"), nonMember.Id).Return(nil, model.NewAppError("where", model.NewId(), nil, "not found", 0))
+	api.On("GetChannel", channel1.Id).Return(channel1, nil)
+	api.On("GetChannel", directChannel.Id).Return(directChannel, nil)
+	api.On("GetChannel", channelInMissingTeam.Id).Return(channelInMissingTeam, nil)
+	api.On("GetTeam", team1.Id).Return(team1, nil)
+	api.On("GetTeam", channelInMissingTeam.TeamId).Return(nil, model.NewAppError("where", model.NewId(), nil, "database unavailable", http.StatusInternalServerError))
+	api.On("GetUser", user.Id).Return(user, nil)
+	api.On("GetUser", nonMember.Id).Return(nonMember, nil)
+	api.On("GetConfig").Return(config)
+	api.On("CreatePost", mock.Anything).Return(mockGeneratePost(), nil)
+	api.On("DeletePost", mock.AnythingOfType("string")).Return(nil)
+	api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+	mockKVStore(api)
+	api.On("LogInfo",
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+	).Return(nil)
+	api.On("LogError",
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+	).Return(nil)
+
+	var p Plugin
+	p.SetAPI(api)
+	p.setConfiguration(&configuration{
+		PermittedWranglerUsers: permittedUserAllUsers,
+	})
+
+	request := func(method, route, userID string, body interface{}) (*httptest.ResponseRecorder, *wranglerAPIResult, *wranglerAPIError) {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, route, bytes.NewReader(data))
+		if len(userID) != 0 {
+			r.Header.Set("Mattermost-User-Id", userID)
+		}
+		p.ServeHTTP(&plugin.Context{}, w, r)
+
+		if w.Code >= http.StatusBadRequest {
+			var apiErr wranglerAPIError
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &apiErr))
+			return w, nil, &apiErr
+		}
+
+		var result wranglerAPIResult
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		return w, &result, nil
+	}
+
+	t.Run("method not allowed", func(t *testing.T) {
+		w, _, apiErr := request(http.MethodGet, routeAPIAttachMessage, user.Id, nil)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, apiErrorMethodNotAllowed, apiErr.ID)
+	})
+
+	t.Run("not authenticated", func(t *testing.T) {
+		w, _, apiErr := request(http.MethodPost, routeAPIAttachMessage, "", nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, apiErrorNotAuthorized, apiErr.ID)
+	})
+
+	t.Run("not a permitted user", func(t *testing.T) {
+		p.setConfiguration(&configuration{
+			PermittedWranglerUsers: permittedUserSystemAdmins,
+		})
+		defer p.setConfiguration(&configuration{
+			PermittedWranglerUsers: permittedUserAllUsers,
+		})
+
+		w, _, apiErr := request(http.MethodPost, routeAPIAttachMessage, user.Id, &wranglerAPIRequest{
+			PostID:       postToBeAttached.Id,
+			TargetPostID: postToAttachTo.Id,
+		})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, apiErrorForbidden, apiErr.ID)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		w, _, apiErr := request(http.MethodPost, routeAPIMoveThread, user.Id, &wranglerAPIRequest{
+			PostID: postToBeAttached.Id,
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, apiErrorInvalidRequest, apiErr.ID)
+		assert.Contains(t, apiErr.Message, "channel_id")
+	})
+
+	t.Run("post not found", func(t *testing.T) {
+		w, _, apiErr := request(http.MethodPost, routeAPICopyThread, user.Id, &wranglerAPIRequest{
+			PostID:    model.NewId(),
+			ChannelID: channel1.Id,
+		})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, apiErrorNotFound, apiErr.ID)
+	})
+
+	t.Run("not a channel member", func(t *testing.T) {
+		w, _, apiErr := request(http.MethodPost, routeAPIAttachMessage, nonMember.Id, &wranglerAPIRequest{
+			PostID:       postToBeAttached.Id,
+			TargetPostID: postToAttachTo.Id,
+		})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, apiErrorForbidden, apiErr.ID)
+	})
+
+	t.Run("team required for direct message channels", func(t *testing.T) {
+		w, _, apiErr := request(http.MethodPost, routeAPIAttachMessage, user.Id, &wranglerAPIRequest{
+			PostID:       postInDirectChannel.Id,
+			TargetPostID: postToAttachTo.Id,
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, apiErrorInvalidRequest, apiErr.ID)
+		assert.Contains(t, apiErr.Message, "team_id")
+	})
+
+	t.Run("rejected by command validation", func(t *testing.T) {
+		w, _, apiErr := request(http.MethodPost, routeAPIAttachMessage, user.Id, &wranglerAPIRequest{
+			PostID:       postInThreadAlready.Id,
+			TargetPostID: postToAttachTo.Id,
+		})
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Equal(t, apiErrorRejected, apiErr.ID)
+		assert.Equal(t, "Error: the message to be attached is already part of a thread", apiErr.Message)
+	})
+
+	t.Run("invalid flag", func(t *testing.T) {
+		w, _, apiErr := request(http.MethodPost, routeAPIMoveThread, user.Id, &wranglerAPIRequest{
+			PostID:    postToBeAttached.Id,
+			ChannelID: channel1.Id,
+			Notify:    "everyone",
+		})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, apiErrorInvalidRequest, apiErr.ID)
+		assert.Contains(t, apiErr.Message, "notification policy everyone must be one of")
+	})
+
+	t.Run("internal error", func(t *testing.T) {
+		w, _, apiErr := request(http.MethodPost, routeAPIAttachMessage, user.Id, &wranglerAPIRequest{
+			PostID:       postInMissingTeam.Id,
+			TargetPostID: otherPostInMissingTeam.Id,
+		})
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, apiErrorInternal, apiErr.ID)
+		assert.Equal(t, "an internal error occurred", apiErr.Message)
+	})
+
+	t.Run("attach message", func(t *testing.T) {
+		w, result, _ := request(http.MethodPost, routeAPIAttachMessage, user.Id, &wranglerAPIRequest{
+			PostID:       postToBeAttached.Id,
+			TargetPostID: postToAttachTo.Id,
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, postToAttachTo.Id, result.PostID)
+		assert.Equal(t, channel1.Id, result.ChannelID)
+		assert.Equal(t, "https://test.sampledomain.com/team-1/pl/"+postToAttachTo.Id, result.Permalink)
+		assert.Equal(t, 1, result.MessageCount)
+		assert.NotEmpty(t, result.OperationID)
+		assert.Contains(t, result.Message, "Message successfully attached to thread")
+
+		records, err := p.getAuditRecords(auditFilter{UserID: user.Id})
+		require.NoError(t, err)
+		require.NotEmpty(t, records)
+		assert.Equal(t, "attach message", records[0].Command)
+		assert.Equal(t, auditResultSucceeded, records[0].Result)
+		assert.Equal(t, result.OperationID, records[0].OperationID)
+	})
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	auditResultSucceeded = "succeeded"
+	auditResultRejected  = "rejected"
+	auditResultFailed    = "failed"
+
+	// auditCategoryWrangle is the category of commands that change messages,
+	// every other command is in auditCategoryOther.
+	auditCategoryWrangle = "wrangle"
+	auditCategoryOther   = "other"
+
+	kvAuditPrefix     = "audit_"
+	auditKVListSize   = 1000
+	auditDayFormat    = "2006-01-02"
+	maxAuditQueryDays = 366
+	// defaultAuditQueryDays is how far back queries without a start time
+	// look, so that they don't load every day of the log.
+	defaultAuditQueryDays = 7
+)
+
+// AuditRecord is a persisted record of a Wrangler command or background job.
+type AuditRecord struct {
+	ID              string `json:"id"`
+	CreateAt        int64  `json:"create_at"`
+	UserID          string `json:"user_id"`
+	Command         string `json:"command"`
+	Category        string `json:"category"`
+	Args            string `json:"args,omitempty"`
+	ChannelID       string `json:"channel_id"`
+	TeamID          string `json:"team_id,omitempty"`
+	SourcePostID    string `json:"source_post_id,omitempty"`
+	SourceChannelID string `json:"source_channel_id,omitempty"`
+	TargetPostID    string `json:"target_post_id,omitempty"`
+	TargetChannelID string `json:"target_channel_id,omitempty"`
+	MessageCount    int    `json:"message_count,omitempty"`
+	OperationID     string `json:"operation_id,omitempty"`
+	JobID           string `json:"job_id,omitempty"`
+	Result          string `json:"result"`
+	Error           string `json:"error,omitempty"`
+}
+
+// setWranglerDetails records the messages that are wrangled by a command.
+func (r *AuditRecord) setWranglerDetails(sourcePostID, sourceChannelID, targetPostID, targetChannelID string, messageCount int) {
+	r.SourcePostID = sourcePostID
+	r.SourceChannelID = sourceChannelID
+	r.TargetPostID = targetPostID
+	r.TargetChannelID = targetChannelID
+	r.MessageCount = messageCount
+}
+
+// auditFilter limits the audit records that are returned by a query. Empty
+// fields match all records.
+type auditFilter struct {
+	UserID    string
+	ChannelID string
+	Since     int64
+}
+
+func (f auditFilter) matches(record *AuditRecord) bool {
+	if record.CreateAt < f.Since {
+		return false
+	}
+	if len(f.UserID) != 0 && record.UserID != f.UserID {
+		return false
+	}
+	if len(f.ChannelID) != 0 &&
+		record.ChannelID != f.ChannelID &&
+		record.SourceChannelID != f.ChannelID &&
+		record.TargetChannelID != f.ChannelID {
+		return false
+	}
+
+	return true
+}
+
+// auditKey returns the key of an audit record created at the given time. Each
+// record is stored under its own key so that recording never contends with
+// other records. The key starts with the UTC day of the record so that
+// queries only load the days they cover.
+func auditKey(t time.Time, id string) string {
+	return kvAuditPrefix + t.UTC().Format(auditDayFormat) + "_" + id
+}
+
+// auditKeyDay returns the UTC day of an audit record key, or an empty string
+// if the key is not an audit record key.
+func auditKeyDay(key string) string {
+	if !strings.HasPrefix(key, kvAuditPrefix) || len(key) < len(kvAuditPrefix)+len(auditDayFormat) {
+		return ""
+	}
+
+	return key[len(kvAuditPrefix) : len(kvAuditPrefix)+len(auditDayFormat)]
+}
+
+// startCommandAudit creates the audit record of a command that is about to
+// run. The record is passed to the command handler, which adds the details of
+// the messages it wrangles to it.
+func (p *Plugin) startCommandAudit(command, category string, args []string, extra *model.CommandArgs) *AuditRecord {
+	return &AuditRecord{
+		UserID:    extra.UserId,
+		Command:   command,
+		Category:  category,
+		Args:      strings.Join(args, " "),
+		ChannelID: extra.ChannelId,
+		TeamID:    extra.TeamId,
+	}
+}
+
+// finishCommandAudit stores the audit record of a command with its result.
+func (p *Plugin) finishCommandAudit(record *AuditRecord, resp *model.CommandResponse, userError bool, err error) {
+	switch {
+	case err != nil:
+		record.Result = auditResultFailed
+		record.Error = err.Error()
+	case userError:
+		record.Result = auditResultRejected
+		record.Error = responseText(resp)
+	default:
+		record.Result = auditResultSucceeded
+	}
+
+	p.recordAuditOrLog(record)
+}
+
+// recordAudit assigns an ID to a new audit record and stores it. Records
+// expire once they are older than any query can reach.
+func (p *Plugin) recordAudit(record *AuditRecord) error {
+	record.ID = model.NewId()
+	record.CreateAt = model.GetMillis()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal audit record")
+	}
+
+	_, appErr := p.API.KVSetWithOptions(auditKey(time.Unix(0, record.CreateAt*int64(time.Millisecond)), record.ID), data, model.PluginKVSetOptions{
+		ExpireInSeconds: int64(maxAuditQueryDays * 24 * time.Hour / time.Second),
+	})
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to store audit record")
+	}
+
+	return nil
+}
+
+func (p *Plugin) recordAuditOrLog(record *AuditRecord) {
+	err := p.recordAudit(record)
+	if err != nil {
+		p.API.LogError("Unable to record Wrangler audit record",
+			"error", err.Error(),
+			"user_id", record.UserID,
+		)
+	}
+}
+
+// getAuditRecords returns the audit records matching the filter, newest
+// first. Filters without a start time return the records of the last
+// defaultAuditQueryDays, and records older than maxAuditQueryDays are never
+// returned.
+func (p *Plugin) getAuditRecords(filter auditFilter) ([]*AuditRecord, error) {
+	now := time.Now().UTC()
+	oldest := now.AddDate(0, 0, -maxAuditQueryDays)
+	if filter.Since == 0 {
+		oldest = now.AddDate(0, 0, -defaultAuditQueryDays)
+	} else if filter.Since > oldest.UnixNano()/int64(time.Millisecond) {
+		oldest = time.Unix(0, filter.Since*int64(time.Millisecond)).UTC()
+	}
+	oldestDay := oldest.Format(auditDayFormat)
+
+	// The KV store can't be queried by prefix, so the keys are listed and
+	// only the records of the days in range are loaded.
+	var records []*AuditRecord
+	for page := 0; ; page++ {
+		keys, appErr := p.API.KVList(page, auditKVListSize)
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "unable to list audit records")
+		}
+
+		for _, key := range keys {
+			day := auditKeyDay(key)
+			if len(day) == 0 || day < oldestDay {
+				continue
+			}
+
+			var record AuditRecord
+			found, err := p.kvGetJSON(key, &record)
+			if err != nil {
+				return nil, errors.Wrap(err, "unable to get audit records")
+			}
+			if found && filter.matches(&record) {
+				records = append(records, &record)
+			}
+		}
+
+		if len(keys) < auditKVListSize {
+			break
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].CreateAt > records[j].CreateAt
+	})
+
+	return records, nil
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAuditSince(t *testing.T) {
+	now := time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC)
+	toMillis := func(t time.Time) int64 {
+		return t.UnixNano() / int64(time.Millisecond)
+	}
+
+	testCases := []struct {
+		input       string
+		expected    int64
+		expectError bool
+	}{
+		{"", 0, false},
+		{"2021-06-01", toMillis(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)), false},
+		{"24h", toMillis(now.Add(-24 * time.Hour)), false},
+		{"7d", toMillis(now.AddDate(0, 0, -7)), false},
+		{"-7d", 0, true},
+		{"yesterday", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			since, err := parseAuditSince(tc.input, now)
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, since)
+		})
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	user := &model.User{
+		Id:       model.NewId(),
+		Username: "user",
+	}
+	adminUser := &model.User{
+		Id:       model.NewId(),
+		Username: "admin",
+		Roles:    model.SYSTEM_ADMIN_ROLE_ID,
+	}
+	channelID := model.NewId()
+	otherChannelID := model.NewId()
+
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	api.On("GetUser", user.Id).Return(user, nil)
+	api.On("GetUser", adminUser.Id).Return(adminUser, nil)
+	api.On("GetUserByUsername", "user").Return(user, nil)
+	api.On("GetUserByUsername", mock.AnythingOfType("string")).Return(nil, &model.AppError{})
+
+	context := &plugin.Context{}
+
+	var p Plugin
+	p.SetAPI(api)
+	p.setConfiguration(&configuration{
+		PermittedWranglerUsers: permittedUserAllUsers,
+	})
+
+	t.Run("every command is recorded", func(t *testing.T) {
+		args := &model.CommandArgs{UserId: user.Id, ChannelId: channelID, Command: "wrangler info"}
+		_, appErr := p.ExecuteCommand(context, args)
+		require.Nil(t, appErr)
+		time.Sleep(time.Millisecond)
+
+		args = &model.CommandArgs{UserId: user.Id, ChannelId: otherChannelID, Command: "wrangler move thread"}
+		_, appErr = p.ExecuteCommand(context, args)
+		require.Nil(t, appErr)
+		time.Sleep(time.Millisecond)
+
+		records, err := p.getAuditRecords(auditFilter{})
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, "move thread", records[0].Command)
+		assert.Equal(t, auditCategoryWrangle, records[0].Category)
+		assert.Equal(t, auditResultRejected, records[0].Result)
+		assert.Contains(t, records[0].Error, "Error: missing arguments")
+		assert.Equal(t, user.Id, records[0].UserID)
+		assert.Equal(t, otherChannelID, records[0].ChannelID)
+		assert.Equal(t, "info", records[1].Command)
+		assert.Equal(t, auditCategoryOther, records[1].Category)
+		assert.Equal(t, auditResultSucceeded, records[1].Result)
+	})
+
+	t.Run("each record is stored under its own key", func(t *testing.T) {
+		records, err := p.getAuditRecords(auditFilter{})
+		require.NoError(t, err)
+		for _, record := range records {
+			assert.Contains(t, store, auditKey(time.Unix(0, record.CreateAt*int64(time.Millisecond)), record.ID))
+		}
+	})
+
+	t.Run("wrangler details are recorded", func(t *testing.T) {
+		args := &model.CommandArgs{UserId: adminUser.Id, ChannelId: channelID}
+		record := p.startCommandAudit("move thread", auditCategoryWrangle, []string{"post-id", otherChannelID}, args)
+		record.setWranglerDetails("post-id", channelID, "new-post-id", otherChannelID, 5)
+		p.finishCommandAudit(record, nil, false, nil)
+
+		records, err := p.getAuditRecords(auditFilter{UserID: adminUser.Id})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, record.ID, records[0].ID)
+		assert.Equal(t, "post-id "+otherChannelID, records[0].Args)
+		assert.Equal(t, "new-post-id", records[0].TargetPostID)
+		assert.Equal(t, 5, records[0].MessageCount)
+	})
+
+	t.Run("filter by channel", func(t *testing.T) {
+		records, err := p.getAuditRecords(auditFilter{ChannelID: otherChannelID})
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, "move thread", records[0].Command)
+		assert.Equal(t, auditResultSucceeded, records[0].Result)
+		assert.Equal(t, "move thread", records[1].Command)
+		assert.Equal(t, auditResultRejected, records[1].Result)
+	})
+
+	t.Run("filter by time", func(t *testing.T) {
+		records, err := p.getAuditRecords(auditFilter{Since: model.GetMillis() + 1000})
+		require.NoError(t, err)
+		assert.Empty(t, records)
+	})
+
+	t.Run("default time window", func(t *testing.T) {
+		old := time.Now().AddDate(0, 0, -defaultAuditQueryDays-3)
+		oldRecord := &AuditRecord{
+			ID:       model.NewId(),
+			CreateAt: old.UnixNano() / int64(time.Millisecond),
+			UserID:   adminUser.Id,
+			Command:  "copy thread",
+			Result:   auditResultSucceeded,
+		}
+		require.NoError(t, p.kvSetJSON(auditKey(old, oldRecord.ID), oldRecord))
+
+		records, err := p.getAuditRecords(auditFilter{UserID: adminUser.Id})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, "move thread", records[0].Command)
+
+		records, err = p.getAuditRecords(auditFilter{UserID: adminUser.Id, Since: oldRecord.CreateAt})
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, oldRecord.ID, records[1].ID)
+	})
+
+	t.Run("audit command", func(t *testing.T) {
+		resp, isUserError, err := p.runAuditCommand([]string{"--user", "@user"}, &model.CommandArgs{UserId: adminUser.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "@user - move thread - rejected")
+		assert.Contains(t, resp.Text, "@user - info - succeeded")
+		assert.NotContains(t, resp.Text, "@admin")
+	})
+
+	t.Run("audit command with unknown user", func(t *testing.T) {
+		resp, isUserError, err := p.runAuditCommand([]string{"--user", "@unknown"}, &model.CommandArgs{UserId: adminUser.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: unable to find user @unknown")
+	})
+
+	t.Run("audit command with invalid since", func(t *testing.T) {
+		resp, isUserError, err := p.runAuditCommand([]string{"--since", "yesterday"}, &model.CommandArgs{UserId: adminUser.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "is not a valid date or duration")
+	})
+
+	t.Run("api", func(t *testing.T) {
+		request := func(userID, query string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, routeAPIAudit+query, nil)
+			if len(userID) != 0 {
+				r.Header.Set("Mattermost-User-Id", userID)
+			}
+			p.ServeHTTP(context, w, r)
+			return w
+		}
+
+		api.On("LogError",
+			mock.AnythingOfType("string"),
+			mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+			mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+			mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+			mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+			mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+			mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		).Return(nil)
+
+		t.Run("not authenticated", func(t *testing.T) {
+			w := request("", "")
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+
+		t.Run("not an admin", func(t *testing.T) {
+			w := request(user.Id, "")
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		})
+
+		t.Run("json", func(t *testing.T) {
+			w := request(adminUser.Id, "?user_id="+user.Id)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var records []*AuditRecord
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &records))
+			assert.Len(t, records, 2)
+		})
+
+		t.Run("csv", func(t *testing.T) {
+			w := request(adminUser.Id, "?format=csv&channel_id="+channelID)
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+
+			rows, err := csv.NewReader(w.Body).ReadAll()
+			require.NoError(t, err)
+			require.Len(t, rows, 3)
+			assert.Equal(t, "id", rows[0][0])
+			assert.Equal(t, "move thread", rows[1][3])
+			assert.Equal(t, auditCategoryWrangle, rows[1][4])
+			assert.Equal(t, "info", rows[2][3])
+			assert.Equal(t, auditCategoryOther, rows[2][4])
+		})
+
+		t.Run("invalid format", func(t *testing.T) {
+			w := request(adminUser.Id, "?format=xml")
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+var (
+	channelLinkRegex     = regexp.MustCompile(`^/?([a-z0-9\-_]+)/channels/([a-z0-9\-_]+)/?$`)
+	teamChannelNameRegex = regexp.MustCompile(`^([a-z0-9\-_]+):([a-z0-9\-_]+)$`)
+)
+
+// channelReference is a parsed channel argument that isn't a channel ID.
+type channelReference struct {
+	teamName    string
+	channelName string
+	displayName string
+}
+
+// parseChannelReference parses a ~channel-name, team-name:channel-name,
+// channel link or channel display name.
+func parseChannelReference(input, siteURL string) channelReference {
+	path := strings.TrimPrefix(input, siteURL)
+	if strings.Contains(path, "://") {
+		if parsed, err := url.Parse(path); err == nil {
+			path = parsed.Path
+		}
+	}
+	if matches := channelLinkRegex.FindStringSubmatch(strings.ToLower(path)); matches != nil {
+		return channelReference{teamName: matches[1], channelName: matches[2]}
+	}
+
+	if strings.HasPrefix(input, "~") {
+		return channelReference{channelName: strings.ToLower(strings.TrimPrefix(input, "~"))}
+	}
+	if matches := teamChannelNameRegex.FindStringSubmatch(strings.ToLower(input)); matches != nil {
+		return channelReference{teamName: matches[1], channelName: matches[2]}
+	}
+
+	return channelReference{channelName: strings.ToLower(input), displayName: input}
+}
+
+func (r channelReference) matches(team *model.Team, channel *model.Channel) bool {
+	if len(r.teamName) != 0 && team.Name != r.teamName {
+		return false
+	}
+	if channel.Name == r.channelName {
+		return true
+	}
+
+	return len(r.displayName) != 0 && strings.EqualFold(channel.DisplayName, r.displayName)
+}
+
+// resolveChannel resolves a channel argument to a channel that the user is a
+// member of. The argument can be a channel ID, ~channel-name,
+// team-name:channel-name, channel display name or channel link. Arguments
+// that could be a channel ID are looked up as one first. Names that match
+// channels in several teams are rejected as ambiguous with the list of
+// matching channels, rather than guessing which one was meant. A non-empty
+// message explains why the argument couldn't be resolved.
+func (p *Plugin) resolveChannel(input string, extra *model.CommandArgs) (*model.Channel, string, error) {
+	if !strings.ContainsAny(input, "~/: ") {
+		_, appErr := p.API.GetChannelMember(input, extra.UserId)
+		if appErr == nil {
+			channel, appErr := p.API.GetChannel(input)
+			if appErr != nil {
+				return nil, "", errors.Errorf("unable to get channel with ID %s", input)
+			}
+			return channel, "", nil
+		}
+		if model.IsValidId(input) {
+			return nil, fmt.Sprintf("Error: channel with ID %s doesn't exist or you are not a member", input), nil
+		}
+	}
+
+	reference := parseChannelReference(strings.TrimSpace(input), extra.SiteURL)
+	if len(reference.channelName) == 0 {
+		return nil, "Error: a channel is required", nil
+	}
+
+	teams, appErr := p.API.GetTeamsForUser(extra.UserId)
+	if appErr != nil {
+		return nil, "", errors.Wrap(appErr, "unable to get teams for user")
+	}
+
+	var matches []*model.Channel
+	teamNames := make(map[string]string)
+	for _, team := range teams {
+		if len(reference.teamName) != 0 && team.Name != reference.teamName {
+			continue
+		}
+		teamNames[team.Id] = team.Name
+
+		channels, appErr := p.API.GetChannelsForTeamForUser(team.Id, extra.UserId, false)
+		if appErr != nil {
+			return nil, "", errors.Wrapf(appErr, "unable to get channels for team %s", team.Id)
+		}
+		for _, channel := range channels {
+			if channel.IsGroupOrDirect() || !reference.matches(team, channel) {
+				continue
+			}
+			matches = append(matches, channel)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Sprintf("Error: unable to find a channel matching %s that you are a member of", input), nil
+	case 1:
+		return matches[0], "", nil
+	}
+
+	var candidates []string
+	for _, channel := range matches {
+		candidates = append(candidates, inlineCode(fmt.Sprintf("%s:%s", teamNames[channel.TeamId], channel.Name)))
+	}
+	sort.Strings(candidates)
+
+	return nil, fmt.Sprintf("Error: %s matches more than one channel; use one of %s", input, strings.Join(candidates, ", ")), nil
+}
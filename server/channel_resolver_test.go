@@ -0,0 +1,181 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChannelReference(t *testing.T) {
+	siteURL := "https://test.sampledomain.com"
+
+	tests := []struct {
+		name     string
+		input    string
+		expected channelReference
+	}{
+		{
+			name:     "mention",
+			input:    "~Town-Square",
+			expected: channelReference{channelName: "town-square"},
+		},
+		{
+			name:     "team and channel",
+			input:    "team-1:town-square",
+			expected: channelReference{teamName: "team-1", channelName: "town-square"},
+		},
+		{
+			name:     "channel link",
+			input:    siteURL + "/team-1/channels/town-square",
+			expected: channelReference{teamName: "team-1", channelName: "town-square"},
+		},
+		{
+			name:     "channel link on another site URL",
+			input:    "https://other.sampledomain.com/team-1/channels/town-square/",
+			expected: channelReference{teamName: "team-1", channelName: "town-square"},
+		},
+		{
+			name:     "display name",
+			input:    "Town Square",
+			expected: channelReference{channelName: "town square", displayName: "Town Square"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseChannelReference(tt.input, siteURL))
+		})
+	}
+}
+
+func TestResolveChannel(t *testing.T) {
+	team1 := &model.Team{
+		Id:   model.NewId(),
+		Name: "team-1",
+	}
+	team2 := &model.Team{
+		Id:   model.NewId(),
+		Name: "team-2",
+	}
+	townSquare1 := &model.Channel{
+		Id:          model.NewId(),
+		TeamId:      team1.Id,
+		Name:        "town-square",
+		DisplayName: "Town Square",
+		Type:        model.CHANNEL_OPEN,
+	}
+	offTopic1 := &model.Channel{
+		Id:          model.NewId(),
+		TeamId:      team1.Id,
+		Name:        "off-topic",
+		DisplayName: "Off Topic",
+		Type:        model.CHANNEL_OPEN,
+	}
+	townSquare2 := &model.Channel{
+		Id:          model.NewId(),
+		TeamId:      team2.Id,
+		Name:        "town-square",
+		DisplayName: "Town Square",
+		Type:        model.CHANNEL_OPEN,
+	}
+	userID := model.NewId()
+	siteURL := "https://test.sampledomain.com"
+
+	api := &plugintest.API{}
+	api.On("GetChannelMember", townSquare1.Id, userID).Return(&model.ChannelMember{}, nil)
+	api.On("GetChannelMember", mock.AnythingOfType("string"), userID).Return(nil, model.NewAppError("where", model.NewId(), nil, "not found", 0))
+	api.On("GetChannel", townSquare1.Id).Return(townSquare1, nil)
+	api.On("GetTeamsForUser", userID).Return([]*model.Team{team1, team2}, nil)
+	api.On("GetChannelsForTeamForUser", team1.Id, userID, false).Return([]*model.Channel{townSquare1, offTopic1}, nil)
+	api.On("GetChannelsForTeamForUser", team2.Id, userID, false).Return([]*model.Channel{townSquare2}, nil)
+
+	var p Plugin
+	p.SetAPI(api)
+
+	tests := []struct {
+		name            string
+		input           string
+		teamID          string
+		expectedChannel *model.Channel
+		expectedMessage string
+	}{
+		{
+			name:            "channel ID",
+			input:           townSquare1.Id,
+			expectedChannel: townSquare1,
+		},
+		{
+			name:            "channel ID the user is not a member of",
+			input:           offTopic1.Id,
+			expectedMessage: "doesn't exist or you are not a member",
+		},
+		{
+			name:            "unique mention",
+			input:           "~off-topic",
+			expectedChannel: offTopic1,
+		},
+		{
+			name:            "mention in several teams",
+			input:           "~town-square",
+			expectedMessage: "Error: ~town-square matches more than one channel; use one of `team-1:town-square`, `team-2:town-square`",
+		},
+		{
+			name:            "mention in several teams from one of them",
+			input:           "~town-square",
+			teamID:          team2.Id,
+			expectedMessage: "Error: ~town-square matches more than one channel; use one of `team-1:town-square`, `team-2:town-square`",
+		},
+		{
+			name:            "team and channel",
+			input:           "team-2:town-square",
+			expectedChannel: townSquare2,
+		},
+		{
+			name:            "channel link",
+			input:           siteURL + "/team-1/channels/town-square",
+			expectedChannel: townSquare1,
+		},
+		{
+			name:            "channel name",
+			input:           "off-topic",
+			expectedChannel: offTopic1,
+		},
+		{
+			name:            "display name",
+			input:           "off topic",
+			expectedChannel: offTopic1,
+		},
+		{
+			name:            "display name in several teams",
+			input:           "town square",
+			teamID:          team1.Id,
+			expectedMessage: "Error: town square matches more than one channel; use one of `team-1:town-square`, `team-2:town-square`",
+		},
+		{
+			name:            "unknown channel",
+			input:           "~unknown",
+			expectedMessage: "Error: unable to find a channel matching ~unknown that you are a member of",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel, message, err := p.resolveChannel(tt.input, &model.CommandArgs{
+				UserId:  userID,
+				TeamId:  tt.teamID,
+				SiteURL: siteURL,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedChannel, channel)
+			if len(tt.expectedMessage) == 0 {
+				assert.Empty(t, message)
+			} else {
+				assert.Contains(t, message, tt.expectedMessage)
+			}
+		})
+	}
+}
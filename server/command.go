@@ -6,42 +6,42 @@ import (
 
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
 )
 
-const helpText = `Wrangler Plugin - Slash Command Help
+const (
+	helpTextHeader = "Wrangler Plugin - Slash Command Help"
 
-%s
-%s
-
-/wrangler attach message [MESSAGE_ID_TO_ATTACH] [ROOT_MESSAGE_ID]
-  Attach a given message to a thread in the same channel
-    - Obtain the message IDs by running '/wrangler list messages' or via the 'Permalink' message dropdown option (it's the last part of the URL)
-%s
-/wrangler list channels [flags]
-  List the IDs of all channels you have joined
+	infoUsage = `/wrangler info [flags]
+  Shows plugin information
 	Flags:
-%s
-/wrangler list messages [flags]
-  List the IDs of recent messages in this channel
-    Flags:
-%s
-/wrangler info
-  Shows plugin information`
+%s`
+)
+
+// pluginInfo is the JSON output of the info command.
+type pluginInfo struct {
+	Version        string `json:"version"`
+	BuildHash      string `json:"build_hash"`
+	BuildHashShort string `json:"build_hash_short"`
+	BuildDate      string `json:"build_date"`
+}
+
+func getInfoFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("info", pflag.ContinueOnError)
+	addFormatFlag(flagSet)
+
+	return flagSet
+}
+
+func getInfoUsage() string {
+	return fmt.Sprintf(infoUsage, getInfoFlagSet().FlagUsages())
+}
 
 func (p *Plugin) getHelp() string {
-	var optionalMergeThread string
-	if p.getConfiguration().MergeThreadEnable {
-		optionalMergeThread = mergeThreadUsage
-	}
+	usages := getCommandTree().usages(p.getConfiguration().MergeThreadEnable)
 
-	return codeBlock(fmt.Sprintf(
-		helpText,
-		getMoveThreadUsage(),
-		copyThreadUsage,
-		optionalMergeThread,
-		getListChannelsFlagSet().FlagUsages(),
-		getListMessagesFlagSet().FlagUsages(),
-	))
+	return codeBlock(fmt.Sprintf("%s\n\n%s", helpTextHeader, strings.Join(usages, "\n\n")))
 }
 
 func getCommand(autocomplete, mergedEnabled bool) *model.Command {
@@ -50,7 +50,7 @@ func getCommand(autocomplete, mergedEnabled bool) *model.Command {
 		DisplayName:      "Wrangler",
 		Description:      "Manage Mattermost messages!",
 		AutoComplete:     autocomplete,
-		AutoCompleteDesc: "Available commands: move thread, copy thread, attach message, list messages, list channels, info",
+		AutoCompleteDesc: "Available commands: " + strings.Join(getCommandTree().commandNames(mergedEnabled), ", "),
 		AutoCompleteHint: "[command]",
 		AutocompleteData: getAutocompleteData(mergedEnabled),
 	}
@@ -65,91 +65,66 @@ func getCommandResponse(responseType, text string) *model.CommandResponse {
 	}
 }
 
+// wranglerResult describes the thread resulting from a command that wrangled
+// messages. When the messages are wrangled by a background job, only the job
+// ID is set.
+type wranglerResult struct {
+	PostID       string
+	ChannelID    string
+	MessageCount int
+	OperationID  string
+	JobID        string
+}
+
+// noWranglerResult is returned by commands that end without wrangling any
+// messages.
+func noWranglerResult(resp *model.CommandResponse, userError bool, err error) (*model.CommandResponse, *wranglerResult, bool, error) {
+	return resp, nil, userError, err
+}
+
+// withoutWranglerResult drops the result of a command for callers that only
+// report the command response.
+func withoutWranglerResult(resp *model.CommandResponse, _ *wranglerResult, userError bool, err error) (*model.CommandResponse, bool, error) {
+	return resp, userError, err
+}
+
 // ExecuteCommand executes a given command and returns a command response.
 func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
-	if !p.authorizedPluginUser(args.UserId) {
+	mergeEnabled := p.getConfiguration().MergeThreadEnable
+	if !p.authorizedPluginUser(args.UserId) && !isAllUsersCommand(args.Command, mergeEnabled) {
 		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Permission denied. Please talk to your system administrator to get access."), nil
 	}
 
-	stringArgs := strings.Split(args.Command, " ")
-
+	stringArgs, err := splitCommandArgs(args.Command)
+	if err != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("__Error: %s__\n\nRun `/wrangler help` for usage instructions.", err.Error())), nil
+	}
 	if len(stringArgs) < 2 {
 		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, p.getHelp()), nil
 	}
 
-	command := stringArgs[1]
-
-	var handler func([]string, *model.CommandArgs) (*model.CommandResponse, bool, error)
-
-	switch command {
-	case "move":
-		if len(stringArgs) < 3 {
-			break
-		}
-
-		switch stringArgs[2] {
-		case "thread":
-			handler = p.runMoveThreadCommand
-			stringArgs = stringArgs[3:]
-		}
-	case "copy":
-		if len(stringArgs) < 3 {
-			break
-		}
-
-		switch stringArgs[2] {
-		case "thread":
-			handler = p.runCopyThreadCommand
-			stringArgs = stringArgs[3:]
-		}
-	case "attach":
-		if len(stringArgs) < 3 {
-			break
-		}
-
-		switch stringArgs[2] {
-		case "message":
-			handler = p.runAttachMessageCommand
-			stringArgs = stringArgs[3:]
-		}
-	case "merge":
-		if len(stringArgs) < 3 {
-			break
-		}
-
-		switch stringArgs[2] {
-		case "thread":
-			handler = p.runMergeThreadCommand
-			stringArgs = stringArgs[3:]
-		}
-	case "list":
-		if len(stringArgs) < 3 {
-			break
-		}
-
-		switch stringArgs[2] {
-		case "channels":
-			handler = p.runListChannelsCommand
-			stringArgs = stringArgs[3:]
-		case "messages":
-			handler = p.runListMessagesCommand
-			stringArgs = stringArgs[3:]
+	command, path, stringArgs := getCommandTree().findCommand(stringArgs[1:], mergeEnabled)
+	if command.handler == nil {
+		var name string
+		if len(stringArgs) != 0 {
+			name = stringArgs[0]
 		}
-	case "info":
-		handler = p.runInfoCommand
-		stringArgs = stringArgs[2:]
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getUnknownCommandMessage(command, path, name, mergeEnabled)), nil
 	}
 
-	if handler == nil {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, p.getHelp()), nil
+	category := auditCategoryOther
+	if command.wrangles {
+		category = auditCategoryWrangle
 	}
-
-	resp, userError, err := handler(stringArgs, args)
+	// The words consumed while routing make up the name of the command.
+	audit := p.startCommandAudit(strings.Join(path, " "), category, stringArgs, args)
+	resp, userError, err := command.handler(p, stringArgs, args, audit)
+	p.finishCommandAudit(audit, resp, userError, err)
 
 	if err != nil {
 		p.API.LogError(err.Error())
 		if userError {
-			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("__Error: %s__\n\nRun `/wrangler help` for usage instructions.", err.Error())), nil
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("__Error: %s__\n\nRun `/wrangler help %s` for usage instructions.", err.Error(), strings.Join(path, " "))), nil
 		}
 
 		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "An unknown error occurred. Please talk to your administrator for help."), nil
@@ -158,10 +133,52 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 	return resp, nil
 }
 
+// isAllUsersCommand returns if the command can be run by users who are not
+// permitted to use Wrangler.
+func isAllUsersCommand(command string, mergeEnabled bool) bool {
+	stringArgs, err := splitCommandArgs(command)
+	if err != nil || len(stringArgs) < 2 {
+		return false
+	}
+	found, _, _ := getCommandTree().findCommand(stringArgs[1:], mergeEnabled)
+
+	return found.allUsers
+}
+
 func (p *Plugin) runInfoCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
-	resp := fmt.Sprintf("Wrangler plugin version: %s, "+
-		"[%s](https://github.com/gabrieljackson/mattermost-plugin-wrangler/commit/%s), built %s\n\n",
-		manifest.Version, BuildHashShort, BuildHash, BuildDate)
+	flagSet := getInfoFlagSet()
+	err := flagSet.Parse(args)
+	if err != nil {
+		return nil, true, errors.Wrap(err, "unable to parse info flag args")
+	}
+	format, err := getFormatFlag(flagSet)
+	if err != nil {
+		return nil, true, err
+	}
+
+	var resp string
+	switch format {
+	case formatJSON:
+		resp, err = formatJSONOutput(&pluginInfo{
+			Version:        manifest.Version,
+			BuildHash:      BuildHash,
+			BuildHashShort: BuildHashShort,
+			BuildDate:      BuildDate,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+	case formatMarkdown:
+		resp = markdownTable([]string{"Version", "Commit", "Built"}, [][]string{{
+			manifest.Version,
+			fmt.Sprintf("[%s](https://github.com/gabrieljackson/mattermost-plugin-wrangler/commit/%s)", BuildHashShort, BuildHash),
+			BuildDate,
+		}})
+	default:
+		resp = fmt.Sprintf("Wrangler plugin version: %s, "+
+			"[%s](https://github.com/gabrieljackson/mattermost-plugin-wrangler/commit/%s), built %s\n\n",
+			manifest.Version, BuildHashShort, BuildHash, BuildDate)
+	}
 
 	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, resp), false, nil
 }
@@ -222,50 +239,17 @@ func (p *Plugin) authorizedPluginUser(userID string) bool {
 }
 
 func getAutocompleteData(mergedEnabled bool) *model.AutocompleteData {
-	wrangler := model.NewAutocompleteData("wrangler", "[command]", "Available commands: move, copy, attach, list, info, help")
-
-	move := model.NewAutocompleteData("move", "[subcommand]", "Move messages")
-	moveThread := model.NewAutocompleteData("thread", "[MESSAGE_ID or MESSAGE_LINK] [CHANNEL_ID]", "Move a message and the thread it belongs to")
-	moveThread.AddTextArgument("The ID of the message or a direct link to the message to be moved", "[MESSAGE_ID or MESSAGE_LINK]", "")
-	moveThread.AddTextArgument("The ID of the channel where the message will be moved to", "[CHANNEL_ID]", "")
-	move.AddCommand(moveThread)
-	wrangler.AddCommand(move)
-
-	copy := model.NewAutocompleteData("copy", "[subcommand]", "Copy messages")
-	copyThread := model.NewAutocompleteData("thread", "[MESSAGE_ID or MESSAGE_LINK] [CHANNEL_ID]", "Copy a message and the thread it belongs to")
-	copyThread.AddTextArgument("The ID of the message or a direct link to the message to be copied", "[MESSAGE_ID or MESSAGE_LINK]", "")
-	copyThread.AddTextArgument("The ID of the channel where the message will be copied to", "[CHANNEL_ID]", "")
-	copy.AddCommand(copyThread)
-	wrangler.AddCommand(copy)
-
-	attach := model.NewAutocompleteData("attach", "[subcommand]", "Attach messages")
-	attachMessage := model.NewAutocompleteData("message", "[MESSAGE_ID_TO_ATTACH or MESSAGE_LINK_TO_ATTACH] [ROOT_MESSAGE_ID or ROOT_MESSAGE_LINK]", "Attach a message to a thread in the channel")
-	attachMessage.AddTextArgument("The ID of the message or a direct link to the message to be attached", "[MESSAGE_ID_TO_ATTACH or MESSAGE_LINK_TO_ATTACH]", "")
-	attachMessage.AddTextArgument("The root message ID or a direct link to the root message of the thread", "[ROOT_MESSAGE_ID or ROOT_MESSAGE_LINK]", "")
-	attach.AddCommand(attachMessage)
-	wrangler.AddCommand(attach)
-
-	if mergedEnabled {
-		merge := model.NewAutocompleteData("merge", "[subcommand]", "Merge threads")
-		mergeThread := model.NewAutocompleteData("thread", "[ROOT_MESSAGE_ID or ROOT_MESSAGE_LINK] [TARGET_ROOT_MESSAGE_ID or TARGET_MESSAGE_LINK]", "Merge a thread's messages into another existing thread")
-		mergeThread.AddTextArgument("The root message ID or a direct link to the root message of the thread to be merged", "[ROOT_MESSAGE_ID or ROOT_MESSAGE_LINK]", "")
-		mergeThread.AddTextArgument("The root message ID or a direct link to the root message of the thread to merge into", "[TARGET_ROOT_MESSAGE_ID or TARGET_ROOT_MESSAGE_LINK]", "")
-		merge.AddCommand(mergeThread)
-		wrangler.AddCommand(merge)
-	}
+	wrangler := getCommandTree()
+	wrangler.description = "Available commands: " + strings.Join(subcommandNames(wrangler.visibleSubcommands(mergedEnabled)), ", ")
 
-	list := model.NewAutocompleteData("list", "[subcommand]", "Lists IDs for channels and messages")
-	listChannels := model.NewAutocompleteData("channels", "[optional flags]", "List channel IDs that you have joined")
-	listMessages := model.NewAutocompleteData("messages", "[optional flags]", "List message IDs in this channel")
-	list.AddCommand(listChannels)
-	list.AddCommand(listMessages)
-	wrangler.AddCommand(list)
-
-	info := model.NewAutocompleteData("info", "", "Shows plugin information")
-	wrangler.AddCommand(info)
+	return wrangler.autocompleteData(mergedEnabled)
+}
 
-	help := model.NewAutocompleteData("help", "", "Shows detailed help information")
-	wrangler.AddCommand(help)
+func subcommandNames(subcommands []*wranglerCommand) []string {
+	var names []string
+	for _, subcommand := range subcommands {
+		names = append(names, subcommand.name)
+	}
 
-	return wrangler
+	return names
 }
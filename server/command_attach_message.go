@@ -7,49 +7,52 @@ import (
 	"github.com/pkg/errors"
 )
 
-const attachMessageCommand = `Error: missing arguments
-
-/wrangler attach message [MESSAGE_ID_TO_BE_ATTACHED or MESSAGE_LINK_TO_BE_ATTACHED] [ROOT_MESSAGE_ID or ROOT_MESSAGE_LINK]
-	Attach a given message to a thread in the same channel
-	  - Obtain the message IDs by running '/wrangler list messages' or via the 'Permalink' message dropdown option (it's the last part of the URL)
-`
+const attachMessageUsage = `/wrangler attach message [MESSAGE_ID_TO_BE_ATTACHED or MESSAGE_LINK_TO_BE_ATTACHED] [ROOT_MESSAGE_ID or ROOT_MESSAGE_LINK]
+  Attach a given message to a thread in the same channel
+    - Obtain the message IDs by running '/wrangler list messages' or via the 'Permalink' message dropdown option (it's the last part of the URL)`
 
 func getAttachMessageCommand() string {
-	return codeBlock(attachMessageCommand)
+	return codeBlock(fmt.Sprintf("Error: missing arguments\n\n%s", attachMessageUsage))
+}
+
+func (p *Plugin) runAttachMessageCommand(args []string, extra *model.CommandArgs, audit *AuditRecord) (*model.CommandResponse, bool, error) {
+	return withoutWranglerResult(p.runAttachMessageCommandWithResult(args, extra, audit))
 }
 
-func (p *Plugin) runAttachMessageCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+// runAttachMessageCommandWithResult runs the attach message command and also
+// returns the thread the message was attached to.
+func (p *Plugin) runAttachMessageCommandWithResult(args []string, extra *model.CommandArgs, audit *AuditRecord) (*model.CommandResponse, *wranglerResult, bool, error) {
 	if len(args) < 2 {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getAttachMessageCommand()), true, nil
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getAttachMessageCommand()), nil, true, nil
 	}
 	postToBeAttachedID := cleanInputID(args[0], extra.SiteURL)
 	postToAttachToID := cleanInputID(args[1], extra.SiteURL)
 
 	if postToBeAttachedID == postToAttachToID {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the two provided message IDs should not be the same"), true, nil
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the two provided message IDs should not be the same"), nil, true, nil
 	}
 
 	postToBeAttached, appErr := p.API.GetPost(postToBeAttachedID)
 	if appErr != nil {
 
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get message with ID %s; ensure this is correct", postToBeAttachedID)), true, nil
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get message with ID %s; ensure this is correct", postToBeAttachedID)), nil, true, nil
 	}
 	postToAttachTo, appErr := p.API.GetPost(postToAttachToID)
 	if appErr != nil {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get message with ID %s; ensure this is correct", postToAttachToID)), true, nil
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get message with ID %s; ensure this is correct", postToAttachToID)), nil, true, nil
 	}
 
 	if postToBeAttached.ChannelId != extra.ChannelId {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the attach command must be run from the channel containing the messages"), true, nil
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the attach command must be run from the channel containing the messages"), nil, true, nil
 	}
 	if postToAttachTo.ChannelId != extra.ChannelId {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: unable to attach message to a thread in another channel"), true, nil
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: unable to attach message to a thread in another channel"), nil, true, nil
 	}
 	if len(postToBeAttached.RootId) != 0 || len(postToBeAttached.ParentId) != 0 {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the message to be attached is already part of a thread"), true, nil
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the message to be attached is already part of a thread"), nil, true, nil
 	}
 	if extra.RootId == postToBeAttached.Id || extra.ParentId == postToBeAttached.Id {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the 'attach message' command cannot be run from inside the thread of the message being attached; please run directly in the channel containing the message you wish to attach"), true, nil
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the 'attach message' command cannot be run from inside the thread of the message being attached; please run directly in the channel containing the message you wish to attach"), nil, true, nil
 	}
 
 	// We now know:
@@ -61,7 +64,7 @@ func (p *Plugin) runAttachMessageCommand(args []string, extra *model.CommandArgs
 
 	currentTeam, appErr := p.API.GetTeam(extra.TeamId)
 	if appErr != nil {
-		return nil, false, errors.Wrap(appErr, "failed to lookup lookup team")
+		return nil, nil, false, errors.Wrap(appErr, "failed to lookup lookup team")
 	}
 
 	newRootID := postToAttachTo.Id
@@ -70,6 +73,32 @@ func (p *Plugin) runAttachMessageCommand(args []string, extra *model.CommandArgs
 	}
 	cleanupID := postToBeAttached.Id
 
+	audit.setWranglerDetails(postToBeAttached.Id, postToBeAttached.ChannelId, newRootID, postToAttachTo.ChannelId, 1)
+
+	// The message being attached is locked along with the thread so that it
+	// can't be wrangled elsewhere at the same time.
+	lock, response, err := p.acquireThreadLock(operationTypeAttach, extra, postToBeAttached.Id, newRootID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if response != nil {
+		return response, nil, true, nil
+	}
+	defer p.releaseThreadLock(lock)
+
+	// The messages are read again now that they are locked.
+	postToBeAttached, appErr = p.API.GetPost(postToBeAttachedID)
+	if appErr != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getThreadChangedMessage(postToBeAttachedID)), nil, true, nil
+	}
+	if len(postToBeAttached.RootId) != 0 || len(postToBeAttached.ParentId) != 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the message to be attached is already part of a thread"), nil, true, nil
+	}
+	_, appErr = p.API.GetPost(newRootID)
+	if appErr != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getThreadChangedMessage(newRootID)), nil, true, nil
+	}
+
 	// Begin attaching message to the thread.
 	p.API.LogInfo("Wrangler is attaching a message",
 		"user_id", extra.UserId,
@@ -89,15 +118,15 @@ func (p *Plugin) runAttachMessageCommand(args []string, extra *model.CommandArgs
 		for _, fileID := range postToBeAttached.FileIds {
 			oldFileInfo, appErr = p.API.GetFileInfo(fileID)
 			if appErr != nil {
-				return nil, false, errors.Wrap(appErr, "unable to lookup file info to re-upload")
+				return nil, nil, false, errors.Wrap(appErr, "unable to lookup file info to re-upload")
 			}
 			fileBytes, appErr = p.API.GetFile(fileID)
 			if appErr != nil {
-				return nil, false, errors.Wrap(appErr, "unable to get file bytes to re-upload")
+				return nil, nil, false, errors.Wrap(appErr, "unable to get file bytes to re-upload")
 			}
 			newFileInfo, appErr = p.API.UploadFile(fileBytes, postToBeAttached.ChannelId, oldFileInfo.Name)
 			if appErr != nil {
-				return nil, false, errors.Wrap(appErr, "unable to re-upload file")
+				return nil, nil, false, errors.Wrap(appErr, "unable to re-upload file")
 			}
 
 			newFileIDs = append(newFileIDs, newFileInfo.Id)
@@ -109,7 +138,7 @@ func (p *Plugin) runAttachMessageCommand(args []string, extra *model.CommandArgs
 	// Store reactions to be reapplied later.
 	reactions, appErr := p.API.GetReactions(postToBeAttached.Id)
 	if appErr != nil {
-		return nil, false, errors.Wrap(appErr, "failed to get reactions on original post")
+		return nil, nil, false, errors.Wrap(appErr, "failed to get reactions on original post")
 	}
 
 	cleanPostID(postToBeAttached)
@@ -118,7 +147,7 @@ func (p *Plugin) runAttachMessageCommand(args []string, extra *model.CommandArgs
 
 	newPost, appErr := p.createPostForUser(postToBeAttached)
 	if appErr != nil {
-		return nil, false, errors.Wrap(appErr, "failed to create new post")
+		return nil, nil, false, errors.Wrap(appErr, "failed to create new post")
 	}
 
 	for _, reaction := range reactions {
@@ -131,7 +160,7 @@ func (p *Plugin) runAttachMessageCommand(args []string, extra *model.CommandArgs
 
 	appErr = p.API.DeletePost(cleanupID)
 	if appErr != nil {
-		return nil, false, errors.Wrap(appErr, "unable to delete post")
+		return nil, nil, false, errors.Wrap(appErr, "unable to delete post")
 	}
 
 	p.API.LogInfo("Wrangler has attached a message",
@@ -140,9 +169,27 @@ func (p *Plugin) runAttachMessageCommand(args []string, extra *model.CommandArgs
 		"new_root_id", newRootID,
 	)
 
+	operation := &WranglerOperation{
+		Type:              operationTypeAttach,
+		UserID:            extra.UserId,
+		OriginalChannelID: extra.ChannelId,
+		TargetChannelID:   extra.ChannelId,
+		OriginalRootID:    cleanupID,
+		NewRootID:         newRootID,
+		Posts:             []postIDPair{{OriginalID: cleanupID, NewID: newPost.Id}},
+	}
+	p.recordWranglerOperationOrLog(operation)
+	audit.OperationID = operation.ID
+	result := &wranglerResult{
+		PostID:       newRootID,
+		ChannelID:    extra.ChannelId,
+		MessageCount: 1,
+		OperationID:  operation.ID,
+	}
+
 	executor, execError := p.API.GetUser(extra.UserId)
 	if execError != nil {
-		return nil, false, errors.Wrap(appErr, "unable to find executor")
+		return nil, nil, false, errors.Wrap(execError, "unable to find executor")
 	}
 
 	if extra.UserId != postToBeAttached.UserId {
@@ -157,14 +204,14 @@ func (p *Plugin) runAttachMessageCommand(args []string, extra *model.CommandArgs
 		}
 	}
 
-	msg := fmt.Sprintf("Message successfully attached to thread")
+	msg := fmt.Sprintf("Message successfully attached to thread\n%s", operationSuffix(operation))
 
-	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), result, false, nil
 }
 
 func (p *Plugin) postAttachMessageBotDM(userID, newPostLink, executor string) error {
 	config := p.getConfiguration()
 	message := makeBotDM(config.ThreadAttachMessage, newPostLink, executor)
 
-	return p.PostBotDM(userID, message)
+	return p.notifyUser(userID, message)
 }
@@ -97,6 +97,7 @@ func TestAttachMessageCommand(t *testing.T) {
 	api.On("GetTeam", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(currentTeam, nil)
 	api.On("GetUser", mock.Anything).Return(executor, nil)
 	api.On("GetConfig", mock.Anything).Return(config)
+	mockKVStore(api)
 	api.On("LogInfo",
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("string"),
@@ -120,35 +121,35 @@ func TestAttachMessageCommand(t *testing.T) {
 	plugin.SetAPI(api)
 
 	t.Run("no args", func(t *testing.T) {
-		resp, isUserError, err := plugin.runAttachMessageCommand([]string{}, &model.CommandArgs{ChannelId: channel1.Id})
+		resp, isUserError, err := plugin.runAttachMessageCommand([]string{}, &model.CommandArgs{ChannelId: channel1.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: missing arguments")
 	})
 
 	t.Run("one arg", func(t *testing.T) {
-		resp, isUserError, err := plugin.runAttachMessageCommand([]string{"id1"}, &model.CommandArgs{ChannelId: channel1.Id})
+		resp, isUserError, err := plugin.runAttachMessageCommand([]string{"id1"}, &model.CommandArgs{ChannelId: channel1.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: missing arguments")
 	})
 
 	t.Run("post IDs are the same", func(t *testing.T) {
-		resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToAttachTo.Id, postToAttachTo.Id}, &model.CommandArgs{ChannelId: model.NewId()})
+		resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToAttachTo.Id, postToAttachTo.Id}, &model.CommandArgs{ChannelId: model.NewId()}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: the two provided message IDs should not be the same")
 	})
 
 	t.Run("post to be attached invalid", func(t *testing.T) {
-		resp, isUserError, err := plugin.runAttachMessageCommand([]string{model.NewId(), postToAttachTo.Id}, &model.CommandArgs{ChannelId: model.NewId()})
+		resp, isUserError, err := plugin.runAttachMessageCommand([]string{model.NewId(), postToAttachTo.Id}, &model.CommandArgs{ChannelId: model.NewId()}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: unable to get message with ID")
 	})
 
 	t.Run("post to be attached to invalid", func(t *testing.T) {
-		resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToBeAttached.Id, model.NewId()}, &model.CommandArgs{ChannelId: model.NewId()})
+		resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToBeAttached.Id, model.NewId()}, &model.CommandArgs{ChannelId: model.NewId()}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: unable to get message with ID")
@@ -156,7 +157,7 @@ func TestAttachMessageCommand(t *testing.T) {
 
 	t.Run("invalid command run location", func(t *testing.T) {
 		t.Run("not in channel with messages", func(t *testing.T) {
-			resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToBeAttached.Id, postToAttachTo.Id}, &model.CommandArgs{ChannelId: model.NewId()})
+			resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToBeAttached.Id, postToAttachTo.Id}, &model.CommandArgs{ChannelId: model.NewId()}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.True(t, isUserError)
 			assert.Contains(t, resp.Text, "Error: the attach command must be run from the channel containing the messages")
@@ -164,14 +165,14 @@ func TestAttachMessageCommand(t *testing.T) {
 
 		t.Run("in thread with message to be attached", func(t *testing.T) {
 			t.Run("parentId matches", func(t *testing.T) {
-				resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToBeAttached.Id, postToAttachTo.Id}, &model.CommandArgs{ChannelId: channel1.Id, ParentId: postToBeAttached.Id})
+				resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToBeAttached.Id, postToAttachTo.Id}, &model.CommandArgs{ChannelId: channel1.Id, ParentId: postToBeAttached.Id}, &AuditRecord{})
 				require.NoError(t, err)
 				assert.True(t, isUserError)
 				assert.Contains(t, resp.Text, "Error: the 'attach message' command cannot be run from inside the thread of the message being attached; please run directly in the channel containing the message you wish to attach")
 			})
 
 			t.Run("rootId matches", func(t *testing.T) {
-				resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToBeAttached.Id, postToAttachTo.Id}, &model.CommandArgs{ChannelId: channel1.Id, RootId: postToBeAttached.Id})
+				resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToBeAttached.Id, postToAttachTo.Id}, &model.CommandArgs{ChannelId: channel1.Id, RootId: postToBeAttached.Id}, &AuditRecord{})
 				require.NoError(t, err)
 				assert.True(t, isUserError)
 				assert.Contains(t, resp.Text, "Error: the 'attach message' command cannot be run from inside the thread of the message being attached; please run directly in the channel containing the message you wish to attach")
@@ -180,14 +181,14 @@ func TestAttachMessageCommand(t *testing.T) {
 	})
 
 	t.Run("attach to message in another channel", func(t *testing.T) {
-		resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToBeAttached.Id, postInAnotherChannel.Id}, &model.CommandArgs{ChannelId: channel1.Id})
+		resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToBeAttached.Id, postInAnotherChannel.Id}, &model.CommandArgs{ChannelId: channel1.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: unable to attach message to a thread in another channel")
 	})
 
 	t.Run("attach message already in another thread", func(t *testing.T) {
-		resp, isUserError, err := plugin.runAttachMessageCommand([]string{postInThreadAlready.Id, postToAttachTo.Id}, &model.CommandArgs{ChannelId: channel1.Id})
+		resp, isUserError, err := plugin.runAttachMessageCommand([]string{postInThreadAlready.Id, postToAttachTo.Id}, &model.CommandArgs{ChannelId: channel1.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: the message to be attached is already part of a thread")
@@ -197,7 +198,7 @@ func TestAttachMessageCommand(t *testing.T) {
 		plugin.setConfiguration(&configuration{MoveThreadToAnotherTeamEnable: true})
 		require.NoError(t, plugin.configuration.IsValid())
 
-		resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToBeAttached.Id, postToAttachTo.Id}, &model.CommandArgs{ChannelId: channel1.Id})
+		resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToBeAttached.Id, postToAttachTo.Id}, &model.CommandArgs{ChannelId: channel1.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.False(t, isUserError)
 		assert.Contains(t, resp.Text, "Message successfully attached to thread")
@@ -212,7 +213,7 @@ func TestAttachMessageCommand(t *testing.T) {
 		postToBeAttachedID := getMessageIDFromLink(postToBeAttachedLink, *config.ServiceSettings.SiteURL)
 		postToAttachToID := getMessageIDFromLink(postToAttachToLink, *config.ServiceSettings.SiteURL)
 
-		resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToBeAttachedID, postToAttachToID}, &model.CommandArgs{ChannelId: channel1.Id})
+		resp, isUserError, err := plugin.runAttachMessageCommand([]string{postToBeAttachedID, postToAttachToID}, &model.CommandArgs{ChannelId: channel1.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.False(t, isUserError)
 		assert.Contains(t, resp.Text, "Message successfully attached to thread")
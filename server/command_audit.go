@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	auditUsage = `/wrangler audit [flags]
+  List recorded Wrangler commands (system admins only)
+	Flags:
+%s`
+
+	flagAuditUser    = "user"
+	flagAuditChannel = "channel"
+	flagAuditSince   = "since"
+
+	defaultAuditSince = "7d"
+	maxAuditResults   = 50
+)
+
+type auditOptions struct {
+	user      string
+	channelID string
+	since     string
+}
+
+func getAuditFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("audit", pflag.ContinueOnError)
+	flagSet.String(flagAuditUser, "", "Only show commands run by this user (user ID or @username)")
+	flagSet.String(flagAuditChannel, "", "Only show commands run in, or wrangling messages from or to, this channel ID")
+	flagSet.String(flagAuditSince, defaultAuditSince, "Only show commands run since this date (YYYY-MM-DD) or for this long (e.g. 24h or 30d)")
+
+	return flagSet
+}
+
+func parseAuditFlagArgs(args []string) (auditOptions, error) {
+	var options auditOptions
+
+	flagSet := getAuditFlagSet()
+	err := flagSet.Parse(args)
+	if err != nil {
+		return options, errors.Wrap(err, "unable to parse audit flag args")
+	}
+
+	options.user, _ = flagSet.GetString(flagAuditUser)
+	options.channelID, _ = flagSet.GetString(flagAuditChannel)
+	options.since, _ = flagSet.GetString(flagAuditSince)
+
+	return options, nil
+}
+
+func getAuditUsage() string {
+	return fmt.Sprintf(auditUsage, getAuditFlagSet().FlagUsages())
+}
+
+// parseAuditSince converts a date in the YYYY-MM-DD format or a duration
+// before now to a timestamp in milliseconds. Durations support days with the
+// 'd' suffix in addition to the units supported by time.ParseDuration.
+func parseAuditSince(s string, now time.Time) (int64, error) {
+	if len(s) == 0 {
+		return 0, nil
+	}
+
+	date, err := time.Parse(auditDayFormat, s)
+	if err == nil {
+		return date.UnixNano() / int64(time.Millisecond), nil
+	}
+
+	var duration time.Duration
+	if strings.HasSuffix(s, "d") {
+		var days int
+		days, err = strconv.Atoi(strings.TrimSuffix(s, "d"))
+		duration = time.Duration(days) * 24 * time.Hour
+	} else {
+		duration, err = time.ParseDuration(s)
+	}
+	if err != nil || duration < 0 {
+		return 0, errors.Errorf("%s is not a valid date or duration", s)
+	}
+
+	return now.Add(-duration).UnixNano() / int64(time.Millisecond), nil
+}
+
+func (p *Plugin) runAuditCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	executor, appErr := p.API.GetUser(extra.UserId)
+	if appErr != nil {
+		return nil, false, errors.Wrap(appErr, "unable to find executor")
+	}
+	if !executor.IsSystemAdmin() {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: only system admins can view the audit log"), true, nil
+	}
+
+	options, err := parseAuditFlagArgs(args)
+	if err != nil {
+		return nil, true, err
+	}
+
+	since, err := parseAuditSince(options.since, time.Now())
+	if err != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: %s", err.Error())), true, nil
+	}
+	filter := auditFilter{
+		ChannelID: options.channelID,
+		Since:     since,
+	}
+	if len(options.user) != 0 {
+		filter.UserID = options.user
+		if strings.HasPrefix(options.user, "@") {
+			user, appErr := p.API.GetUserByUsername(strings.TrimPrefix(options.user, "@"))
+			if appErr != nil {
+				return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to find user %s", options.user)), true, nil
+			}
+			filter.UserID = user.Id
+		}
+	}
+
+	records, err := p.getAuditRecords(filter)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(records) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "No results found"), false, nil
+	}
+
+	msg := "Wrangler audit log:\n"
+	for i, record := range records {
+		if i == maxAuditResults {
+			msg += fmt.Sprintf("... and %d older record(s); narrow the results with flags or use the audit API\n", len(records)-maxAuditResults)
+			break
+		}
+
+		msg += fmt.Sprintf("%s - @%s - %s - %s",
+			time.Unix(0, record.CreateAt*int64(time.Millisecond)).UTC().Format(time.RFC822),
+			p.getUsernameOrID(record.UserID),
+			record.Command,
+			record.Result,
+		)
+		if record.MessageCount != 0 {
+			msg += fmt.Sprintf(" - %d message(s) from %s to %s", record.MessageCount, record.SourceChannelID, record.TargetChannelID)
+		}
+		if len(record.JobID) != 0 {
+			msg += fmt.Sprintf(" - job %s", record.JobID)
+		}
+		if len(record.Error) != 0 {
+			msg += fmt.Sprintf(" - %s", strings.ReplaceAll(record.Error, "\n", " "))
+		}
+		msg += "\n"
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, codeBlock(strings.TrimRight(msg, "\n"))), false, nil
+}
@@ -5,66 +5,166 @@ import (
 
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
 )
 
-const copyThreadUsage = `/wrangler copy thread [MESSAGE_ID or MESSAGE_LINK] [CHANNEL_ID]
+const copyThreadUsage = `/wrangler copy thread [MESSAGE_ID or MESSAGE_LINK] [CHANNEL]
   Copy a given message, along with the thread it belongs to, to a given channel
     - This can be on any channel in any team that you have joined
     - Obtain the message ID by running '/wrangler list messages' or via the 'Permalink' message dropdown option (it's the last part of the URL)
-    - Obtain the channel ID by running '/wrangler list channels' or via the channel 'View Info' option`
+    - The channel can be a channel ID, ~channel-name, team-name:channel-name, display name or channel link
+	Flags:
+%s`
+
+type copyThreadOptions struct {
+	dryRun bool
+	notify string
+}
+
+func getCopyThreadFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("copy thread", pflag.ContinueOnError)
+	flagSet.Bool(flagDryRun, false, "Show what would be copied without copying anything")
+	flagSet.String(flagNotify, "", "Who is sent a DM about the copied thread: root-author, all-participants or none (defaults to the plugin setting)")
+
+	return flagSet
+}
+
+func parseCopyThreadFlagArgs(args []string) (copyThreadOptions, error) {
+	var options copyThreadOptions
+
+	flagSet := getCopyThreadFlagSet()
+	err := flagSet.Parse(args)
+	if err != nil {
+		return options, errors.Wrap(err, "unable to parse copy thread flag args")
+	}
+
+	options.dryRun, _ = flagSet.GetBool(flagDryRun)
+	options.notify, _ = flagSet.GetString(flagNotify)
+	if len(options.notify) != 0 {
+		if _, err = parseNotifyPolicy(options.notify); err != nil {
+			return options, err
+		}
+	}
+
+	return options, nil
+}
+
+func getCopyThreadUsage() string {
+	return fmt.Sprintf(copyThreadUsage, getCopyThreadFlagSet().FlagUsages())
+}
 
 func getCopyThreadMessage() string {
-	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", copyThreadUsage))
+	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", getCopyThreadUsage()))
 }
 
-func (p *Plugin) runCopyThreadCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+func (p *Plugin) runCopyThreadCommand(args []string, extra *model.CommandArgs, audit *AuditRecord) (*model.CommandResponse, bool, error) {
+	return withoutWranglerResult(p.runCopyThreadCommandWithResult(args, extra, audit))
+}
+
+// runCopyThreadCommandWithResult runs the copy thread command and also returns the thread
+// the messages were copied.
+func (p *Plugin) runCopyThreadCommandWithResult(args []string, extra *model.CommandArgs, audit *AuditRecord) (*model.CommandResponse, *wranglerResult, bool, error) {
 	if len(args) < 2 {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getCopyThreadMessage()), true, nil
+		if len(extra.TriggerId) != 0 {
+			return noWranglerResult(p.openWranglerDialog(p.getWranglerDialogCommand("copy thread"), args, extra))
+		}
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getCopyThreadMessage()), nil, true, nil
+	}
+	options, err := parseCopyThreadFlagArgs(args)
+	if err != nil {
+		return nil, nil, true, err
 	}
+	options.notify = p.getNotifyPolicy(options.notify)
 	postID := cleanInputID(args[0], extra.SiteURL)
 	channelID := args[1]
 
 	postListResponse, appErr := p.API.GetPostThread(postID)
 	if appErr != nil {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get post with ID %s; ensure this is correct", postID)), true, nil
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get post with ID %s; ensure this is correct", postID)), nil, true, nil
 	}
 	wpl := buildWranglerPostList(postListResponse)
 
 	originalChannel, appErr := p.API.GetChannel(extra.ChannelId)
 	if appErr != nil {
-		return nil, false, fmt.Errorf("unable to get channel with ID %s", extra.ChannelId)
+		return nil, nil, false, fmt.Errorf("unable to get channel with ID %s", extra.ChannelId)
 	}
-	_, appErr = p.API.GetChannelMember(channelID, extra.UserId)
-	if appErr != nil {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: channel with ID %s doesn't exist or you are not a member", channelID)), true, nil
+	targetChannel, userMessage, err := p.resolveChannel(channelID, extra)
+	if err != nil {
+		return nil, nil, false, err
 	}
-	targetChannel, appErr := p.API.GetChannel(channelID)
-	if appErr != nil {
-		return nil, false, fmt.Errorf("unable to get channel with ID %s", channelID)
+	if len(userMessage) != 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, userMessage), nil, true, nil
 	}
 
 	response, userErr, err := p.validateMoveOrCopy(wpl, originalChannel, targetChannel, extra)
 	if response != nil || err != nil {
-		return response, userErr, err
+		return response, nil, userErr, err
 	}
 
 	targetTeam, appErr := p.API.GetTeam(targetChannel.TeamId)
 	if appErr != nil {
-		return nil, false, fmt.Errorf("unable to get team with ID %s", targetChannel.TeamId)
+		return nil, nil, false, fmt.Errorf("unable to get team with ID %s", targetChannel.TeamId)
+	}
+
+	audit.setWranglerDetails(wpl.RootPost().Id, originalChannel.Id, "", targetChannel.Id, wpl.NumPosts())
+
+	if options.dryRun {
+		dmUserIDs := p.getNotifiedUserIDs(wpl, options.notify, extra.UserId)
+		return noWranglerResult(p.buildDryRunResponse(wpl, "copied", fmt.Sprintf("to ~%s in team %s", targetChannel.Name, targetTeam.Name), dmUserIDs))
 	}
 
+	lock, response, err := p.acquireThreadLock(operationTypeCopy, extra, wpl.RootPost().Id)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if response != nil {
+		return response, nil, true, nil
+	}
+
+	// The thread is read and validated again now that it is locked.
+	wpl, response, userErr, err = p.getLockedThread(wpl.RootPost().Id)
+	if response == nil && err == nil {
+		response, userErr, err = p.validateMoveOrCopy(wpl, originalChannel, targetChannel, extra)
+	}
+	if response != nil || err != nil {
+		p.releaseThreadLock(lock)
+		return response, nil, userErr, err
+	}
+	audit.MessageCount = wpl.NumPosts()
+
+	if p.shouldRunAsJob(wpl) {
+		job := &WranglerJob{
+			Type:       jobTypeCopy,
+			UserID:     extra.UserId,
+			ChannelID:  extra.ChannelId,
+			RootPostID: wpl.RootPost().Id,
+			TotalPosts: wpl.NumPosts(),
+		}
+		return p.startWranglerJob(job, lock, audit, func(progress progressFunc, jobAudit *AuditRecord) (*model.CommandResponse, bool, error) {
+			return withoutWranglerResult(p.copyThread(wpl, originalChannel, targetChannel, targetTeam, options, extra, jobAudit, progress))
+		})
+	}
+
+	defer p.releaseThreadLock(lock)
+
+	return p.copyThread(wpl, originalChannel, targetChannel, targetTeam, options, extra, audit, nil)
+}
+
+// copyThread copies a validated thread to the target channel.
+func (p *Plugin) copyThread(wpl *WranglerPostList, originalChannel, targetChannel *model.Channel, targetTeam *model.Team, options copyThreadOptions, extra *model.CommandArgs, audit *AuditRecord, progress progressFunc) (*model.CommandResponse, *wranglerResult, bool, error) {
 	p.API.LogInfo("Wrangler is copying a thread",
 		"user_id", extra.UserId,
 		"original_post_id", wpl.RootPost().Id,
 		"original_channel_id", originalChannel.Id,
 	)
 
-	newRootPost, err := p.copyWranglerPostlist(wpl, targetChannel)
+	newRootPost, postIDs, err := p.copyWranglerPostlist(wpl, targetChannel, progress)
 	if err != nil {
-		return nil, false, err
+		return noWranglerResult(p.getRollbackResponse(err))
 	}
+	audit.TargetPostID = newRootPost.Id
 
-	_, appErr = p.API.CreatePost(&model.Post{
+	_, appErr := p.API.CreatePost(&model.Post{
 		UserId:    p.BotUserID,
 		RootId:    newRootPost.Id,
 		ParentId:  newRootPost.Id,
@@ -72,7 +172,12 @@ func (p *Plugin) runCopyThreadCommand(args []string, extra *model.CommandArgs) (
 		Message:   "This thread was copied from another channel",
 	})
 	if appErr != nil {
-		return nil, false, errors.Wrap(appErr, "unable to create new bot post")
+		return noWranglerResult(p.getRollbackResponse(p.rollback(errors.Wrap(appErr, "unable to create new bot post"), postIDs, nil)))
+	}
+	result := &wranglerResult{
+		PostID:       newRootPost.Id,
+		ChannelID:    targetChannel.Id,
+		MessageCount: wpl.NumPosts(),
 	}
 
 	newPostLink := makePostLink(*p.API.GetConfig().ServiceSettings.SiteURL, targetTeam.Name, newRootPost.Id)
@@ -84,38 +189,38 @@ func (p *Plugin) runCopyThreadCommand(args []string, extra *model.CommandArgs) (
 		Message:   fmt.Sprintf("A copy of this thread has been made: %s", newPostLink),
 	})
 	if appErr != nil {
-		return nil, false, errors.Wrap(appErr, "unable to create new bot post")
+		return nil, nil, false, errors.Wrap(appErr, "unable to create new bot post")
 	}
 
 	p.API.LogInfo("Wrangler thread copy complete",
 		"user_id", extra.UserId,
 		"new_post_id", newRootPost.Id,
-		"new_channel_id", channelID,
+		"new_channel_id", targetChannel.Id,
 	)
 
 	executor, execError := p.API.GetUser(extra.UserId)
 	if execError != nil {
-		return nil, false, errors.Wrap(appErr, "unable to find executor")
+		return nil, nil, false, errors.Wrap(execError, "unable to find executor")
 	}
 
-	if extra.UserId != wpl.RootPost().UserId {
-		// The wrangled thread was not started by the user running the command.
-		// Send a DM to the user who created the root message to let them know.
-		err := p.postCopyThreadBotDM(wpl.RootPost().UserId, newPostLink, executor.Username)
+	// Let the users of the thread other than the one running the command know
+	// where the copy is.
+	for _, userID := range p.getNotifiedUserIDs(wpl, options.notify, extra.UserId) {
+		err := p.postCopyThreadBotDM(userID, newPostLink, executor.Username)
 		if err != nil {
 			p.API.LogError("Unable to send copy-thread DM to user",
 				"error", err.Error(),
-				"user_id", wpl.RootPost().UserId,
+				"user_id", userID,
 			)
 		}
 	}
 
-	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Thread copy complete"), false, nil
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Thread copy complete"), result, false, nil
 }
 
 func (p *Plugin) postCopyThreadBotDM(userID, newPostLink, executor string) error {
 	config := p.getConfiguration()
 	message := makeBotDM(config.CopyThreadMessage, newPostLink, executor)
 
-	return p.PostBotDM(userID, message)
+	return p.notifyUser(userID, message)
 }
@@ -81,6 +81,7 @@ func TestCopyThreadCommand(t *testing.T) {
 	originalPostByLinkID := generatedPostsByLink.ToSlice()[0].Id
 
 	api := &plugintest.API{}
+	mockKVStore(api)
 	api.On("GetChannel", originalChannel.Id).Return(originalChannel, nil)
 	api.On("GetChannel", privateChannel.Id).Return(privateChannel, nil)
 	api.On("GetChannel", directChannel.Id).Return(directChannel, nil)
@@ -114,14 +115,14 @@ func TestCopyThreadCommand(t *testing.T) {
 	plugin.SetAPI(api)
 
 	t.Run("no args", func(t *testing.T) {
-		resp, isUserError, err := plugin.runCopyThreadCommand([]string{}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runCopyThreadCommand([]string{}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: missing arguments")
 	})
 
 	t.Run("one arg", func(t *testing.T) {
-		resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1"}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1"}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: missing arguments")
@@ -132,7 +133,7 @@ func TestCopyThreadCommand(t *testing.T) {
 			plugin.setConfiguration(&configuration{MoveThreadFromPrivateChannelEnable: false})
 			require.NoError(t, plugin.configuration.IsValid())
 
-			resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: privateChannel.Id})
+			resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: privateChannel.Id}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.False(t, isUserError)
 			assert.Contains(t, resp.Text, "Wrangler is currently configured to not allow moving posts from private channels")
@@ -144,7 +145,7 @@ func TestCopyThreadCommand(t *testing.T) {
 			plugin.setConfiguration(&configuration{MoveThreadFromDirectMessageChannelEnable: false})
 			require.NoError(t, plugin.configuration.IsValid())
 
-			resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: directChannel.Id})
+			resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: directChannel.Id}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.False(t, isUserError)
 			assert.Contains(t, resp.Text, "Wrangler is currently configured to not allow moving posts from direct message channels")
@@ -156,7 +157,7 @@ func TestCopyThreadCommand(t *testing.T) {
 			plugin.setConfiguration(&configuration{MoveThreadFromGroupMessageChannelEnable: false})
 			require.NoError(t, plugin.configuration.IsValid())
 
-			resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: groupChannel.Id})
+			resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: groupChannel.Id}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.False(t, isUserError)
 			assert.Contains(t, resp.Text, "Wrangler is currently configured to not allow moving posts from group message channels")
@@ -168,7 +169,7 @@ func TestCopyThreadCommand(t *testing.T) {
 			plugin.setConfiguration(&configuration{MoveThreadToAnotherTeamEnable: false})
 			require.NoError(t, plugin.configuration.IsValid())
 
-			resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id})
+			resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.False(t, isUserError)
 			assert.Contains(t, resp.Text, "Wrangler is currently configured to not allow moving messages to different teams")
@@ -179,7 +180,7 @@ func TestCopyThreadCommand(t *testing.T) {
 		plugin.setConfiguration(&configuration{MoveThreadToAnotherTeamEnable: true})
 
 		t.Run("not in thread channel", func(t *testing.T) {
-			resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: model.NewId()})
+			resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: model.NewId()}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.True(t, isUserError)
 			assert.Contains(t, resp.Text, "Error: this command must be run from the channel containing the post")
@@ -190,14 +191,14 @@ func TestCopyThreadCommand(t *testing.T) {
 
 		t.Run("in thread being copied", func(t *testing.T) {
 			t.Run("parentId matches", func(t *testing.T) {
-				resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id, ParentId: rootPostID})
+				resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id, ParentId: rootPostID}, &AuditRecord{})
 				require.NoError(t, err)
 				assert.True(t, isUserError)
 				assert.Contains(t, resp.Text, "Error: this command cannot be run from inside the thread; please run directly in the channel containing the thread")
 			})
 
 			t.Run("rootId matches", func(t *testing.T) {
-				resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id, RootId: rootPostID})
+				resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id, RootId: rootPostID}, &AuditRecord{})
 				require.NoError(t, err)
 				assert.True(t, isUserError)
 				assert.Contains(t, resp.Text, "Error: this command cannot be run from inside the thread; please run directly in the channel containing the thread")
@@ -211,7 +212,7 @@ func TestCopyThreadCommand(t *testing.T) {
 	targetCall.Return(nil, &model.AppError{})
 
 	t.Run("no target channel member", func(t *testing.T) {
-		resp, isUserError, err := plugin.runCopyThreadCommand([]string{originalPostID, targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runCopyThreadCommand([]string{originalPostID, targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: channel with ID")
@@ -221,7 +222,7 @@ func TestCopyThreadCommand(t *testing.T) {
 	api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(mockGenerateChannelMember(), nil)
 
 	t.Run("no permission to create posts in target channel", func(t *testing.T) {
-		resp, isUserError, err := plugin.runCopyThreadCommand([]string{originalPostID, readOnlyChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runCopyThreadCommand([]string{originalPostID, readOnlyChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: you don't have permissions to create posts in channel read-only")
@@ -230,12 +231,22 @@ func TestCopyThreadCommand(t *testing.T) {
 	t.Run("copy thread successfully", func(t *testing.T) {
 		require.NoError(t, plugin.configuration.IsValid())
 
-		resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.False(t, isUserError)
 		assert.Contains(t, resp.Text, "Thread copy complete")
 	})
 
+	t.Run("copy thread dry run", func(t *testing.T) {
+		require.NoError(t, plugin.configuration.IsValid())
+
+		resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2", "--dry-run"}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Dry run: no messages were copied")
+		assert.Contains(t, resp.Text, "3 message(s) would be copied to ~target-channel")
+	})
+
 	t.Run("copy thread by link successfully", func(t *testing.T) {
 		require.NoError(t, plugin.configuration.IsValid())
 
@@ -243,7 +254,7 @@ func TestCopyThreadCommand(t *testing.T) {
 		originalPostLink := fmt.Sprintf("https://%s/%s/pl/%s", *config.ServiceSettings.SiteURL, team1.Name, originalPostByLinkID)
 		cleanOriginalPostID := getMessageIDFromLink(originalPostLink, *config.ServiceSettings.SiteURL)
 
-		resp, isUserError, err := plugin.runCopyThreadCommand([]string{cleanOriginalPostID, targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runCopyThreadCommand([]string{cleanOriginalPostID, targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.False(t, isUserError)
 		assert.Contains(t, resp.Text, "Thread copy complete")
@@ -252,7 +263,7 @@ func TestCopyThreadCommand(t *testing.T) {
 	t.Run("thread is above configuration move-maximum", func(t *testing.T) {
 		plugin.setConfiguration(&configuration{MoveThreadMaxCount: "1"})
 		require.NoError(t, plugin.configuration.IsValid())
-		resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: model.NewId()})
+		resp, isUserError, err := plugin.runCopyThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: model.NewId()}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: the thread is 3 posts long, but this command is configured to only move threads of up to 1 posts")
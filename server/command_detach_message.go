@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const detachMessageUsage = `/wrangler detach message [MESSAGE_ID or MESSAGE_LINK]
+  Detach a given reply from its thread and turn it into a new message in the same channel
+    - Obtain the message ID by running '/wrangler list messages' or via the 'Permalink' message dropdown option (it's the last part of the URL)`
+
+func getDetachMessageMessage() string {
+	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", detachMessageUsage))
+}
+
+func (p *Plugin) runDetachMessageCommand(args []string, extra *model.CommandArgs, audit *AuditRecord) (*model.CommandResponse, bool, error) {
+	if len(args) < 1 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getDetachMessageMessage()), true, nil
+	}
+	postToBeDetachedID := cleanInputID(args[0], extra.SiteURL)
+
+	postToBeDetached, appErr := p.API.GetPost(postToBeDetachedID)
+	if appErr != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get message with ID %s; ensure this is correct", postToBeDetachedID)), true, nil
+	}
+
+	if postToBeDetached.ChannelId != extra.ChannelId {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the detach command must be run from the channel containing the message"), true, nil
+	}
+	if len(postToBeDetached.RootId) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the message to be detached is not a reply in a thread"), true, nil
+	}
+	if extra.RootId == postToBeDetached.RootId || extra.ParentId == postToBeDetached.RootId {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the 'detach message' command cannot be run from inside the thread of the message being detached; please run directly in the channel containing the thread"), true, nil
+	}
+
+	// We now know:
+	// 1. The post ID is valid.
+	// 2. The post is a reply in a thread.
+	// 3. The command was run from the channel containing the post, so they
+	//    are also a member of that channel.
+
+	channel, appErr := p.API.GetChannel(postToBeDetached.ChannelId)
+	if appErr != nil {
+		return nil, false, errors.Errorf("unable to get channel with ID %s", postToBeDetached.ChannelId)
+	}
+
+	originalRootID := postToBeDetached.RootId
+
+	audit.setWranglerDetails(postToBeDetached.Id, channel.Id, "", channel.Id, 1)
+
+	lock, response, err := p.acquireThreadLock(operationTypeDetach, extra, originalRootID)
+	if err != nil {
+		return nil, false, err
+	}
+	if response != nil {
+		return response, true, nil
+	}
+	defer p.releaseThreadLock(lock)
+
+	// Begin detaching the message from the thread.
+	p.API.LogInfo("Wrangler is detaching a message",
+		"user_id", extra.UserId,
+		"post_to_be_detached", postToBeDetachedID,
+		"original_root_id", originalRootID,
+	)
+
+	// Copying a single reply creates it as a new root post with its files,
+	// reactions and the wrangler prop.
+	wpl := newWranglerPostList([]*model.Post{postToBeDetached})
+	newPost, postIDs, err := p.copyWranglerPostlist(wpl, channel, nil)
+	if err != nil {
+		return p.getRollbackResponse(err)
+	}
+	audit.TargetPostID = newPost.Id
+
+	appErr = p.API.DeletePost(postToBeDetachedID)
+	if appErr != nil {
+		return p.getRollbackResponse(p.rollback(errors.Wrap(appErr, "unable to delete post"), postIDs, nil))
+	}
+
+	p.API.LogInfo("Wrangler has detached a message",
+		"user_id", extra.UserId,
+		"post_to_be_detached", postToBeDetachedID,
+		"new_post_id", newPost.Id,
+	)
+
+	operation := &WranglerOperation{
+		Type:              operationTypeDetach,
+		UserID:            extra.UserId,
+		OriginalChannelID: channel.Id,
+		TargetChannelID:   channel.Id,
+		OriginalRootID:    originalRootID,
+		NewRootID:         newPost.Id,
+		Posts:             postIDs,
+	}
+	p.recordWranglerOperationOrLog(operation)
+	audit.OperationID = operation.ID
+
+	executor, appErr := p.API.GetUser(extra.UserId)
+	if appErr != nil {
+		return nil, false, errors.Wrap(appErr, "unable to find executor")
+	}
+
+	if extra.UserId != postToBeDetached.UserId {
+		// The wrangled message was not created by the user running the command.
+		// Send a DM to the user who created it to let them know.
+		newPostLink, err := p.getPostLinkForChannel(channel, extra.TeamId, newPost.Id)
+		if err != nil {
+			return nil, false, err
+		}
+		err = p.postDetachMessageBotDM(postToBeDetached.UserId, newPostLink, executor.Username)
+		if err != nil {
+			p.API.LogError("Unable to send detach-message DM to user",
+				"error", err.Error(),
+				"user_id", postToBeDetached.UserId,
+			)
+		}
+	}
+
+	msg := fmt.Sprintf("Message successfully detached from thread\n%s", operationSuffix(operation))
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
+}
+
+func (p *Plugin) postDetachMessageBotDM(userID, newPostLink, executor string) error {
+	config := p.getConfiguration()
+	message := makeBotDM(config.ThreadDetachMessage, newPostLink, executor)
+
+	return p.notifyUser(userID, message)
+}
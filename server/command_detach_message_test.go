@@ -0,0 +1,138 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetachMessageCommand(t *testing.T) {
+	team := &model.Team{
+		Id:   model.NewId(),
+		Name: "team-1",
+	}
+	channel := &model.Channel{
+		Id:     model.NewId(),
+		TeamId: team.Id,
+		Name:   "channel",
+		Type:   model.CHANNEL_OPEN,
+	}
+	directChannel := &model.Channel{
+		Id:   model.NewId(),
+		Name: "direct-channel",
+		Type: model.CHANNEL_DIRECT,
+	}
+
+	rootPost := &model.Post{
+		Id:        model.NewId(),
+		UserId:    model.NewId(),
+		ChannelId: channel.Id,
+		Message:   "root message",
+	}
+	reply := &model.Post{
+		Id:        model.NewId(),
+		UserId:    model.NewId(),
+		ChannelId: channel.Id,
+		RootId:    rootPost.Id,
+		ParentId:  rootPost.Id,
+		Message:   "reply message",
+	}
+	replyInAnotherChannel := &model.Post{
+		Id:        model.NewId(),
+		ChannelId: model.NewId(),
+		RootId:    model.NewId(),
+	}
+
+	executor := &model.User{
+		Id:       model.NewId(),
+		Username: "executor",
+	}
+
+	config := &model.Config{
+		ServiceSettings: model.ServiceSettings{
+			SiteURL: NewString("test.sampledomain.com"),
+		},
+	}
+
+	api := &plugintest.API{}
+	mockKVStore(api)
+	api.On("GetPost", rootPost.Id).Return(rootPost, nil)
+	api.On("GetPost", reply.Id).Return(reply, nil)
+	api.On("GetPost", replyInAnotherChannel.Id).Return(replyInAnotherChannel, nil)
+	api.On("GetPost", mock.AnythingOfType("string")).Return(nil, &model.AppError{})
+	api.On("GetChannel", channel.Id).Return(channel, nil)
+	api.On("GetDirectChannel", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(directChannel, nil)
+	api.On("GetTeam", team.Id).Return(team, nil)
+	api.On("GetUser", executor.Id).Return(executor, nil)
+	api.On("GetConfig").Return(config)
+	api.On("GetReactions", mock.AnythingOfType("string")).Return(nil, nil)
+	api.On("CreatePost", mock.Anything).Return(mockGeneratePost(), nil)
+	api.On("DeletePost", mock.AnythingOfType("string")).Return(nil)
+	api.On("LogInfo",
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+	).Return(nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{
+		ThreadDetachMessage: "@{executor} wrangled one of your messages out of a thread for you: {postLink}",
+	})
+
+	t.Run("no args", func(t *testing.T) {
+		resp, isUserError, err := plugin.runDetachMessageCommand([]string{}, &model.CommandArgs{ChannelId: channel.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: missing arguments")
+	})
+
+	t.Run("invalid message ID", func(t *testing.T) {
+		resp, isUserError, err := plugin.runDetachMessageCommand([]string{"invalid"}, &model.CommandArgs{ChannelId: channel.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: unable to get message with ID invalid")
+	})
+
+	t.Run("message in another channel", func(t *testing.T) {
+		resp, isUserError, err := plugin.runDetachMessageCommand([]string{replyInAnotherChannel.Id}, &model.CommandArgs{ChannelId: channel.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: the detach command must be run from the channel containing the message")
+	})
+
+	t.Run("message is not a reply", func(t *testing.T) {
+		resp, isUserError, err := plugin.runDetachMessageCommand([]string{rootPost.Id}, &model.CommandArgs{ChannelId: channel.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: the message to be detached is not a reply in a thread")
+	})
+
+	t.Run("run from inside the thread", func(t *testing.T) {
+		resp, isUserError, err := plugin.runDetachMessageCommand([]string{reply.Id}, &model.CommandArgs{ChannelId: channel.Id, RootId: rootPost.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "cannot be run from inside the thread")
+	})
+
+	t.Run("detach message successfully", func(t *testing.T) {
+		audit := &AuditRecord{}
+		resp, isUserError, err := plugin.runDetachMessageCommand([]string{reply.Id}, &model.CommandArgs{ChannelId: channel.Id, TeamId: team.Id, UserId: executor.Id}, audit)
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Message successfully detached from thread")
+		assert.Contains(t, resp.Text, "/wrangler undo")
+		assert.NotEmpty(t, audit.OperationID)
+		assert.Contains(t, resp.Text, audit.OperationID)
+		api.AssertCalled(t, "DeletePost", reply.Id)
+		api.AssertCalled(t, "GetDirectChannel", reply.UserId, mock.AnythingOfType("string"))
+	})
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	findMessagesUsage = `/wrangler find messages [TERMS] [flags]
+  Search the channels you are a member of for messages and list their IDs
+    - TERMS use the same syntax as the Mattermost search box; quote phrases to search for them exactly
+	Flags:
+%s`
+
+	flagFindIn       = "in"
+	flagFindFrom     = "from"
+	flagFindAfter    = "after"
+	flagFindHasFiles = "has-files"
+	flagFindCount    = "count"
+
+	minFindMessagesCount = 1
+	maxFindMessagesCount = 100
+
+	findMessagesTrimLength = 50
+	findMessagesDateFormat = "2006-01-02"
+)
+
+type findMessagesOptions struct {
+	terms    string
+	in       string
+	from     string
+	after    string
+	hasFiles bool
+	count    int
+	format   string
+}
+
+// foundMessage is a message in the JSON output of the find messages command.
+type foundMessage struct {
+	ID          string `json:"id"`
+	RootID      string `json:"root_id"`
+	ChannelID   string `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+	ChannelType string `json:"channel_type"`
+	TeamID      string `json:"team_id"`
+	TeamName    string `json:"team_name"`
+	UserID      string `json:"user_id"`
+	Username    string `json:"username"`
+	Message     string `json:"message"`
+	CreateAt    int64  `json:"create_at"`
+	FileCount   int    `json:"file_count"`
+}
+
+// findMessagesOutput is the JSON output of the find messages command.
+type findMessagesOutput struct {
+	Terms    string          `json:"terms"`
+	Messages []*foundMessage `json:"messages"`
+}
+
+func getFindMessagesFlagSet() *pflag.FlagSet {
+	findMessagesFlagSet := pflag.NewFlagSet("find messages", pflag.ContinueOnError)
+	findMessagesFlagSet.String(flagFindIn, "", "Only find messages in this channel (~name, team:channel, ID or link)")
+	findMessagesFlagSet.String(flagFindFrom, "", "Only find messages posted by this user (@username or user ID)")
+	findMessagesFlagSet.String(flagFindAfter, "", "Only find messages posted after this date (YYYY-MM-DD)")
+	findMessagesFlagSet.Bool(flagFindHasFiles, false, "Only find messages with attached files")
+	findMessagesFlagSet.Int(flagFindCount, 20, fmt.Sprintf("Number of messages to return. Must be between %d and %d", minFindMessagesCount, maxFindMessagesCount))
+	addFormatFlag(findMessagesFlagSet)
+
+	return findMessagesFlagSet
+}
+
+func getFindMessagesUsage() string {
+	return fmt.Sprintf(findMessagesUsage, getFindMessagesFlagSet().FlagUsages())
+}
+
+func parseFindMessagesArgs(args []string) (findMessagesOptions, error) {
+	var options findMessagesOptions
+
+	findMessagesFlagSet := getFindMessagesFlagSet()
+	err := findMessagesFlagSet.Parse(args)
+	if err != nil {
+		return options, err
+	}
+
+	var terms []string
+	for _, term := range findMessagesFlagSet.Args() {
+		// Arguments with spaces were quoted phrases in the command.
+		if strings.Contains(term, " ") {
+			term = fmt.Sprintf("%q", term)
+		}
+		terms = append(terms, term)
+	}
+	options.terms = strings.Join(terms, " ")
+
+	options.in, _ = findMessagesFlagSet.GetString(flagFindIn)
+	options.from, _ = findMessagesFlagSet.GetString(flagFindFrom)
+	options.hasFiles, _ = findMessagesFlagSet.GetBool(flagFindHasFiles)
+
+	options.after, _ = findMessagesFlagSet.GetString(flagFindAfter)
+	if len(options.after) != 0 {
+		if _, err = time.Parse(findMessagesDateFormat, options.after); err != nil {
+			return options, errors.Errorf("%s (%s) must be a date in the YYYY-MM-DD format", flagFindAfter, options.after)
+		}
+	}
+
+	options.count, err = findMessagesFlagSet.GetInt(flagFindCount)
+	if err != nil {
+		return options, err
+	}
+	if options.count < minFindMessagesCount || options.count > maxFindMessagesCount {
+		return options, errors.Errorf("%s (%d) must be between %d and %d", flagFindCount, options.count, minFindMessagesCount, maxFindMessagesCount)
+	}
+
+	options.format, err = getFormatFlag(findMessagesFlagSet)
+	if err != nil {
+		return options, err
+	}
+
+	if len(options.terms) == 0 && len(options.in) == 0 && len(options.from) == 0 && len(options.after) == 0 {
+		return options, errors.Errorf("provide search terms or at least one of --%s, --%s or --%s", flagFindIn, flagFindFrom, flagFindAfter)
+	}
+
+	return options, nil
+}
+
+func (p *Plugin) runFindMessagesCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	options, err := parseFindMessagesArgs(args)
+	if err != nil {
+		return nil, true, err
+	}
+
+	terms := []string{options.terms}
+
+	var channel *model.Channel
+	if len(options.in) != 0 {
+		var userMessage string
+		channel, userMessage, err = p.resolveChannel(options.in, extra)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(userMessage) != 0 {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, userMessage), true, nil
+		}
+		// Direct and group message channels can't be searched by name, so
+		// their results are filtered below instead.
+		if !channel.IsGroupOrDirect() {
+			terms = append(terms, "in:"+channel.Name)
+		}
+	}
+
+	if len(options.from) != 0 {
+		var user *model.User
+		var appErr *model.AppError
+		if model.IsValidId(options.from) {
+			user, appErr = p.API.GetUser(options.from)
+		} else {
+			user, appErr = p.API.GetUserByUsername(strings.TrimPrefix(options.from, "@"))
+		}
+		if appErr != nil {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to find user %s", options.from)), true, nil
+		}
+		terms = append(terms, "from:"+user.Username)
+	}
+
+	if len(options.after) != 0 {
+		terms = append(terms, "after:"+options.after)
+	}
+
+	teams, appErr := p.API.GetTeamsForUser(extra.UserId)
+	if appErr != nil {
+		return nil, false, errors.Wrap(appErr, "unable to get teams for user")
+	}
+
+	location := p.getUserLocation(extra.UserId)
+	_, timeZoneOffset := time.Now().In(location).Zone()
+	searchTerms := strings.TrimSpace(strings.Join(terms, " "))
+	page := 0
+	perPage := maxFindMessagesCount
+	searchParameter := model.SearchParameter{
+		Terms:          &searchTerms,
+		TimeZoneOffset: &timeZoneOffset,
+		Page:           &page,
+		PerPage:        &perPage,
+	}
+
+	var posts []*model.Post
+	seen := make(map[string]bool)
+	teamsByID := make(map[string]*model.Team)
+	for _, team := range teams {
+		teamsByID[team.Id] = team
+		if channel != nil && !channel.IsGroupOrDirect() && channel.TeamId != team.Id {
+			continue
+		}
+
+		// Searches are limited to the channels the user is a member of.
+		results, appErr := p.API.SearchPostsInTeamForUser(team.Id, extra.UserId, searchParameter)
+		if appErr != nil {
+			return nil, false, errors.Wrapf(appErr, "unable to search posts in team %s", team.Id)
+		}
+
+		for _, post := range results.ToSlice() {
+			// Direct and group message results are returned for every team.
+			if seen[post.Id] {
+				continue
+			}
+			seen[post.Id] = true
+
+			if channel != nil && post.ChannelId != channel.Id {
+				continue
+			}
+			if options.hasFiles && len(post.FileIds) == 0 {
+				continue
+			}
+			posts = append(posts, post)
+		}
+	}
+
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].CreateAt > posts[j].CreateAt
+	})
+	if len(posts) > options.count {
+		posts = posts[:options.count]
+	}
+
+	found := []*foundMessage{}
+	channels := make(map[string]*model.Channel)
+	usernames := make(map[string]string)
+	for _, post := range posts {
+		postChannel, ok := channels[post.ChannelId]
+		if !ok {
+			postChannel, appErr = p.API.GetChannel(post.ChannelId)
+			if appErr != nil {
+				return nil, false, errors.Wrapf(appErr, "unable to get channel %s", post.ChannelId)
+			}
+			channels[post.ChannelId] = postChannel
+		}
+		if _, ok = usernames[post.UserId]; !ok {
+			usernames[post.UserId] = p.getUsernameOrID(post.UserId)
+		}
+
+		message := &foundMessage{
+			ID:          post.Id,
+			RootID:      post.RootId,
+			ChannelID:   postChannel.Id,
+			ChannelName: postChannel.Name,
+			ChannelType: postChannel.Type,
+			UserID:      post.UserId,
+			Username:    usernames[post.UserId],
+			Message:     post.Message,
+			CreateAt:    post.CreateAt,
+			FileCount:   len(post.FileIds),
+		}
+		if team, ok := teamsByID[postChannel.TeamId]; ok {
+			message.TeamID = team.Id
+			message.TeamName = team.Name
+		}
+		found = append(found, message)
+	}
+
+	var msg string
+	switch options.format {
+	case formatJSON:
+		msg, err = formatJSONOutput(&findMessagesOutput{Terms: searchTerms, Messages: found})
+		if err != nil {
+			return nil, false, err
+		}
+	case formatMarkdown:
+		var rows [][]string
+		for _, message := range found {
+			rows = append(rows, []string{
+				message.ID,
+				getFoundMessageChannel(message),
+				formatListMessagesTime(message.CreateAt, location),
+				"@" + message.Username,
+				cleanAndTrimMessage(message.Message, findMessagesTrimLength),
+			})
+		}
+		msg = fmt.Sprintf("Messages matching %s:\n\n", inlineCode(searchTerms))
+		msg += markdownTable([]string{"Message ID", "Channel", "Time", "Author", "Message"}, rows)
+	default:
+		msg = fmt.Sprintf("Messages matching %s:\n", inlineCode(searchTerms))
+		var lines []string
+		for _, message := range found {
+			lines = append(lines, fmt.Sprintf("%s - %s - %s - @%s - %s",
+				message.ID,
+				getFoundMessageChannel(message),
+				formatListMessagesTime(message.CreateAt, location),
+				message.Username,
+				cleanAndTrimMessage(message.Message, findMessagesTrimLength),
+			))
+		}
+		if len(lines) == 0 {
+			lines = append(lines, "No results found")
+		}
+		msg += codeBlock(strings.Join(lines, "\n"))
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
+}
+
+// getFoundMessageChannel returns the channel of a found message as it is
+// shown to the user.
+func getFoundMessageChannel(message *foundMessage) string {
+	switch message.ChannelType {
+	case model.CHANNEL_DIRECT:
+		return "direct message"
+	case model.CHANNEL_GROUP:
+		return "group message"
+	}
+
+	return fmt.Sprintf("%s:%s", message.TeamName, message.ChannelName)
+}
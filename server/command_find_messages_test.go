@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindMessagesCommand(t *testing.T) {
+	user := &model.User{Id: model.NewId(), Username: "user"}
+	alice := &model.User{Id: model.NewId(), Username: "alice"}
+	team1 := &model.Team{Id: model.NewId(), Name: "team1"}
+	team2 := &model.Team{Id: model.NewId(), Name: "team2"}
+	town := &model.Channel{Id: model.NewId(), TeamId: team1.Id, Name: "town-square", Type: model.CHANNEL_OPEN}
+	dev := &model.Channel{Id: model.NewId(), TeamId: team2.Id, Name: "dev", Type: model.CHANNEL_PRIVATE}
+	direct := &model.Channel{Id: model.NewId(), Name: model.GetDMNameFromIds(user.Id, alice.Id), Type: model.CHANNEL_DIRECT}
+
+	townPost := &model.Post{Id: model.NewId(), ChannelId: town.Id, UserId: alice.Id, Message: "release notes", CreateAt: 1000}
+	devPost := &model.Post{Id: model.NewId(), ChannelId: dev.Id, UserId: user.Id, Message: "release notes draft", CreateAt: 3000, FileIds: []string{model.NewId()}}
+	directPost := &model.Post{Id: model.NewId(), ChannelId: direct.Id, UserId: alice.Id, Message: "release notes?", CreateAt: 2000}
+
+	searchResults := func(posts ...*model.Post) *model.PostSearchResults {
+		postList := model.NewPostList()
+		for _, post := range posts {
+			postList.AddPost(post)
+			postList.AddOrder(post.Id)
+		}
+		return model.MakePostSearchResults(postList, nil)
+	}
+	searchTerms := func(terms string) interface{} {
+		return mock.MatchedBy(func(params model.SearchParameter) bool {
+			return *params.Terms == terms
+		})
+	}
+
+	api := &plugintest.API{}
+	api.On("GetTeamsForUser", user.Id).Return([]*model.Team{team1, team2}, nil)
+	api.On("GetUser", user.Id).Return(user, nil)
+	api.On("GetUser", alice.Id).Return(alice, nil)
+	api.On("GetUserByUsername", "alice").Return(alice, nil)
+	api.On("GetUserByUsername", "unknown").Return(nil, &model.AppError{})
+	api.On("GetChannel", town.Id).Return(town, nil)
+	api.On("GetChannel", dev.Id).Return(dev, nil)
+	api.On("GetChannel", direct.Id).Return(direct, nil)
+	api.On("GetChannelMember", town.Id, user.Id).Return(&model.ChannelMember{}, nil)
+	api.On("GetChannelMember", direct.Id, user.Id).Return(&model.ChannelMember{}, nil)
+	api.On("SearchPostsInTeamForUser", team1.Id, user.Id, searchTerms("release")).Return(searchResults(townPost, directPost), nil)
+	api.On("SearchPostsInTeamForUser", team2.Id, user.Id, searchTerms("release")).Return(searchResults(devPost, directPost), nil)
+	api.On("SearchPostsInTeamForUser", team1.Id, user.Id, searchTerms(`"release notes" in:town-square`)).Return(searchResults(townPost), nil)
+	api.On("SearchPostsInTeamForUser", mock.AnythingOfType("string"), user.Id, searchTerms("from:alice after:2021-06-01")).Return(searchResults(townPost, directPost), nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	find := func(t *testing.T, args ...string) *findMessagesOutput {
+		resp, isUserError, err := plugin.runFindMessagesCommand(append(args, "--format=json"), &model.CommandArgs{UserId: user.Id})
+		require.NoError(t, err)
+		require.False(t, isUserError, resp.Text)
+
+		var output findMessagesOutput
+		require.NoError(t, json.Unmarshal([]byte(resp.Text), &output))
+		return &output
+	}
+	ids := func(output *findMessagesOutput) []string {
+		var ids []string
+		for _, message := range output.Messages {
+			ids = append(ids, message.ID)
+		}
+		return ids
+	}
+
+	t.Run("search all teams", func(t *testing.T) {
+		output := find(t, "release")
+		assert.Equal(t, []string{devPost.Id, directPost.Id, townPost.Id}, ids(output))
+		assert.Equal(t, "team2", output.Messages[0].TeamName)
+		assert.Equal(t, "dev", output.Messages[0].ChannelName)
+		assert.Equal(t, "user", output.Messages[0].Username)
+		assert.Empty(t, output.Messages[1].TeamName)
+	})
+
+	t.Run("in channel", func(t *testing.T) {
+		output := find(t, "release notes", "--in", town.Id)
+		assert.Equal(t, `"release notes" in:town-square`, output.Terms)
+		assert.Equal(t, []string{townPost.Id}, ids(output))
+	})
+
+	t.Run("in direct message channel", func(t *testing.T) {
+		assert.Equal(t, []string{directPost.Id}, ids(find(t, "release", "--in", direct.Id)))
+	})
+
+	t.Run("from user after date", func(t *testing.T) {
+		assert.Equal(t, []string{directPost.Id, townPost.Id}, ids(find(t, "--from=@alice", "--after=2021-06-01")))
+	})
+
+	t.Run("has files", func(t *testing.T) {
+		assert.Equal(t, []string{devPost.Id}, ids(find(t, "release", "--has-files")))
+	})
+
+	t.Run("count", func(t *testing.T) {
+		assert.Equal(t, []string{devPost.Id}, ids(find(t, "release", "--count=1")))
+	})
+
+	t.Run("table", func(t *testing.T) {
+		resp, isUserError, err := plugin.runFindMessagesCommand([]string{"release"}, &model.CommandArgs{UserId: user.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Messages matching `release`:")
+		assert.Contains(t, resp.Text, devPost.Id+" - team2:dev - 1970-01-01 00:00 UTC - @user - release notes draft")
+		assert.Contains(t, resp.Text, directPost.Id+" - direct message - ")
+	})
+
+	t.Run("user errors", func(t *testing.T) {
+		_, isUserError, err := plugin.runFindMessagesCommand([]string{}, &model.CommandArgs{UserId: user.Id})
+		require.EqualError(t, err, "provide search terms or at least one of --in, --from or --after")
+		assert.True(t, isUserError)
+
+		_, isUserError, err = plugin.runFindMessagesCommand([]string{"--after=yesterday"}, &model.CommandArgs{UserId: user.Id})
+		require.EqualError(t, err, "after (yesterday) must be a date in the YYYY-MM-DD format")
+		assert.True(t, isUserError)
+
+		resp, isUserError, err := plugin.runFindMessagesCommand([]string{"--from=unknown"}, &model.CommandArgs{UserId: user.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Equal(t, "Error: unable to find user unknown", resp.Text)
+	})
+}
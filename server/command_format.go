@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	flagFormat = "format"
+
+	formatTable    = "table"
+	formatMarkdown = "markdown"
+	formatJSON     = "json"
+)
+
+// addFormatFlag adds the flag choosing the output format of a command.
+func addFormatFlag(flagSet *pflag.FlagSet) {
+	flagSet.String(flagFormat, formatTable, "The output format: table, markdown or json. The json output is a stable document meant for automation")
+}
+
+// getFormatFlag returns the validated output format of a parsed flag set.
+func getFormatFlag(flagSet *pflag.FlagSet) (string, error) {
+	format, err := flagSet.GetString(flagFormat)
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case formatTable, formatMarkdown, formatJSON:
+		return format, nil
+	}
+
+	return "", errors.Errorf("%s (%s) must be one of %s, %s or %s", flagFormat, format, formatTable, formatMarkdown, formatJSON)
+}
+
+// formatJSONOutput returns the JSON document of a command. The document is
+// the whole response text so that it can be parsed as is.
+func formatJSONOutput(v interface{}) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", errors.Wrap(err, "unable to marshal JSON output")
+	}
+
+	return string(data), nil
+}
+
+// markdownTable returns a markdown table with the given header and rows.
+func markdownTable(header []string, rows [][]string) string {
+	var separators []string
+	for range header {
+		separators = append(separators, "---")
+	}
+
+	lines := []string{markdownTableRow(header), markdownTableRow(separators)}
+	for _, row := range rows {
+		lines = append(lines, markdownTableRow(row))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func markdownTableRow(cells []string) string {
+	var escaped []string
+	for _, cell := range cells {
+		cell = strings.ReplaceAll(cell, "|", `\|`)
+		cell = strings.ReplaceAll(cell, "\n", " ")
+		escaped = append(escaped, cell)
+	}
+
+	return fmt.Sprintf("| %s |", strings.Join(escaped, " | "))
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetFormatFlag(t *testing.T) {
+	parse := func(args ...string) (string, error) {
+		flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		addFormatFlag(flagSet)
+		require.NoError(t, flagSet.Parse(args))
+		return getFormatFlag(flagSet)
+	}
+
+	format, err := parse()
+	require.NoError(t, err)
+	assert.Equal(t, formatTable, format)
+
+	format, err = parse("--format=json")
+	require.NoError(t, err)
+	assert.Equal(t, formatJSON, format)
+
+	_, err = parse("--format", "yaml")
+	require.EqualError(t, err, "format (yaml) must be one of table, markdown or json")
+}
+
+func TestMarkdownTable(t *testing.T) {
+	table := markdownTable([]string{"ID", "Message"}, [][]string{
+		{"id1", "a | b"},
+		{"id2", "line 1\nline 2"},
+	})
+
+	assert.Equal(t, "| ID | Message |\n| --- | --- |\n| id1 | a \\| b |\n| id2 | line 1 line 2 |", table)
+}
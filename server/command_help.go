@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// maxSuggestionDistance is the largest edit distance between an unknown
+// command and a command that is suggested instead.
+const maxSuggestionDistance = 2
+
+const helpUsage = `/wrangler help [COMMAND]
+  Show the usage, flags and examples of a command, or of all commands when none is given`
+
+func (p *Plugin) runHelpCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, p.getHelp()), false, nil
+	}
+
+	mergeEnabled := p.getConfiguration().MergeThreadEnable
+	command := getCommandTree()
+	var path []string
+	for _, name := range args {
+		// Commands with usage text describe their subcommands themselves.
+		if command.usage != nil {
+			break
+		}
+		subcommand := command.visibleSubcommand(name, mergeEnabled)
+		if subcommand == nil {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getUnknownCommandMessage(command, path, name, mergeEnabled)), true, nil
+		}
+		command = subcommand
+		path = append(path, name)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getCommandHelp(command, mergeEnabled)), false, nil
+}
+
+// getCommandHelp returns the usage, flags and examples of a command and of
+// the commands below it.
+func getCommandHelp(command *wranglerCommand, mergeEnabled bool) string {
+	var usages []string
+	for _, documented := range command.documentedCommands(mergeEnabled) {
+		usage := strings.Trim(documented.usage(), "\n")
+		if len(documented.examples) != 0 {
+			usage += "\n\tExamples:\n      " + strings.Join(documented.examples, "\n      ")
+		}
+		usages = append(usages, usage)
+	}
+
+	return codeBlock(strings.Join(usages, "\n\n"))
+}
+
+// getUnknownCommandMessage explains that a subcommand of the given command
+// doesn't exist and suggests the subcommands with the most similar names.
+func getUnknownCommandMessage(command *wranglerCommand, path []string, name string, mergeEnabled bool) string {
+	prefix := strings.Join(append([]string{"/wrangler"}, path...), " ")
+
+	var available []string
+	for _, subcommand := range command.visibleSubcommands(mergeEnabled) {
+		available = append(available, inlineCode(prefix+" "+subcommand.name))
+	}
+
+	var suggestions []string
+	for _, suggestion := range suggestCommands(command.visibleSubcommands(mergeEnabled), name) {
+		suggestions = append(suggestions, inlineCode(prefix+" "+suggestion))
+	}
+
+	var msg string
+	if len(name) == 0 {
+		msg = fmt.Sprintf("Error: %s requires a subcommand.", inlineCode(prefix))
+	} else {
+		msg = fmt.Sprintf("Error: unknown command %s.", inlineCode(prefix+" "+name))
+	}
+	if len(suggestions) != 0 {
+		msg += fmt.Sprintf(" Did you mean %s?", strings.Join(suggestions, " or "))
+	} else {
+		msg += fmt.Sprintf("\n\nAvailable commands: %s", strings.Join(available, ", "))
+	}
+	msg += fmt.Sprintf("\n\nRun %s for usage instructions.", inlineCode(strings.Join(append([]string{"/wrangler", "help"}, path...), " ")))
+
+	return msg
+}
+
+// suggestCommands returns the names of the commands within a small edit
+// distance of the given name, closest first. Names that start with the given
+// name are always suggested.
+func suggestCommands(commands []*wranglerCommand, name string) []string {
+	if len(name) == 0 {
+		return nil
+	}
+
+	type suggestion struct {
+		name     string
+		distance int
+	}
+	var suggestions []suggestion
+	for _, command := range commands {
+		distance := editDistance(strings.ToLower(name), command.name)
+		if distance <= maxSuggestionDistance || strings.HasPrefix(command.name, strings.ToLower(name)) {
+			suggestions = append(suggestions, suggestion{name: command.name, distance: distance})
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].distance < suggestions[j].distance
+	})
+
+	var names []string
+	for _, s := range suggestions {
+		names = append(names, s.name)
+	}
+
+	return names
+}
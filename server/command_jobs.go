@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const jobsUsage = `/wrangler jobs [subcommand]
+  Manage background jobs for large move, copy and merge operations
+    - '/wrangler jobs list' lists recent jobs
+    - '/wrangler jobs info [JOB_ID]' shows the details of a job
+    - '/wrangler jobs cancel [JOB_ID]' cancels a running job and rolls back the messages it already created
+    - System admins can view and cancel jobs run by any user`
+
+func getJobsMessage() string {
+	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", jobsUsage))
+}
+
+func (p *Plugin) runJobsCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) < 1 || args[0] == "list" {
+		return p.runListJobsCommand(extra)
+	}
+
+	switch args[0] {
+	case "info":
+		if len(args) < 2 {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getJobsMessage()), true, nil
+		}
+		return p.runJobInfoCommand(args[1], extra)
+	case "cancel":
+		if len(args) < 2 {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getJobsMessage()), true, nil
+		}
+		return p.runCancelJobCommand(args[1], extra)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, codeBlock(jobsUsage)), true, nil
+}
+
+func (p *Plugin) runListJobsCommand(extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	executor, appErr := p.API.GetUser(extra.UserId)
+	if appErr != nil {
+		return nil, false, errors.Wrap(appErr, "unable to find executor")
+	}
+
+	jobs, err := p.getRecentWranglerJobs()
+	if err != nil {
+		return nil, false, err
+	}
+
+	msg := "Recent Wrangler jobs:\n"
+	var count int
+	for _, job := range jobs {
+		// System admins can see and cancel all jobs.
+		if !executor.IsSystemAdmin() && job.UserID != extra.UserId {
+			continue
+		}
+
+		msg += fmt.Sprintf("%s - %-5s - %-9s - %d/%d message(s) - %s - @%s\n",
+			job.ID,
+			job.Type,
+			job.Status,
+			job.CompletedPosts,
+			job.TotalPosts,
+			time.Unix(0, job.CreateAt*int64(time.Millisecond)).UTC().Format(time.RFC822),
+			p.getUsernameOrID(job.UserID),
+		)
+		count++
+	}
+
+	if count == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "No results found"), false, nil
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, codeBlock(strings.TrimRight(msg, "\n"))), false, nil
+}
+
+func (p *Plugin) runJobInfoCommand(jobID string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	job, resp, userErr, err := p.getWranglerJobForExecutor(jobID, extra)
+	if job == nil {
+		return resp, userErr, err
+	}
+
+	msg := fmt.Sprintf("Job ID: %s\nType: %s\nStatus: %s\nProgress: %d/%d message(s)\nStarted by: @%s\nCreated: %s\nUpdated: %s\n",
+		job.ID,
+		job.Type,
+		job.Status,
+		job.CompletedPosts,
+		job.TotalPosts,
+		p.getUsernameOrID(job.UserID),
+		time.Unix(0, job.CreateAt*int64(time.Millisecond)).UTC().Format(time.RFC822),
+		time.Unix(0, job.UpdateAt*int64(time.Millisecond)).UTC().Format(time.RFC822),
+	)
+	if len(job.Result) != 0 {
+		msg += fmt.Sprintf("Result:\n%s", job.Result)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, codeBlock(strings.TrimRight(msg, "\n"))), false, nil
+}
+
+func (p *Plugin) runCancelJobCommand(jobID string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	job, resp, userErr, err := p.getWranglerJobForExecutor(jobID, extra)
+	if job == nil {
+		return resp, userErr, err
+	}
+	if job.IsFinished() {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: job %s has already %s", job.ID, job.Status)), true, nil
+	}
+
+	err = p.requestWranglerJobCancel(job.ID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	p.API.LogInfo("Wrangler job cancellation requested",
+		"user_id", extra.UserId,
+		"job_id", job.ID,
+		"job_type", job.Type,
+	)
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Cancellation of job %s has been requested; any messages it already created will be removed", job.ID)), false, nil
+}
+
+// getWranglerJobForExecutor returns the job with the given ID if the executor
+// is allowed to manage it. A command response is returned instead of the job
+// if the job doesn't exist or belongs to another user.
+func (p *Plugin) getWranglerJobForExecutor(jobID string, extra *model.CommandArgs) (*WranglerJob, *model.CommandResponse, bool, error) {
+	job, err := p.getWranglerJob(jobID)
+	if err != nil {
+		return nil, nil, false, errors.Wrap(err, "unable to get job")
+	}
+	if job == nil {
+		return nil, getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to find job with ID %s", jobID)), true, nil
+	}
+
+	if job.UserID != extra.UserId {
+		executor, appErr := p.API.GetUser(extra.UserId)
+		if appErr != nil {
+			return nil, nil, false, errors.Wrap(appErr, "unable to find executor")
+		}
+		if !executor.IsSystemAdmin() {
+			return nil, getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to find job with ID %s", jobID)), true, nil
+		}
+	}
+
+	return job, nil, false, nil
+}
+
+func (p *Plugin) getUsernameOrID(userID string) string {
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return userID
+	}
+
+	return user.Username
+}
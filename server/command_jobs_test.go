@@ -0,0 +1,312 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunWranglerJob(t *testing.T) {
+	user := &model.User{
+		Id:       model.NewId(),
+		Username: "user",
+	}
+	directChannel := &model.Channel{
+		Id:   model.NewId(),
+		Name: "direct-channel",
+		Type: model.CHANNEL_DIRECT,
+	}
+
+	setupAPI := func() *plugintest.API {
+		api := &plugintest.API{}
+		mockKVStore(api)
+		api.On("GetDirectChannel", user.Id, mock.AnythingOfType("string")).Return(directChannel, nil)
+		api.On("CreatePost", mock.Anything).Return(mockGeneratePost(), nil)
+		return api
+	}
+
+	newJob := func() *WranglerJob {
+		return &WranglerJob{
+			ID:         model.NewId(),
+			Type:       jobTypeMove,
+			UserID:     user.Id,
+			ChannelID:  model.NewId(),
+			RootPostID: model.NewId(),
+			Status:     jobStatusQueued,
+			TotalPosts: 60,
+		}
+	}
+
+	// runPosts simulates a copy that reports progress after every post.
+	runPosts := func(count int) jobRunFunc {
+		return func(progress progressFunc, audit *AuditRecord) (*model.CommandResponse, bool, error) {
+			for i := 1; i <= count; i++ {
+				err := progress(i)
+				if err != nil {
+					return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "rolled back"), false, nil
+				}
+			}
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "job complete"), false, nil
+		}
+	}
+
+	t.Run("job succeeds with progress updates", func(t *testing.T) {
+		api := setupAPI()
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		job := newJob()
+		plugin.runWranglerJob(job, nil, &AuditRecord{}, runPosts(job.TotalPosts))
+
+		storedJob, err := plugin.getWranglerJob(job.ID)
+		require.NoError(t, err)
+		require.NotNil(t, storedJob)
+		assert.Equal(t, jobStatusSucceeded, storedJob.Status)
+		assert.Equal(t, 60, storedJob.CompletedPosts)
+		assert.Equal(t, "job complete", storedJob.Result)
+
+		// Two progress updates at 25 and 50 posts and the final result.
+		api.AssertNumberOfCalls(t, "CreatePost", 3)
+	})
+
+	t.Run("job is cancelled", func(t *testing.T) {
+		api := setupAPI()
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		job := newJob()
+		require.NoError(t, plugin.requestWranglerJobCancel(job.ID))
+		plugin.runWranglerJob(job, nil, &AuditRecord{}, runPosts(job.TotalPosts))
+
+		storedJob, err := plugin.getWranglerJob(job.ID)
+		require.NoError(t, err)
+		require.NotNil(t, storedJob)
+		assert.Equal(t, jobStatusCancelled, storedJob.Status)
+		assert.Equal(t, 0, storedJob.CompletedPosts)
+		assert.Equal(t, "rolled back", storedJob.Result)
+	})
+
+	t.Run("in-channel summary is posted by the bot", func(t *testing.T) {
+		api := setupAPI()
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		job := newJob()
+		plugin.runWranglerJob(job, nil, &AuditRecord{}, func(progress progressFunc, audit *AuditRecord) (*model.CommandResponse, bool, error) {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_IN_CHANNEL, "thread moved"), false, nil
+		})
+
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == job.ChannelID && post.Message == "thread moved"
+		}))
+	})
+}
+
+func TestShouldRunAsJob(t *testing.T) {
+	wpl := buildWranglerPostList(mockGeneratePostList(10, model.NewId(), false))
+
+	var plugin Plugin
+
+	plugin.setConfiguration(&configuration{})
+	assert.False(t, plugin.shouldRunAsJob(wpl))
+
+	plugin.setConfiguration(&configuration{BackgroundJobThreshold: "10"})
+	assert.True(t, plugin.shouldRunAsJob(wpl))
+
+	plugin.setConfiguration(&configuration{BackgroundJobThreshold: "11"})
+	assert.False(t, plugin.shouldRunAsJob(wpl))
+}
+
+func TestInterruptUnfinishedWranglerJobs(t *testing.T) {
+	user := &model.User{Id: model.NewId(), Username: "user"}
+
+	setupPlugin := func(clusterEnabled bool) (*Plugin, *plugintest.API) {
+		config := &model.Config{}
+		config.ClusterSettings.Enable = &clusterEnabled
+
+		api := &plugintest.API{}
+		mockKVStore(api)
+		api.On("GetConfig").Return(config)
+		api.On("GetDirectChannel", user.Id, mock.AnythingOfType("string")).Return(&model.Channel{Id: model.NewId()}, nil)
+		api.On("CreatePost", mock.Anything).Return(mockGeneratePost(), nil)
+
+		plugin := &Plugin{}
+		plugin.SetAPI(api)
+
+		return plugin, api
+	}
+
+	addJob := func(t *testing.T, plugin *Plugin, status string, updateAt int64) *WranglerJob {
+		job := &WranglerJob{
+			ID:       model.NewId(),
+			Type:     jobTypeMove,
+			UserID:   user.Id,
+			Status:   status,
+			UpdateAt: updateAt,
+		}
+		require.NoError(t, plugin.kvSetJSON(jobKey(job.ID), job))
+		require.NoError(t, plugin.kvAddToIndex(kvJobIndexKey, job.ID, maxJobHistory))
+		return job
+	}
+
+	getStatus := func(t *testing.T, plugin *Plugin, id string) string {
+		job, err := plugin.getWranglerJob(id)
+		require.NoError(t, err)
+		return job.Status
+	}
+
+	t.Run("without clustering", func(t *testing.T) {
+		plugin, api := setupPlugin(false)
+		running := addJob(t, plugin, jobStatusRunning, model.GetMillis())
+		queued := addJob(t, plugin, jobStatusQueued, model.GetMillis())
+		finished := addJob(t, plugin, jobStatusSucceeded, model.GetMillis())
+
+		require.NoError(t, plugin.interruptUnfinishedWranglerJobs())
+		assert.Equal(t, jobStatusInterrupted, getStatus(t, plugin, running.ID))
+		assert.Equal(t, jobStatusInterrupted, getStatus(t, plugin, queued.ID))
+		assert.Equal(t, jobStatusSucceeded, getStatus(t, plugin, finished.ID))
+		api.AssertNumberOfCalls(t, "CreatePost", 2)
+	})
+
+	t.Run("with clustering", func(t *testing.T) {
+		plugin, _ := setupPlugin(true)
+		active := addJob(t, plugin, jobStatusRunning, model.GetMillis())
+		stale := addJob(t, plugin, jobStatusRunning, model.GetMillis()-2*threadLockExpiry.Milliseconds())
+
+		require.NoError(t, plugin.interruptUnfinishedWranglerJobs())
+		assert.Equal(t, jobStatusRunning, getStatus(t, plugin, active.ID))
+		assert.Equal(t, jobStatusInterrupted, getStatus(t, plugin, stale.ID))
+	})
+}
+
+func TestJobsCommand(t *testing.T) {
+	user := &model.User{
+		Id:       model.NewId(),
+		Username: "user",
+	}
+	otherUser := &model.User{
+		Id:       model.NewId(),
+		Username: "other-user",
+	}
+	adminUser := &model.User{
+		Id:       model.NewId(),
+		Username: "admin",
+		Roles:    model.SYSTEM_ADMIN_ROLE_ID,
+	}
+
+	api := &plugintest.API{}
+	mockKVStore(api)
+	api.On("GetUser", user.Id).Return(user, nil)
+	api.On("GetUser", otherUser.Id).Return(otherUser, nil)
+	api.On("GetUser", adminUser.Id).Return(adminUser, nil)
+	api.On("LogInfo",
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+	).Return(nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	runningJob := &WranglerJob{
+		ID:             model.NewId(),
+		Type:           jobTypeCopy,
+		UserID:         user.Id,
+		Status:         jobStatusRunning,
+		TotalPosts:     200,
+		CompletedPosts: 50,
+		CreateAt:       model.GetMillis(),
+	}
+	finishedJob := &WranglerJob{
+		ID:             model.NewId(),
+		Type:           jobTypeMove,
+		UserID:         user.Id,
+		Status:         jobStatusSucceeded,
+		TotalPosts:     150,
+		CompletedPosts: 150,
+		CreateAt:       model.GetMillis(),
+		Result:         "A thread with 150 messages has been moved",
+	}
+	for _, job := range []*WranglerJob{finishedJob, runningJob} {
+		require.NoError(t, plugin.updateWranglerJob(job))
+		require.NoError(t, plugin.kvAddToIndex(kvJobIndexKey, job.ID, maxJobHistory))
+	}
+
+	t.Run("list jobs", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{}, &model.CommandArgs{UserId: user.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, runningJob.ID)
+		assert.Contains(t, resp.Text, "50/200 message(s)")
+		assert.Contains(t, resp.Text, finishedJob.ID)
+	})
+
+	t.Run("list jobs of other users", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{"list"}, &model.CommandArgs{UserId: otherUser.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Equal(t, "No results found", resp.Text)
+	})
+
+	t.Run("list jobs as admin", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{"list"}, &model.CommandArgs{UserId: adminUser.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, runningJob.ID)
+	})
+
+	t.Run("info missing job ID", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{"info"}, &model.CommandArgs{UserId: user.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: missing arguments")
+	})
+
+	t.Run("info", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{"info", finishedJob.ID}, &model.CommandArgs{UserId: user.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Status: succeeded")
+		assert.Contains(t, resp.Text, finishedJob.Result)
+	})
+
+	t.Run("info for job of another user", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{"info", finishedJob.ID}, &model.CommandArgs{UserId: otherUser.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: unable to find job")
+	})
+
+	t.Run("cancel finished job", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{"cancel", finishedJob.ID}, &model.CommandArgs{UserId: user.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "has already succeeded")
+	})
+
+	t.Run("cancel running job", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{"cancel", runningJob.ID}, &model.CommandArgs{UserId: user.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Cancellation of job")
+
+		cancelRequested, err := plugin.isWranglerJobCancelRequested(runningJob.ID)
+		require.NoError(t, err)
+		assert.True(t, cancelRequested)
+	})
+
+	t.Run("unknown subcommand", func(t *testing.T) {
+		resp, isUserError, err := plugin.runJobsCommand([]string{"pause"}, &model.CommandArgs{UserId: user.Id})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "/wrangler jobs")
+	})
+}
@@ -2,30 +2,96 @@ package main
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
 
 const (
-	flagTeamFilter    = "team-filter"
-	flagChannelFilter = "channel-filter"
+	listChannelsUsage = `/wrangler list channels [flags]
+  List the IDs of all channels you have joined
+    - Direct and group messages are listed with --include-direct when no team filter is provided
+    - With --wrangle-target, direct and group messages are only listed if --include-direct is provided and the plugin settings allow wrangling to them
+	Flags:
+%s`
+
+	flagTeamFilter      = "team-filter"
+	flagChannelFilter   = "channel-filter"
+	flagIncludeDirect   = "include-direct"
+	flagIncludeArchived = "include-archived"
+	flagListSort        = "sort"
+	flagWrangleTarget   = "wrangle-target"
+
+	listChannelsSortName     = "name"
+	listChannelsSortActivity = "activity"
+
+	// maxGroupMessageMembers is the largest number of users in a group
+	// message channel.
+	maxGroupMessageMembers = 8
+
+	directMessagesGroupName = "direct and group messages"
 )
 
 type listChannelsOptions struct {
-	teamFilter    string
-	channelFilter string
+	teamFilter      string
+	channelFilter   string
+	format          string
+	includeDirect   bool
+	includeArchived bool
+	sort            string
+	wrangleTarget   bool
+}
+
+// listedChannel is a channel in the JSON output of the list channels
+// command.
+type listedChannel struct {
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	DisplayName     string   `json:"display_name"`
+	Type            string   `json:"type"`
+	TeamID          string   `json:"team_id"`
+	TeamName        string   `json:"team_name"`
+	TeamDisplayName string   `json:"team_display_name"`
+	Participants    []string `json:"participants,omitempty"`
+	Archived        bool     `json:"archived"`
+	MemberCount     int64    `json:"member_count"`
+	CreateAt        int64    `json:"create_at"`
+	LastPostAt      int64    `json:"last_post_at"`
+}
+
+// listChannelsOutput is the JSON output of the list channels command.
+type listChannelsOutput struct {
+	Channels []*listedChannel `json:"channels"`
+}
+
+// listedChannelGroup is the list of channels shown under one team, or under
+// the direct and group messages heading.
+type listedChannelGroup struct {
+	name     string
+	channels []*listedChannel
 }
 
 func getListChannelsFlagSet() *pflag.FlagSet {
 	listChannelsFlagSet := pflag.NewFlagSet("list channels", pflag.ContinueOnError)
 	listChannelsFlagSet.String(flagTeamFilter, "", "A filter value that team names must contain to be shown on the list")
 	listChannelsFlagSet.String(flagChannelFilter, "", "A filter value that channel names must contain to be shown on the list")
+	listChannelsFlagSet.Bool(flagIncludeDirect, false, "Also list direct and group message channels")
+	listChannelsFlagSet.Bool(flagIncludeArchived, false, "Also list archived channels")
+	listChannelsFlagSet.String(flagListSort, listChannelsSortName, "The order of the channels of each team: name or activity")
+	listChannelsFlagSet.Bool(flagWrangleTarget, false, "Only list channels that messages in this channel can currently be moved or copied to")
+	addFormatFlag(listChannelsFlagSet)
 
 	return listChannelsFlagSet
 }
 
+func getListChannelsUsage() string {
+	return fmt.Sprintf(listChannelsUsage, getListChannelsFlagSet().FlagUsages())
+}
+
 func parseListChannelsArgs(args []string) (listChannelsOptions, error) {
 	var options listChannelsOptions
 
@@ -45,6 +111,23 @@ func parseListChannelsArgs(args []string) (listChannelsOptions, error) {
 		return options, err
 	}
 
+	options.format, err = getFormatFlag(listChannelsFlagSet)
+	if err != nil {
+		return options, err
+	}
+
+	options.sort, err = listChannelsFlagSet.GetString(flagListSort)
+	if err != nil {
+		return options, err
+	}
+	if options.sort != listChannelsSortName && options.sort != listChannelsSortActivity {
+		return options, errors.Errorf("%s (%s) must be one of %s or %s", flagListSort, options.sort, listChannelsSortName, listChannelsSortActivity)
+	}
+
+	options.includeDirect, _ = listChannelsFlagSet.GetBool(flagIncludeDirect)
+	options.includeArchived, _ = listChannelsFlagSet.GetBool(flagIncludeArchived)
+	options.wrangleTarget, _ = listChannelsFlagSet.GetBool(flagWrangleTarget)
+
 	return options, nil
 }
 
@@ -54,48 +137,265 @@ func (p *Plugin) runListChannelsCommand(args []string, extra *model.CommandArgs)
 		return nil, true, err
 	}
 
-	teams, appErr := p.API.GetTeamsForUser(extra.UserId)
-	if appErr != nil {
-		return nil, false, appErr
+	var sourceChannel *model.Channel
+	if options.wrangleTarget {
+		var appErr *model.AppError
+		sourceChannel, appErr = p.API.GetChannel(extra.ChannelId)
+		if appErr != nil {
+			return nil, false, errors.Wrap(appErr, "unable to get current channel")
+		}
+	}
+
+	groups, err := p.getListedChannelGroups(options, sourceChannel, extra.UserId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	listed := []*listedChannel{}
+	for _, group := range groups {
+		listed = append(listed, group.channels...)
 	}
 
 	var msg string
+	switch options.format {
+	case formatJSON:
+		msg, err = formatJSONOutput(&listChannelsOutput{Channels: listed})
+		if err != nil {
+			return nil, false, err
+		}
+	case formatMarkdown:
+		location := p.getUserLocation(extra.UserId)
+		var rows [][]string
+		for _, channel := range listed {
+			rows = append(rows, []string{
+				channel.TeamName,
+				channel.ID,
+				channel.Name,
+				channel.DisplayName,
+				getListedChannelType(channel),
+				fmt.Sprintf("%d", channel.MemberCount),
+				formatListedChannelLastPost(channel, location),
+			})
+		}
+		if len(rows) != 0 {
+			msg = markdownTable([]string{"Team", "Channel ID", "Name", "Display Name", "Type", "Members", "Last Post"}, rows)
+		}
+	default:
+		location := p.getUserLocation(extra.UserId)
+		for _, group := range groups {
+			newChannelGroup := fmt.Sprintf("%s\n", group.name)
+			for _, channel := range group.channels {
+				name := channel.Name
+				if len(channel.Participants) != 0 {
+					name = strings.Join(channel.Participants, ", ")
+				}
+				newChannelGroup += fmt.Sprintf("%s - [%s] %s - %d members - last post %s\n",
+					channel.ID,
+					getListedChannelType(channel),
+					name,
+					channel.MemberCount,
+					formatListedChannelLastPost(channel, location),
+				)
+			}
+			newChannelGroup = strings.TrimRight(newChannelGroup, "\n")
+			msg += codeBlock(newChannelGroup) + "\n"
+		}
+	}
+
+	if len(msg) == 0 {
+		msg = "No results found"
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
+}
+
+// getListedChannelGroups returns the channels of the user matching the list
+// channels options, grouped by team. Direct and group message channels are
+// returned in a final group of their own.
+func (p *Plugin) getListedChannelGroups(options listChannelsOptions, sourceChannel *model.Channel, userID string) ([]*listedChannelGroup, error) {
+	teams, appErr := p.API.GetTeamsForUser(userID)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	includeDirect := options.includeDirect && len(options.teamFilter) == 0
+	directGroup := &listedChannelGroup{name: directMessagesGroupName}
+	seenDirect := make(map[string]bool)
+
+	var groups []*listedChannelGroup
 	for _, team := range teams {
 		if len(options.teamFilter) != 0 && !strings.Contains(team.Name, options.teamFilter) {
 			continue
 		}
 
-		channels, appErr := p.API.GetChannelsForTeamForUser(team.Id, extra.UserId, false)
+		channels, appErr := p.API.GetChannelsForTeamForUser(team.Id, userID, options.includeArchived)
 		if appErr != nil {
-			return nil, false, appErr
+			return nil, appErr
 		}
 
-		var filteredChannels []*model.Channel
+		group := &listedChannelGroup{name: team.Name}
 		for _, channel := range channels {
+			if channel.DeleteAt != 0 && !options.includeArchived {
+				continue
+			}
+
 			if channel.IsGroupOrDirect() {
+				// Direct and group message channels are returned with the
+				// channels of every team.
+				if !includeDirect || seenDirect[channel.Id] {
+					continue
+				}
+				seenDirect[channel.Id] = true
+				if sourceChannel != nil && !p.isWrangleTarget(sourceChannel, channel, userID) {
+					continue
+				}
+
+				listed, err := p.getListedDirectChannel(channel, userID)
+				if err != nil {
+					return nil, err
+				}
+				if len(options.channelFilter) != 0 && !strings.Contains(strings.Join(listed.Participants, ","), options.channelFilter) {
+					continue
+				}
+				directGroup.channels = append(directGroup.channels, listed)
 				continue
 			}
+
 			if len(options.channelFilter) != 0 && !strings.Contains(channel.Name, options.channelFilter) {
 				continue
 			}
-			filteredChannels = append(filteredChannels, channel)
+			if sourceChannel != nil && !p.isWrangleTarget(sourceChannel, channel, userID) {
+				continue
+			}
+
+			group.channels = append(group.channels, &listedChannel{
+				ID:              channel.Id,
+				Name:            channel.Name,
+				DisplayName:     channel.DisplayName,
+				Type:            channel.Type,
+				TeamID:          team.Id,
+				TeamName:        team.Name,
+				TeamDisplayName: team.DisplayName,
+				Archived:        channel.DeleteAt != 0,
+				MemberCount:     p.getChannelMemberCount(channel.Id),
+				CreateAt:        channel.CreateAt,
+				LastPostAt:      channel.LastPostAt,
+			})
 		}
-		if len(filteredChannels) == 0 {
+
+		if len(group.channels) != 0 {
+			groups = append(groups, group)
+		}
+	}
+
+	if len(directGroup.channels) != 0 {
+		groups = append(groups, directGroup)
+	}
+
+	for _, group := range groups {
+		sortListedChannels(group.channels, options.sort)
+	}
+
+	return groups, nil
+}
+
+// getListedDirectChannel returns a direct or group message channel along with
+// the usernames of the other users in it.
+func (p *Plugin) getListedDirectChannel(channel *model.Channel, userID string) (*listedChannel, error) {
+	users, appErr := p.API.GetUsersInChannel(channel.Id, "username", 0, maxGroupMessageMembers)
+	if appErr != nil {
+		return nil, errors.Wrapf(appErr, "unable to get users in channel %s", channel.Id)
+	}
+
+	var participants []string
+	for _, user := range users {
+		if user.Id == userID && len(users) > 1 {
 			continue
 		}
+		participants = append(participants, "@"+user.Username)
+	}
 
-		// Format filtered channel list and append.
-		newChannelGroup := fmt.Sprintf("%s\n", team.Name)
-		for _, channel := range filteredChannels {
-			newChannelGroup += fmt.Sprintf("%s - %s\n", channel.Id, channel.Name)
+	return &listedChannel{
+		ID:           channel.Id,
+		Name:         channel.Name,
+		DisplayName:  channel.DisplayName,
+		Type:         channel.Type,
+		Participants: participants,
+		Archived:     channel.DeleteAt != 0,
+		MemberCount:  int64(len(users)),
+		CreateAt:     channel.CreateAt,
+		LastPostAt:   channel.LastPostAt,
+	}, nil
+}
+
+// isWrangleTarget returns whether messages in the source channel can
+// currently be moved or copied to the target channel by the user.
+func (p *Plugin) isWrangleTarget(sourceChannel, targetChannel *model.Channel, userID string) bool {
+	if targetChannel.Id == sourceChannel.Id || targetChannel.DeleteAt != 0 {
+		return false
+	}
+	if len(p.getMovePolicyViolation(sourceChannel, targetChannel)) != 0 {
+		return false
+	}
+
+	return p.API.HasPermissionToChannel(userID, targetChannel.Id, model.PERMISSION_CREATE_POST)
+}
+
+// getChannelMemberCount returns the number of members of a channel. It is
+// only called for channels that are listed, as the count takes a request per
+// channel.
+func (p *Plugin) getChannelMemberCount(channelID string) int64 {
+	stats, appErr := p.API.GetChannelStats(channelID)
+	if appErr != nil {
+		return 0
+	}
+
+	return stats.MemberCount
+}
+
+func sortListedChannels(channels []*listedChannel, order string) {
+	sort.SliceStable(channels, func(i, j int) bool {
+		if order == listChannelsSortActivity && channels[i].LastPostAt != channels[j].LastPostAt {
+			return channels[i].LastPostAt > channels[j].LastPostAt
 		}
-		newChannelGroup = strings.TrimRight(newChannelGroup, "\n")
-		msg += codeBlock(newChannelGroup) + "\n"
+		return getListedChannelSortName(channels[i]) < getListedChannelSortName(channels[j])
+	})
+}
+
+func getListedChannelSortName(channel *listedChannel) string {
+	if len(channel.Participants) != 0 {
+		return strings.Join(channel.Participants, ",")
 	}
 
-	if len(msg) == 0 {
-		msg = "No results found"
+	return channel.Name
+}
+
+// getListedChannelType returns the type marker shown for a channel.
+func getListedChannelType(channel *listedChannel) string {
+	var marker string
+	switch channel.Type {
+	case model.CHANNEL_OPEN:
+		marker = "open"
+	case model.CHANNEL_PRIVATE:
+		marker = "private"
+	case model.CHANNEL_DIRECT:
+		marker = "direct"
+	case model.CHANNEL_GROUP:
+		marker = "group"
+	default:
+		marker = channel.Type
+	}
+	if channel.Archived {
+		marker += ", archived"
 	}
 
-	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
+	return marker
+}
+
+func formatListedChannelLastPost(channel *listedChannel, location *time.Location) string {
+	if channel.LastPostAt == 0 {
+		return "never"
+	}
+
+	return formatListMessagesTime(channel.LastPostAt, location)
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -15,6 +16,8 @@ func TestChannelListCommand(t *testing.T) {
 	api := &plugintest.API{}
 	api.On("GetTeamsForUser", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(mockGenerateTeams(3), nil)
 	api.On("GetChannelsForTeamForUser", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(mockGenerateChannels(3), nil)
+	api.On("GetChannelStats", mock.AnythingOfType("string")).Return(&model.ChannelStats{MemberCount: 2}, nil)
+	api.On("GetUser", mock.AnythingOfType("string")).Return(nil, &model.AppError{})
 
 	var plugin Plugin
 	plugin.SetAPI(api)
@@ -95,6 +98,147 @@ func TestChannelListCommand(t *testing.T) {
 		assert.Contains(t, resp.Text, "channel-1")
 		assert.Contains(t, resp.Text, "channel-2")
 	})
+
+	t.Run("formats", func(t *testing.T) {
+		t.Run("json", func(t *testing.T) {
+			resp, isUserError, err := plugin.runListChannelsCommand([]string{"--format=json"}, &model.CommandArgs{})
+			require.NoError(t, err)
+			assert.False(t, isUserError)
+
+			var output listChannelsOutput
+			require.NoError(t, json.Unmarshal([]byte(resp.Text), &output))
+			require.Len(t, output.Channels, 9)
+			assert.Equal(t, "team-0", output.Channels[0].TeamName)
+			assert.Equal(t, "channel-0", output.Channels[0].Name)
+			assert.True(t, model.IsValidId(output.Channels[0].ID))
+		})
+
+		t.Run("json without results", func(t *testing.T) {
+			resp, isUserError, err := plugin.runListChannelsCommand([]string{"--format=json", "--team-filter=thisteamdoesnotexist"}, &model.CommandArgs{})
+			require.NoError(t, err)
+			assert.False(t, isUserError)
+			assert.JSONEq(t, `{"channels": []}`, resp.Text)
+		})
+
+		t.Run("markdown", func(t *testing.T) {
+			resp, isUserError, err := plugin.runListChannelsCommand([]string{"--format=markdown"}, &model.CommandArgs{})
+			require.NoError(t, err)
+			assert.False(t, isUserError)
+			assert.Contains(t, resp.Text, "| Team | Channel ID | Name | Display Name | Type | Members | Last Post |")
+			assert.Contains(t, resp.Text, "| team-0 |")
+		})
+
+		t.Run("invalid", func(t *testing.T) {
+			_, isUserError, err := plugin.runListChannelsCommand([]string{"--format=yaml"}, &model.CommandArgs{})
+			require.Error(t, err)
+			assert.True(t, isUserError)
+		})
+	})
+}
+
+func TestChannelListCommandOptions(t *testing.T) {
+	user := &model.User{Id: model.NewId(), Username: "user"}
+	alice := &model.User{Id: model.NewId(), Username: "alice"}
+	bob := &model.User{Id: model.NewId(), Username: "bob"}
+	team := &model.Team{Id: model.NewId(), Name: "team"}
+
+	town := &model.Channel{Id: model.NewId(), TeamId: team.Id, Name: "town-square", Type: model.CHANNEL_OPEN, LastPostAt: 1000}
+	secret := &model.Channel{Id: model.NewId(), TeamId: team.Id, Name: "secret", Type: model.CHANNEL_PRIVATE, LastPostAt: 3000}
+	archived := &model.Channel{Id: model.NewId(), TeamId: team.Id, Name: "archived", Type: model.CHANNEL_OPEN, DeleteAt: 2000}
+	readOnly := &model.Channel{Id: model.NewId(), TeamId: team.Id, Name: "announcements", Type: model.CHANNEL_OPEN}
+	direct := &model.Channel{Id: model.NewId(), Name: model.GetDMNameFromIds(user.Id, alice.Id), Type: model.CHANNEL_DIRECT, LastPostAt: 2000}
+	group := &model.Channel{Id: model.NewId(), Name: model.NewId(), Type: model.CHANNEL_GROUP}
+
+	api := &plugintest.API{}
+	api.On("GetTeamsForUser", user.Id).Return([]*model.Team{team}, nil)
+	api.On("GetChannelsForTeamForUser", team.Id, user.Id, false).Return([]*model.Channel{town, secret, readOnly, direct, group}, nil)
+	api.On("GetChannelsForTeamForUser", team.Id, user.Id, true).Return([]*model.Channel{town, secret, archived, readOnly, direct, group}, nil)
+	api.On("GetChannelStats", mock.AnythingOfType("string")).Return(&model.ChannelStats{MemberCount: 5}, nil)
+	api.On("GetUser", user.Id).Return(user, nil)
+	api.On("GetUsersInChannel", direct.Id, "username", 0, maxGroupMessageMembers).Return([]*model.User{alice, user}, nil)
+	api.On("GetUsersInChannel", group.Id, "username", 0, maxGroupMessageMembers).Return([]*model.User{alice, bob, user}, nil)
+	api.On("GetChannel", town.Id).Return(town, nil)
+	api.On("HasPermissionToChannel", user.Id, secret.Id, model.PERMISSION_CREATE_POST).Return(true)
+	api.On("HasPermissionToChannel", user.Id, readOnly.Id, model.PERMISSION_CREATE_POST).Return(false)
+	api.On("HasPermissionToChannel", user.Id, direct.Id, model.PERMISSION_CREATE_POST).Return(true)
+	api.On("HasPermissionToChannel", user.Id, group.Id, model.PERMISSION_CREATE_POST).Return(true)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{})
+
+	list := func(t *testing.T, args ...string) []*listedChannel {
+		resp, isUserError, err := plugin.runListChannelsCommand(append(args, "--format=json"), &model.CommandArgs{UserId: user.Id, ChannelId: town.Id})
+		require.NoError(t, err)
+		require.False(t, isUserError)
+
+		var output listChannelsOutput
+		require.NoError(t, json.Unmarshal([]byte(resp.Text), &output))
+		return output.Channels
+	}
+	names := func(channels []*listedChannel) []string {
+		var names []string
+		for _, channel := range channels {
+			names = append(names, channel.Name)
+		}
+		return names
+	}
+
+	t.Run("sorted by name", func(t *testing.T) {
+		channels := list(t)
+		assert.Equal(t, []string{"announcements", "secret", "town-square"}, names(channels))
+		assert.Equal(t, int64(5), channels[0].MemberCount)
+	})
+
+	t.Run("sorted by activity", func(t *testing.T) {
+		assert.Equal(t, []string{"secret", "town-square", "announcements"}, names(list(t, "--sort=activity")))
+	})
+
+	t.Run("invalid sort", func(t *testing.T) {
+		_, isUserError, err := plugin.runListChannelsCommand([]string{"--sort=size"}, &model.CommandArgs{UserId: user.Id})
+		require.EqualError(t, err, "sort (size) must be one of name or activity")
+		assert.True(t, isUserError)
+	})
+
+	t.Run("archived", func(t *testing.T) {
+		channels := list(t, "--include-archived")
+		assert.Equal(t, []string{"announcements", "archived", "secret", "town-square"}, names(channels))
+		assert.True(t, channels[1].Archived)
+	})
+
+	t.Run("direct and group messages", func(t *testing.T) {
+		channels := list(t, "--include-direct")
+		require.Len(t, channels, 5)
+		assert.Equal(t, []string{"@alice"}, channels[3].Participants)
+		assert.Equal(t, int64(2), channels[3].MemberCount)
+		assert.Equal(t, []string{"@alice", "@bob"}, channels[4].Participants)
+
+		assert.Len(t, list(t, "--include-direct", "--team-filter=team"), 3)
+	})
+
+	t.Run("wrangle targets", func(t *testing.T) {
+		assert.Equal(t, []string{"secret"}, names(list(t, "--wrangle-target", "--include-archived", "--include-direct")))
+	})
+
+	t.Run("direct and group messages as wrangle targets", func(t *testing.T) {
+		plugin.setConfiguration(&configuration{MoveThreadToAnotherTeamEnable: true})
+		defer plugin.setConfiguration(&configuration{})
+
+		channels := list(t, "--wrangle-target", "--include-direct")
+		assert.Equal(t, []string{"secret", direct.Name, group.Name}, names(channels))
+
+		assert.Equal(t, []string{"secret"}, names(list(t, "--wrangle-target")))
+	})
+
+	t.Run("table", func(t *testing.T) {
+		resp, isUserError, err := plugin.runListChannelsCommand([]string{"--include-direct", "--include-archived"}, &model.CommandArgs{UserId: user.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, secret.Id+" - [private] secret - 5 members - last post 1970-01-01 00:00 UTC")
+		assert.Contains(t, resp.Text, archived.Id+" - [open, archived] archived - 5 members - last post never")
+		assert.Contains(t, resp.Text, "direct and group messages\n"+direct.Id+" - [direct] @alice - 2 members")
+		assert.Contains(t, resp.Text, group.Id+" - [group] @alice, @bob - 3 members")
+	})
 }
 
 func mockGenerateTeams(total int) []*model.Team {
@@ -3,12 +3,21 @@ package main
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
 
 const (
+	listMessagesUsage = `/wrangler list messages [flags]
+  List the IDs of recent messages in this channel
+    - Replies are listed along with the messages that start a thread unless --roots-only is provided
+    - --before and --after accept a message ID or link, a date (YYYY-MM-DD) or a duration before now (e.g. 24h or 30d)
+	Flags:
+%s`
+
 	flagListMessagesCount = "count"
 	minListMessagesCount  = 1
 	maxListMessagesCount  = 100
@@ -16,21 +25,80 @@ const (
 	flagListMessagesTrimLength = "trim-length"
 	minListMessagesTrimLength  = 10
 	maxListMessagesTrimLength  = 500
+
+	flagListMessagesUser        = "user"
+	flagListMessagesBefore      = "before"
+	flagListMessagesAfter       = "after"
+	flagListMessagesThreadsOnly = "threads-only"
+	flagListMessagesRootsOnly   = "roots-only"
+	flagListMessagesPage        = "page"
+
+	// listMessagesPageSize is the number of posts requested at a time while
+	// looking for messages to list, and listMessagesMaxScanned bounds how far
+	// back in the channel history the command looks.
+	listMessagesPageSize   = 200
+	listMessagesMaxScanned = 5000
+
+	listMessagesTimeFormat = "2006-01-02 15:04 MST"
 )
 
 type listMessagesOptions struct {
-	count      int
-	trimLength int
+	count       int
+	trimLength  int
+	format      string
+	user        string
+	before      string
+	after       string
+	threadsOnly bool
+	rootsOnly   bool
+	page        int
+}
+
+// listedMessage is a message in the JSON output of the list messages
+// command.
+type listedMessage struct {
+	ID         string `json:"id"`
+	RootID     string `json:"root_id"`
+	UserID     string `json:"user_id"`
+	Username   string `json:"username"`
+	Type       string `json:"type"`
+	Message    string `json:"message"`
+	CreateAt   int64  `json:"create_at"`
+	IsSystem   bool   `json:"is_system"`
+	ReplyCount int64  `json:"reply_count"`
+	FileCount  int    `json:"file_count"`
+}
+
+// listMessagesOutput is the JSON output of the list messages command.
+// ScannedCount is the number of messages of the channel that were searched,
+// and ScanLimitReached is set when older messages were not searched.
+type listMessagesOutput struct {
+	ChannelID        string           `json:"channel_id"`
+	Page             int              `json:"page"`
+	ScannedCount     int              `json:"scanned_count"`
+	ScanLimitReached bool             `json:"scan_limit_reached"`
+	Messages         []*listedMessage `json:"messages"`
 }
 
 func getListMessagesFlagSet() *pflag.FlagSet {
 	listMessagesFlagSet := pflag.NewFlagSet("list messages", pflag.ContinueOnError)
 	listMessagesFlagSet.Int(flagListMessagesCount, 20, fmt.Sprintf("Number of messages to return. Must be between %d and %d", minListMessagesCount, maxListMessagesCount))
 	listMessagesFlagSet.Int(flagListMessagesTrimLength, 50, fmt.Sprintf("The max character count of messages listed before they are trimmed. Must be between %d and %d", minListMessagesTrimLength, maxListMessagesTrimLength))
+	listMessagesFlagSet.String(flagListMessagesUser, "", "Only list messages posted by this user (@username or user ID)")
+	listMessagesFlagSet.String(flagListMessagesBefore, "", "Only list messages posted before this message or time")
+	listMessagesFlagSet.String(flagListMessagesAfter, "", "Only list messages posted after this message or time")
+	listMessagesFlagSet.Bool(flagListMessagesThreadsOnly, false, "Only list messages that have replies")
+	listMessagesFlagSet.Bool(flagListMessagesRootsOnly, false, "Only list messages that start a thread, hiding replies")
+	listMessagesFlagSet.Int(flagListMessagesPage, 0, "The page of results to show, starting at 0, with count messages per page")
+	addFormatFlag(listMessagesFlagSet)
 
 	return listMessagesFlagSet
 }
 
+func getListMessagesUsage() string {
+	return fmt.Sprintf(listMessagesUsage, getListMessagesFlagSet().FlagUsages())
+}
+
 func parseListMessagesArgs(args []string) (listMessagesOptions, error) {
 	var options listMessagesOptions
 
@@ -56,30 +124,389 @@ func parseListMessagesArgs(args []string) (listMessagesOptions, error) {
 		return options, fmt.Errorf("%s (%d) must be between %d and %d", flagListMessagesTrimLength, options.trimLength, minListMessagesTrimLength, maxListMessagesTrimLength)
 	}
 
+	options.format, err = getFormatFlag(listMessagesFlagSet)
+	if err != nil {
+		return options, err
+	}
+
+	options.user, _ = listMessagesFlagSet.GetString(flagListMessagesUser)
+	options.before, _ = listMessagesFlagSet.GetString(flagListMessagesBefore)
+	options.after, _ = listMessagesFlagSet.GetString(flagListMessagesAfter)
+	options.threadsOnly, _ = listMessagesFlagSet.GetBool(flagListMessagesThreadsOnly)
+	options.rootsOnly, _ = listMessagesFlagSet.GetBool(flagListMessagesRootsOnly)
+
+	options.page, err = listMessagesFlagSet.GetInt(flagListMessagesPage)
+	if err != nil {
+		return options, err
+	}
+	if options.page < 0 {
+		return options, fmt.Errorf("%s (%d) must not be negative", flagListMessagesPage, options.page)
+	}
+
 	return options, nil
 }
 
+// listMessagesFilter decides which messages are listed by the list messages
+// command.
+type listMessagesFilter struct {
+	userID      string
+	before      int64
+	after       int64
+	threadsOnly bool
+	rootsOnly   bool
+}
+
+func (f *listMessagesFilter) matches(post *model.Post, replyCount int64) bool {
+	if len(f.userID) != 0 && post.UserId != f.userID {
+		return false
+	}
+	if f.before != 0 && post.CreateAt >= f.before {
+		return false
+	}
+	if f.after != 0 && post.CreateAt <= f.after {
+		return false
+	}
+	if len(post.RootId) != 0 && f.rootsOnly {
+		return false
+	}
+	if f.threadsOnly && replyCount == 0 {
+		return false
+	}
+
+	return true
+}
+
+// getListMessagesFilter resolves the user and times of the list messages
+// flags. A non-empty message explains why a flag couldn't be resolved.
+func (p *Plugin) getListMessagesFilter(options listMessagesOptions, extra *model.CommandArgs) (*listMessagesFilter, string) {
+	filter := &listMessagesFilter{
+		threadsOnly: options.threadsOnly,
+		rootsOnly:   options.rootsOnly,
+	}
+
+	if len(options.user) != 0 {
+		if model.IsValidId(options.user) {
+			filter.userID = options.user
+		} else {
+			user, appErr := p.API.GetUserByUsername(strings.TrimPrefix(options.user, "@"))
+			if appErr != nil {
+				return nil, fmt.Sprintf("Error: unable to find user %s", options.user)
+			}
+			filter.userID = user.Id
+		}
+	}
+
+	var msg string
+	filter.before, msg = p.parseListMessagesBound(flagListMessagesBefore, options.before, extra)
+	if len(msg) != 0 {
+		return nil, msg
+	}
+	filter.after, msg = p.parseListMessagesBound(flagListMessagesAfter, options.after, extra)
+	if len(msg) != 0 {
+		return nil, msg
+	}
+
+	return filter, ""
+}
+
+// parseListMessagesBound converts a message ID or link, a date or a duration
+// before now to a timestamp in milliseconds.
+func (p *Plugin) parseListMessagesBound(flag, value string, extra *model.CommandArgs) (int64, string) {
+	if len(value) == 0 {
+		return 0, ""
+	}
+
+	postID := cleanInputID(value, extra.SiteURL)
+	if model.IsValidId(postID) {
+		post, appErr := p.API.GetPost(postID)
+		if appErr != nil {
+			return 0, fmt.Sprintf("Error: unable to get message with ID %s for --%s", postID, flag)
+		}
+		return post.CreateAt, ""
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UnixNano() / int64(time.Millisecond), ""
+	}
+	timestamp, err := parseAuditSince(value, time.Now())
+	if err != nil {
+		return 0, fmt.Sprintf("Error: --%s must be a message ID or link, a date or a duration; %s", flag, err.Error())
+	}
+
+	return timestamp, ""
+}
+
+// listMessagesResult is a message found by the list messages command.
+type listMessagesResult struct {
+	post       *model.Post
+	replyCount int64
+}
+
+// listMessagesScan describes how much of the channel history the list
+// messages command searched.
+type listMessagesScan struct {
+	scanned      int
+	limitReached bool
+}
+
+// findListMessages looks through the channel history, newest first, for the
+// given page of messages matching the filter. The search stops at
+// listMessagesMaxScanned messages even if the page was not filled.
+func (p *Plugin) findListMessages(channelID string, filter *listMessagesFilter, options listMessagesOptions) ([]*listMessagesResult, listMessagesScan, error) {
+	skip := options.page * options.count
+
+	var results []*listMessagesResult
+	var scan listMessagesScan
+	// Replies are always newer than their root, so every reply of a thread
+	// has been seen by the time its root is reached.
+	replyCounts := make(map[string]int64)
+	for page := 0; ; page++ {
+		if page*listMessagesPageSize >= listMessagesMaxScanned {
+			scan.limitReached = true
+			return results, scan, nil
+		}
+
+		postList, appErr := p.API.GetPostsForChannel(channelID, page, listMessagesPageSize)
+		if appErr != nil {
+			return nil, scan, errors.Wrap(appErr, "unable to get posts for channel")
+		}
+
+		posts := postList.ToSlice()
+		for _, post := range posts {
+			if len(post.RootId) != 0 {
+				replyCounts[post.RootId]++
+			}
+			if filter.after != 0 && post.CreateAt <= filter.after {
+				return results, scan, nil
+			}
+			scan.scanned++
+
+			replyCount := replyCounts[post.Id]
+			if post.ReplyCount > replyCount {
+				replyCount = post.ReplyCount
+			}
+			if !filter.matches(post, replyCount) {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+
+			results = append(results, &listMessagesResult{post: post, replyCount: replyCount})
+			if len(results) == options.count {
+				return results, scan, nil
+			}
+		}
+
+		if len(posts) < listMessagesPageSize {
+			return results, scan, nil
+		}
+	}
+}
+
+// getListMessagesHeader describes the listed messages and how many messages
+// of the channel were searched to find them.
+func getListMessagesHeader(shown int, scan listMessagesScan, options listMessagesOptions) string {
+	if options.page != 0 {
+		return fmt.Sprintf("Page %d: %d message(s) found among the %d most recent messages searched in this channel:", options.page, shown, scan.scanned)
+	}
+
+	return fmt.Sprintf("%d message(s) found among the %d most recent messages searched in this channel:", shown, scan.scanned)
+}
+
+// getListMessagesScanLimitNote tells the user that older messages of the
+// channel were not searched.
+func getListMessagesScanLimitNote() string {
+	return fmt.Sprintf("Only the newest %d messages of this channel were searched, so older matching messages are not listed.", listMessagesMaxScanned)
+}
+
+// getUserLocation returns the preferred time zone of a user, falling back to
+// UTC.
+func (p *Plugin) getUserLocation(userID string) *time.Location {
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(user.GetPreferredTimezone())
+	if err != nil {
+		return time.UTC
+	}
+
+	return location
+}
+
+func formatListMessagesTime(createAt int64, location *time.Location) string {
+	return time.Unix(0, createAt*int64(time.Millisecond)).In(location).Format(listMessagesTimeFormat)
+}
+
+// formatListMessagesSystemMessage describes a system message by its type and
+// text, if it has any.
+func formatListMessagesSystemMessage(post *model.Post, trimLength int) string {
+	description := "system message"
+	if systemType := strings.TrimPrefix(post.Type, model.POST_SYSTEM_MESSAGE_PREFIX); len(systemType) != 0 {
+		description += " (" + systemType + ")"
+	}
+	if len(post.Message) != 0 {
+		description += " " + cleanAndTrimMessage(post.Message, trimLength)
+	}
+
+	return description
+}
+
+func formatListMessagesDetails(result *listMessagesResult) string {
+	details := fmt.Sprintf("%d replies", result.replyCount)
+	if len(result.post.RootId) != 0 {
+		details = "reply"
+	}
+	if len(result.post.FileIds) != 0 {
+		details += fmt.Sprintf(", %d files", len(result.post.FileIds))
+	}
+
+	return details
+}
+
 func (p *Plugin) runListMessagesCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
 	options, err := parseListMessagesArgs(args)
 	if err != nil {
 		return nil, true, err
 	}
 
-	channelPosts, appErr := p.API.GetPostsForChannel(extra.ChannelId, 0, options.count)
-	if appErr != nil {
-		return nil, false, appErr
+	filter, msg := p.getListMessagesFilter(options, extra)
+	if len(msg) != 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), true, nil
+	}
+
+	results, scan, err := p.findListMessages(extra.ChannelId, filter, options)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch options.format {
+	case formatJSON:
+		return p.getListMessagesJSON(results, scan, extra.ChannelId, options)
+	case formatMarkdown:
+		return p.getListMessagesMarkdown(results, scan, extra.UserId, options)
 	}
 
-	msg := fmt.Sprintf("The last %d messages in this channel:\n", options.count)
-	for _, post := range channelPosts.ToSlice() {
+	location := p.getUserLocation(extra.UserId)
+	usernames := make(map[string]string)
+
+	msg = getListMessagesHeader(len(results), scan, options) + "\n"
+	for _, result := range results {
+		post := result.post
 		if post.IsSystemMessage() {
-			msg += "[     system message     ] - <skipped>\n"
-		} else {
-			msg += fmt.Sprintf("%s - %s\n", post.Id, cleanAndTrimMessage(post.Message, options.trimLength))
+			msg += fmt.Sprintf("%s - %s - %s\n",
+				post.Id,
+				formatListMessagesTime(post.CreateAt, location),
+				formatListMessagesSystemMessage(post, options.trimLength),
+			)
+			continue
+		}
+		if _, ok := usernames[post.UserId]; !ok {
+			usernames[post.UserId] = p.getUsernameOrID(post.UserId)
 		}
+		msg += fmt.Sprintf("%s - %s - @%s - %s - %s\n",
+			post.Id,
+			formatListMessagesTime(post.CreateAt, location),
+			usernames[post.UserId],
+			formatListMessagesDetails(result),
+			cleanAndTrimMessage(post.Message, options.trimLength),
+		)
+	}
+	if len(results) == 0 {
+		msg += "No results found\n"
 	}
 
 	msg = codeBlock(strings.TrimRight(msg, "\n"))
+	if scan.limitReached {
+		msg += "\n" + getListMessagesScanLimitNote()
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
+}
+
+func (p *Plugin) getListMessagesJSON(results []*listMessagesResult, scan listMessagesScan, channelID string, options listMessagesOptions) (*model.CommandResponse, bool, error) {
+	usernames := make(map[string]string)
+	output := &listMessagesOutput{
+		ChannelID:        channelID,
+		Page:             options.page,
+		ScannedCount:     scan.scanned,
+		ScanLimitReached: scan.limitReached,
+		Messages:         []*listedMessage{},
+	}
+	for _, result := range results {
+		post := result.post
+		if _, ok := usernames[post.UserId]; !ok {
+			usernames[post.UserId] = p.getUsernameOrID(post.UserId)
+		}
+		output.Messages = append(output.Messages, &listedMessage{
+			ID:         post.Id,
+			RootID:     post.RootId,
+			UserID:     post.UserId,
+			Username:   usernames[post.UserId],
+			Type:       post.Type,
+			Message:    post.Message,
+			CreateAt:   post.CreateAt,
+			IsSystem:   post.IsSystemMessage(),
+			ReplyCount: result.replyCount,
+			FileCount:  len(post.FileIds),
+		})
+	}
+
+	msg, err := formatJSONOutput(output)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
+}
+
+func (p *Plugin) getListMessagesMarkdown(results []*listMessagesResult, scan listMessagesScan, userID string, options listMessagesOptions) (*model.CommandResponse, bool, error) {
+	location := p.getUserLocation(userID)
+	usernames := make(map[string]string)
+
+	var rows [][]string
+	for _, result := range results {
+		post := result.post
+		if post.IsSystemMessage() {
+			rows = append(rows, []string{
+				post.Id,
+				formatListMessagesTime(post.CreateAt, location),
+				"",
+				"",
+				"",
+				formatListMessagesSystemMessage(post, options.trimLength),
+			})
+			continue
+		}
+		if _, ok := usernames[post.UserId]; !ok {
+			usernames[post.UserId] = p.getUsernameOrID(post.UserId)
+		}
+
+		files := ""
+		if len(post.FileIds) != 0 {
+			files = fmt.Sprintf("%d", len(post.FileIds))
+		}
+		replies := fmt.Sprintf("%d", result.replyCount)
+		if len(post.RootId) != 0 {
+			replies = "reply"
+		}
+		rows = append(rows, []string{
+			post.Id,
+			formatListMessagesTime(post.CreateAt, location),
+			"@" + usernames[post.UserId],
+			replies,
+			files,
+			cleanAndTrimMessage(post.Message, options.trimLength),
+		})
+	}
+
+	msg := getListMessagesHeader(len(results), scan, options) + "\n\n"
+	msg += markdownTable([]string{"Message ID", "Time", "Author", "Replies", "Files", "Message"}, rows)
+	if scan.limitReached {
+		msg += "\n\n" + getListMessagesScanLimitNote()
+	}
 
 	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/mattermost/mattermost-server/v5/model"
@@ -20,6 +21,7 @@ func TestMessagelListCommand(t *testing.T) {
 
 	api := &plugintest.API{}
 	api.On("GetPostsForChannel", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(testPostList, nil)
+	api.On("GetUser", mock.AnythingOfType("string")).Return(&model.User{Username: "author"}, nil)
 
 	var plugin Plugin
 	plugin.SetAPI(api)
@@ -38,7 +40,7 @@ func TestMessagelListCommand(t *testing.T) {
 		resp, isUserError, err := plugin.runListMessagesCommand([]string{"--count=50"}, &model.CommandArgs{ChannelId: testChannel.Id})
 		require.NoError(t, err)
 		assert.False(t, isUserError)
-		assert.Contains(t, resp.Text, "The last 50 messages in this channel")
+		assert.Contains(t, resp.Text, "3 message(s) found among the 3 most recent messages searched in this channel")
 		for _, post := range testPostList.ToSlice() {
 			assert.Contains(t, resp.Text, post.Id)
 			assert.Contains(t, resp.Text, post.Message)
@@ -64,7 +66,7 @@ func TestMessagelListCommand(t *testing.T) {
 		resp, isUserError, err := plugin.runListMessagesCommand([]string{"--trim-length=60"}, &model.CommandArgs{ChannelId: testChannel.Id})
 		require.NoError(t, err)
 		assert.False(t, isUserError)
-		assert.Contains(t, resp.Text, "The last 20 messages in this channel")
+		assert.Contains(t, resp.Text, "3 message(s) found among the 3 most recent messages searched in this channel")
 		for _, post := range testPostList.ToSlice() {
 			assert.Contains(t, resp.Text, post.Id)
 			assert.Contains(t, resp.Text, post.Message)
@@ -91,6 +93,7 @@ func TestMessagelListCommand(t *testing.T) {
 
 		api := &plugintest.API{}
 		api.On("GetPostsForChannel", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(testPostList, nil)
+		api.On("GetUser", mock.AnythingOfType("string")).Return(&model.User{Username: "author"}, nil)
 
 		var plugin Plugin
 		plugin.SetAPI(api)
@@ -98,7 +101,214 @@ func TestMessagelListCommand(t *testing.T) {
 		resp, isUserError, err := plugin.runListMessagesCommand([]string{}, &model.CommandArgs{ChannelId: testChannel.Id})
 		require.NoError(t, err)
 		assert.False(t, isUserError)
-		assert.Contains(t, resp.Text, "[     system message     ] - <skipped>")
+		for _, post := range testPostList.ToSlice() {
+			assert.Contains(t, resp.Text, post.Id)
+			assert.Contains(t, resp.Text, "system message "+post.Message)
+		}
+	})
+
+	t.Run("formats", func(t *testing.T) {
+		testPostList := mockGeneratePostList(3, testChannel.Id, false)
+		author := &model.User{Id: model.NewId(), Username: "author"}
+		for _, post := range testPostList.Posts {
+			post.UserId = author.Id
+		}
+		testPostList.Posts[testPostList.Order[0]].Type = model.POST_SYSTEM_MESSAGE_PREFIX + "join_channel"
+
+		api := &plugintest.API{}
+		api.On("GetPostsForChannel", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(testPostList, nil)
+		api.On("GetUser", author.Id).Return(author, nil)
+		api.On("GetUser", mock.AnythingOfType("string")).Return(nil, &model.AppError{})
+
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		t.Run("json", func(t *testing.T) {
+			resp, isUserError, err := plugin.runListMessagesCommand([]string{"--format=json"}, &model.CommandArgs{ChannelId: testChannel.Id})
+			require.NoError(t, err)
+			assert.False(t, isUserError)
+
+			var output listMessagesOutput
+			require.NoError(t, json.Unmarshal([]byte(resp.Text), &output))
+			assert.Equal(t, testChannel.Id, output.ChannelID)
+			assert.Equal(t, 3, output.ScannedCount)
+			require.Len(t, output.Messages, 3)
+			for i, post := range testPostList.ToSlice() {
+				assert.Equal(t, post.Id, output.Messages[i].ID)
+				assert.Equal(t, "author", output.Messages[i].Username)
+				assert.Equal(t, post.Message, output.Messages[i].Message)
+				assert.Equal(t, post.IsSystemMessage(), output.Messages[i].IsSystem)
+			}
+		})
+
+		t.Run("markdown", func(t *testing.T) {
+			resp, isUserError, err := plugin.runListMessagesCommand([]string{"--format=markdown"}, &model.CommandArgs{ChannelId: testChannel.Id})
+			require.NoError(t, err)
+			assert.False(t, isUserError)
+			assert.Contains(t, resp.Text, "| Message ID | Time | Author | Replies | Files | Message |")
+			assert.Contains(t, resp.Text, "@author")
+			assert.Contains(t, resp.Text, testPostList.Order[0])
+			assert.Contains(t, resp.Text, "system message (join_channel)")
+		})
+	})
+}
+
+func TestMessageListCommandFilters(t *testing.T) {
+	channelID := model.NewId()
+	author := &model.User{Id: model.NewId(), Username: "author", Timezone: model.StringMap{"useAutomaticTimezone": "false", "manualTimezone": "Europe/Berlin"}}
+	other := &model.User{Id: model.NewId(), Username: "other"}
+
+	root1 := &model.Post{Id: model.NewId(), UserId: author.Id, ChannelId: channelID, Message: "root 1", CreateAt: 1000}
+	root2 := &model.Post{Id: model.NewId(), UserId: other.Id, ChannelId: channelID, Message: "root 2", CreateAt: 2000, FileIds: []string{model.NewId()}}
+	reply1 := &model.Post{Id: model.NewId(), UserId: other.Id, ChannelId: channelID, RootId: root1.Id, Message: "reply 1", CreateAt: 3000}
+	reply2 := &model.Post{Id: model.NewId(), UserId: author.Id, ChannelId: channelID, RootId: root1.Id, Message: "reply 2", CreateAt: 4000}
+
+	postList := model.NewPostList()
+	for _, post := range []*model.Post{reply2, reply1, root2, root1} {
+		postList.AddPost(post)
+		postList.AddOrder(post.Id)
+	}
+
+	api := &plugintest.API{}
+	api.On("GetPostsForChannel", channelID, 0, listMessagesPageSize).Return(postList, nil)
+	api.On("GetUser", author.Id).Return(author, nil)
+	api.On("GetUser", other.Id).Return(other, nil)
+	api.On("GetUserByUsername", "other").Return(other, nil)
+	api.On("GetUserByUsername", "unknown").Return(nil, &model.AppError{})
+	api.On("GetPost", root2.Id).Return(root2, nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	list := func(t *testing.T, args ...string) []*listedMessage {
+		resp, isUserError, err := plugin.runListMessagesCommand(append(args, "--format=json"), &model.CommandArgs{ChannelId: channelID, UserId: author.Id})
+		require.NoError(t, err)
+		require.False(t, isUserError, resp.Text)
+
+		var output listMessagesOutput
+		require.NoError(t, json.Unmarshal([]byte(resp.Text), &output))
+		return output.Messages
+	}
+	ids := func(messages []*listedMessage) []string {
+		var ids []string
+		for _, message := range messages {
+			ids = append(ids, message.ID)
+		}
+		return ids
+	}
+
+	t.Run("replies are listed by default", func(t *testing.T) {
+		assert.Equal(t, []string{reply2.Id, reply1.Id, root2.Id, root1.Id}, ids(list(t)))
+	})
+
+	t.Run("roots only with reply counts", func(t *testing.T) {
+		messages := list(t, "--roots-only")
+		assert.Equal(t, []string{root2.Id, root1.Id}, ids(messages))
+		assert.Equal(t, int64(0), messages[0].ReplyCount)
+		assert.Equal(t, 1, messages[0].FileCount)
+		assert.Equal(t, int64(2), messages[1].ReplyCount)
+	})
+
+	t.Run("threads only", func(t *testing.T) {
+		assert.Equal(t, []string{root1.Id}, ids(list(t, "--threads-only")))
+	})
+
+	t.Run("user", func(t *testing.T) {
+		assert.Equal(t, []string{reply1.Id, root2.Id}, ids(list(t, "--user=@other")))
+		assert.Equal(t, []string{reply2.Id, root1.Id}, ids(list(t, "--user", author.Id)))
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		resp, isUserError, err := plugin.runListMessagesCommand([]string{"--user=unknown"}, &model.CommandArgs{ChannelId: channelID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Equal(t, "Error: unable to find user unknown", resp.Text)
+	})
+
+	t.Run("before and after a message", func(t *testing.T) {
+		assert.Equal(t, []string{root1.Id}, ids(list(t, "--before", root2.Id)))
+		assert.Equal(t, []string{reply2.Id, reply1.Id}, ids(list(t, "--after", root2.Id)))
+	})
+
+	t.Run("invalid bound", func(t *testing.T) {
+		resp, isUserError, err := plugin.runListMessagesCommand([]string{"--after=yesterday"}, &model.CommandArgs{ChannelId: channelID})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: --after must be a message ID or link, a date or a duration")
+	})
+
+	t.Run("paging", func(t *testing.T) {
+		assert.Equal(t, []string{reply2.Id, reply1.Id}, ids(list(t, "--count=2")))
+		assert.Equal(t, []string{root2.Id, root1.Id}, ids(list(t, "--count=2", "--page=1")))
+		assert.Empty(t, list(t, "--count=2", "--page=2"))
+	})
+
+	t.Run("header counts shown and scanned messages", func(t *testing.T) {
+		resp, isUserError, err := plugin.runListMessagesCommand([]string{"--user=@other"}, &model.CommandArgs{ChannelId: channelID, UserId: author.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "2 message(s) found among the 4 most recent messages searched in this channel:")
+
+		resp, isUserError, err = plugin.runListMessagesCommand([]string{"--count=1", "--page=1"}, &model.CommandArgs{ChannelId: channelID, UserId: author.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Page 1: 1 message(s) found among the 2 most recent messages searched in this channel:")
+	})
+
+	t.Run("table columns", func(t *testing.T) {
+		resp, isUserError, err := plugin.runListMessagesCommand([]string{}, &model.CommandArgs{ChannelId: channelID, UserId: author.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, reply1.Id+" - 1970-01-01 01:00 CET - @other - reply - reply 1")
+		assert.Contains(t, resp.Text, root1.Id+" - 1970-01-01 01:00 CET - @author - 2 replies - root 1")
+		assert.Contains(t, resp.Text, root2.Id+" - 1970-01-01 01:00 CET - @other - 0 replies, 1 files - root 2")
+	})
+}
+
+func TestMessageListCommandScanLimit(t *testing.T) {
+	channelID := model.NewId()
+	author := &model.User{Id: model.NewId(), Username: "author"}
+	other := &model.User{Id: model.NewId(), Username: "other"}
+
+	postList := model.NewPostList()
+	for i := 0; i < listMessagesPageSize; i++ {
+		post := &model.Post{Id: model.NewId(), UserId: author.Id, ChannelId: channelID, Message: "message", CreateAt: int64(1000 + i)}
+		postList.AddPost(post)
+		postList.AddOrder(post.Id)
+	}
+
+	api := &plugintest.API{}
+	api.On("GetPostsForChannel", channelID, mock.Anything, listMessagesPageSize).Return(postList, nil)
+	api.On("GetUser", author.Id).Return(author, nil)
+	api.On("GetUserByUsername", "other").Return(other, nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	t.Run("table", func(t *testing.T) {
+		resp, isUserError, err := plugin.runListMessagesCommand([]string{"--user=@other"}, &model.CommandArgs{ChannelId: channelID, UserId: author.Id})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "No results found")
+		assert.Contains(t, resp.Text, getListMessagesScanLimitNote())
+		api.AssertNumberOfCalls(t, "GetPostsForChannel", listMessagesMaxScanned/listMessagesPageSize)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		resp, _, err := plugin.runListMessagesCommand([]string{"--user=@other", "--format=json"}, &model.CommandArgs{ChannelId: channelID, UserId: author.Id})
+		require.NoError(t, err)
+
+		var output listMessagesOutput
+		require.NoError(t, json.Unmarshal([]byte(resp.Text), &output))
+		assert.True(t, output.ScanLimitReached)
+		assert.Equal(t, listMessagesMaxScanned, output.ScannedCount)
+		assert.Empty(t, output.Messages)
+	})
+
+	t.Run("not reached", func(t *testing.T) {
+		resp, _, err := plugin.runListMessagesCommand([]string{"--count=5"}, &model.CommandArgs{ChannelId: channelID, UserId: author.Id})
+		require.NoError(t, err)
+		assert.NotContains(t, resp.Text, getListMessagesScanLimitNote())
 	})
 }
 
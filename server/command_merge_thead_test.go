@@ -97,13 +97,20 @@ func TestMergeThreadCommand(t *testing.T) {
 	api.On("GetChannel", oldPostID).Return(targetChannel, nil)
 	api.On("GetChannel", targetByLinkPostID).Return(targetChannel, nil)
 
-	api.On("GetPostThread", originalPostID).Return(generatedOriginalPosts, nil)
-	api.On("GetPostThread", privatePostID).Return(generatedPrivatePosts, nil)
-	api.On("GetPostThread", directPostID).Return(generatedDirectPosts, nil)
-	api.On("GetPostThread", groupPostID).Return(generatedGroupPosts, nil)
-	api.On("GetPostThread", targetPostID).Return(generatedTargetPosts, nil)
-	api.On("GetPostThread", oldPostID).Return(oldGeneratedPosts, nil)
-	api.On("GetPostThread", targetByLinkPostID).Return(generatedTargetByLinkPosts, nil)
+	// Threads can be read from any of their posts.
+	for _, postList := range []*model.PostList{
+		generatedOriginalPosts,
+		generatedPrivatePosts,
+		generatedDirectPosts,
+		generatedGroupPosts,
+		generatedTargetPosts,
+		oldGeneratedPosts,
+		generatedTargetByLinkPosts,
+	} {
+		for postID := range postList.Posts {
+			api.On("GetPostThread", postID).Return(postList, nil)
+		}
+	}
 
 	api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(mockGenerateChannelMember(), nil)
 	api.On("GetTeam", mock.AnythingOfType("string")).Return(targetTeam, nil)
@@ -113,6 +120,7 @@ func TestMergeThreadCommand(t *testing.T) {
 	api.On("GetReactions", mock.AnythingOfType("string")).Return(reactions, nil)
 	api.On("AddReaction", mock.Anything).Return(nil, nil)
 	api.On("GetConfig").Return(config)
+	mockKVStore(api)
 	api.On("LogInfo",
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("string"),
@@ -133,7 +141,7 @@ func TestMergeThreadCommand(t *testing.T) {
 	plugin.SetAPI(api)
 
 	t.Run("not enabled", func(t *testing.T) {
-		resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID, targetPostID}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID, targetPostID}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Merge thread command is not enabled")
@@ -143,14 +151,14 @@ func TestMergeThreadCommand(t *testing.T) {
 	require.NoError(t, plugin.configuration.IsValid())
 
 	t.Run("no args", func(t *testing.T) {
-		resp, isUserError, err := plugin.runMergeThreadCommand([]string{}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runMergeThreadCommand([]string{}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: missing arguments")
 	})
 
 	t.Run("one arg", func(t *testing.T) {
-		resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: missing arguments")
@@ -158,7 +166,7 @@ func TestMergeThreadCommand(t *testing.T) {
 
 	t.Run("private channel", func(t *testing.T) {
 		t.Run("disabled", func(t *testing.T) {
-			resp, isUserError, err := plugin.runMergeThreadCommand([]string{privatePostID, targetPostID}, &model.CommandArgs{ChannelId: privateChannel.Id})
+			resp, isUserError, err := plugin.runMergeThreadCommand([]string{privatePostID, targetPostID}, &model.CommandArgs{ChannelId: privateChannel.Id}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.False(t, isUserError)
 			assert.Contains(t, resp.Text, "Wrangler is currently configured to not allow moving posts from private channels")
@@ -167,7 +175,7 @@ func TestMergeThreadCommand(t *testing.T) {
 
 	t.Run("direct channel", func(t *testing.T) {
 		t.Run("disabled", func(t *testing.T) {
-			resp, isUserError, err := plugin.runMergeThreadCommand([]string{directPostID, targetPostID}, &model.CommandArgs{ChannelId: directChannel.Id})
+			resp, isUserError, err := plugin.runMergeThreadCommand([]string{directPostID, targetPostID}, &model.CommandArgs{ChannelId: directChannel.Id}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.False(t, isUserError)
 			assert.Contains(t, resp.Text, "Wrangler is currently configured to not allow moving posts from direct message channels")
@@ -176,7 +184,7 @@ func TestMergeThreadCommand(t *testing.T) {
 
 	t.Run("group channel", func(t *testing.T) {
 		t.Run("disabled", func(t *testing.T) {
-			resp, isUserError, err := plugin.runMergeThreadCommand([]string{groupPostID, targetPostID}, &model.CommandArgs{ChannelId: groupChannel.Id})
+			resp, isUserError, err := plugin.runMergeThreadCommand([]string{groupPostID, targetPostID}, &model.CommandArgs{ChannelId: groupChannel.Id}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.False(t, isUserError)
 			assert.Contains(t, resp.Text, "Wrangler is currently configured to not allow moving posts from group message channels")
@@ -185,7 +193,7 @@ func TestMergeThreadCommand(t *testing.T) {
 
 	t.Run("to another team", func(t *testing.T) {
 		t.Run("disabled", func(t *testing.T) {
-			resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID, targetPostID}, &model.CommandArgs{ChannelId: originalChannel.Id})
+			resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID, targetPostID}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.False(t, isUserError)
 			assert.Contains(t, resp.Text, "Wrangler is currently configured to not allow moving messages to different teams")
@@ -196,14 +204,14 @@ func TestMergeThreadCommand(t *testing.T) {
 	require.NoError(t, plugin.configuration.IsValid())
 
 	t.Run("merge thead into itself", func(t *testing.T) {
-		resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID, originalPostID}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID, originalPostID}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: Original and target threads are the same")
 	})
 
 	t.Run("merge older thread into newer thread", func(t *testing.T) {
-		resp, isUserError, err := plugin.runMergeThreadCommand([]string{oldPostID, targetPostID}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runMergeThreadCommand([]string{oldPostID, targetPostID}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: Cannot merge older threads into newer threads. The destination thread must be older than the thread being moved.")
@@ -216,7 +224,7 @@ func TestMergeThreadCommand(t *testing.T) {
 	targetCall.Return(nil, &model.AppError{})
 
 	t.Run("no original channel member", func(t *testing.T) {
-		resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID, targetPostID}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID, targetPostID}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: Original Channel: Channel with ID")
@@ -225,7 +233,7 @@ func TestMergeThreadCommand(t *testing.T) {
 	originalCall.Return(nil, nil)
 
 	t.Run("no target channel member", func(t *testing.T) {
-		resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID, targetPostID}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID, targetPostID}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: Target Channel: Channel with ID")
@@ -234,17 +242,26 @@ func TestMergeThreadCommand(t *testing.T) {
 	targetCall.Return(nil, nil)
 
 	t.Run("merge thread successfully", func(t *testing.T) {
-		resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID, targetPostID}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID, targetPostID}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.False(t, isUserError)
 		assert.Contains(t, resp.Text, "A thread with 3 message(s) has been merged")
 	})
 
+	t.Run("merge thread dry run", func(t *testing.T) {
+		resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID, targetPostID, "--dry-run"}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Dry run: no messages were merged")
+		assert.Contains(t, resp.Text, "3 message(s) would be merged into the thread")
+		assert.Contains(t, resp.Text, "Direct messages: none")
+	})
+
 	t.Run("thread is above configuration move-maximum", func(t *testing.T) {
 		plugin.configuration.MoveThreadMaxCount = "1"
 		require.NoError(t, plugin.configuration.IsValid())
 
-		resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID, targetPostID}, &model.CommandArgs{ChannelId: model.NewId()})
+		resp, isUserError, err := plugin.runMergeThreadCommand([]string{originalPostID, targetPostID}, &model.CommandArgs{ChannelId: model.NewId()}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: the thread is 3 posts long, but this command is configured to only move threads of up to 1 posts")
@@ -261,7 +278,7 @@ func TestMergeThreadCommand(t *testing.T) {
 		cleanOriginalPostID := getMessageIDFromLink(originalPostLink, *config.ServiceSettings.SiteURL)
 		cleanTargetPostID := getMessageIDFromLink(targetPostLink, *config.ServiceSettings.SiteURL)
 
-		resp, isUserError, err := plugin.runMergeThreadCommand([]string{cleanOriginalPostID, cleanTargetPostID}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runMergeThreadCommand([]string{cleanOriginalPostID, cleanTargetPostID}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.False(t, isUserError)
 		assert.Contains(t, resp.Text, "A thread with 3 message(s) has been merged")
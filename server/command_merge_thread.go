@@ -6,66 +6,157 @@ import (
 
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
 )
 
-const mergeThreadUsage = `
-/wrangler merge thread [ROOT_MESSAGE_ID or ROOT_MESSAGE_LINK] [TARGET_ROOT_MESSAGE_ID or TARGET_ROOT_MESSAGE_LINK]
+const mergeThreadUsage = `/wrangler merge thread [ROOT_MESSAGE_ID or ROOT_MESSAGE_LINK] [TARGET_ROOT_MESSAGE_ID or TARGET_ROOT_MESSAGE_LINK]
   Merge the messages of two threads
     - Message creation timestamps of both threads will be preserved. This could result in merged threads having messages that seem out of order or with different contexts.
 	- Use the '/wrangler list' commands to get message and channel IDs
-`
+	Flags:
+%s`
+
+type mergeThreadOptions struct {
+	dryRun bool
+}
+
+func getMergeThreadFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("merge thread", pflag.ContinueOnError)
+	flagSet.Bool(flagDryRun, false, "Show what would be merged without merging anything")
+
+	return flagSet
+}
+
+func parseMergeThreadFlagArgs(args []string) (mergeThreadOptions, error) {
+	var options mergeThreadOptions
+
+	flagSet := getMergeThreadFlagSet()
+	err := flagSet.Parse(args)
+	if err != nil {
+		return options, errors.Wrap(err, "unable to parse merge thread flag args")
+	}
+
+	options.dryRun, _ = flagSet.GetBool(flagDryRun)
+
+	return options, nil
+}
+
+func getMergeThreadUsage() string {
+	return fmt.Sprintf(mergeThreadUsage, getMergeThreadFlagSet().FlagUsages())
+}
 
 func getMergeThreadMessage() string {
-	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", mergeThreadUsage))
+	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", getMergeThreadUsage()))
+}
+
+func (p *Plugin) runMergeThreadCommand(args []string, extra *model.CommandArgs, audit *AuditRecord) (*model.CommandResponse, bool, error) {
+	return withoutWranglerResult(p.runMergeThreadCommandWithResult(args, extra, audit))
 }
 
-func (p *Plugin) runMergeThreadCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+// runMergeThreadCommandWithResult runs the merge thread command and also returns the thread
+// the messages were merged into.
+func (p *Plugin) runMergeThreadCommandWithResult(args []string, extra *model.CommandArgs, audit *AuditRecord) (*model.CommandResponse, *wranglerResult, bool, error) {
 	if !p.getConfiguration().MergeThreadEnable {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Merge thread command is not enabled"), true, nil
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Merge thread command is not enabled"), nil, true, nil
 	}
 	if len(args) < 2 {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getMergeThreadMessage()), true, nil
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getMergeThreadMessage()), nil, true, nil
+	}
+	options, err := parseMergeThreadFlagArgs(args)
+	if err != nil {
+		return nil, nil, true, err
 	}
 	originalPostID := cleanInputID(args[0], extra.SiteURL)
 	mergeToPostID := cleanInputID(args[1], extra.SiteURL)
 
 	postListResponse, appErr := p.API.GetPostThread(originalPostID)
 	if appErr != nil {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get post with ID %s; ensure this is correct", originalPostID)), true, nil
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get post with ID %s; ensure this is correct", originalPostID)), nil, true, nil
 	}
 	wpl := buildWranglerPostList(postListResponse)
 	originalChannelID := wpl.RootPost().ChannelId
 
 	targetPostListResponse, appErr := p.API.GetPostThread(mergeToPostID)
 	if appErr != nil {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get post with ID %s; ensure this is correct", mergeToPostID)), true, nil
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get post with ID %s; ensure this is correct", mergeToPostID)), nil, true, nil
 	}
 	targetRootPost := getRootPostFromPostList(targetPostListResponse)
 
-	err := p.ensureOriginalAndTargetChannelMember(originalChannelID, targetRootPost.ChannelId, extra.UserId)
+	err = p.ensureOriginalAndTargetChannelMember(originalChannelID, targetRootPost.ChannelId, extra.UserId)
 	if err != nil {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, err.Error()), true, nil
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, err.Error()), nil, true, nil
 	}
 
 	originalChannel, appErr := p.API.GetChannel(originalChannelID)
 	if appErr != nil {
-		return nil, false, errors.Errorf("unable to get channel with ID %s", originalChannelID)
+		return nil, nil, false, errors.Errorf("unable to get channel with ID %s", originalChannelID)
 	}
 	targetChannel, appErr := p.API.GetChannel(targetRootPost.ChannelId)
 	if appErr != nil {
-		return nil, false, errors.Errorf("unable to get channel with ID %s", targetRootPost.ChannelId)
+		return nil, nil, false, errors.Errorf("unable to get channel with ID %s", targetRootPost.ChannelId)
 	}
 
 	response, userErr, err := p.validateMerge(wpl, targetRootPost, originalChannel, targetChannel, extra)
 	if response != nil || err != nil {
-		return response, userErr, err
+		return response, nil, userErr, err
 	}
 
 	targetTeam, appErr := p.API.GetTeam(targetChannel.TeamId)
 	if appErr != nil {
-		return nil, false, errors.Errorf("unable to get team with ID %s", targetChannel.TeamId)
+		return nil, nil, false, errors.Errorf("unable to get team with ID %s", targetChannel.TeamId)
+	}
+
+	audit.setWranglerDetails(wpl.RootPost().Id, originalChannel.Id, targetRootPost.Id, targetChannel.Id, wpl.NumPosts())
+
+	if options.dryRun {
+		targetPostLink := makePostLink(*p.API.GetConfig().ServiceSettings.SiteURL, targetTeam.Name, targetRootPost.Id)
+		return noWranglerResult(p.buildDryRunResponse(wpl, "merged", fmt.Sprintf("into the thread %s", targetPostLink), nil))
+	}
+
+	lock, response, err := p.acquireThreadLock(operationTypeMerge, extra, wpl.RootPost().Id, targetRootPost.Id)
+	if err != nil {
+		return nil, nil, false, err
 	}
+	if response != nil {
+		return response, nil, true, nil
+	}
+
+	// Both threads are read and validated again now that they are locked.
+	var targetWpl *WranglerPostList
+	wpl, response, userErr, err = p.getLockedThread(wpl.RootPost().Id)
+	if response == nil && err == nil {
+		targetWpl, response, userErr, err = p.getLockedThread(targetRootPost.Id)
+	}
+	if response == nil && err == nil {
+		targetRootPost = targetWpl.RootPost()
+		response, userErr, err = p.validateMerge(wpl, targetRootPost, originalChannel, targetChannel, extra)
+	}
+	if response != nil || err != nil {
+		p.releaseThreadLock(lock)
+		return response, nil, userErr, err
+	}
+	audit.MessageCount = wpl.NumPosts()
+
+	if p.shouldRunAsJob(wpl) {
+		job := &WranglerJob{
+			Type:       jobTypeMerge,
+			UserID:     extra.UserId,
+			ChannelID:  extra.ChannelId,
+			RootPostID: wpl.RootPost().Id,
+			TotalPosts: wpl.NumPosts(),
+		}
+		return p.startWranglerJob(job, lock, audit, func(progress progressFunc, jobAudit *AuditRecord) (*model.CommandResponse, bool, error) {
+			return withoutWranglerResult(p.mergeThread(wpl, targetRootPost, originalChannel, targetChannel, targetTeam, extra, jobAudit, progress))
+		})
+	}
+
+	defer p.releaseThreadLock(lock)
+
+	return p.mergeThread(wpl, targetRootPost, originalChannel, targetChannel, targetTeam, extra, audit, nil)
+}
 
+// mergeThread merges a validated thread into the target thread.
+func (p *Plugin) mergeThread(wpl *WranglerPostList, targetRootPost *model.Post, originalChannel, targetChannel *model.Channel, targetTeam *model.Team, extra *model.CommandArgs, audit *AuditRecord, progress progressFunc) (*model.CommandResponse, *wranglerResult, bool, error) {
 	// Begin merging the thread.
 	p.API.LogInfo("Wrangler is merging a thread",
 		"user_id", extra.UserId,
@@ -78,65 +169,57 @@ func (p *Plugin) runMergeThreadCommand(args []string, extra *model.CommandArgs)
 
 	// To merge threads, we first copy the original messages(s) to the new
 	// thread and later delete the original messages(s).
-	err = p.mergeWranglerPostlist(wpl, targetRootPost)
+	postIDs, err := p.mergeWranglerPostlist(wpl, targetRootPost, progress)
 	if err != nil {
-		return nil, false, err
+		return noWranglerResult(p.getRollbackResponse(err))
 	}
 
 	// Cleanup is handled by simply deleting the root post. Any comments/replies
 	// are automatically marked as deleted for us.
-	appErr = p.API.DeletePost(wpl.RootPost().Id)
+	appErr := p.API.DeletePost(wpl.RootPost().Id)
 	if appErr != nil {
-		return nil, false, errors.Wrap(appErr, "unable to delete post")
+		return noWranglerResult(p.getRollbackResponse(p.rollback(errors.Wrap(appErr, "unable to delete post"), postIDs, nil)))
 	}
 
+	p.recordThreadRedirectOrLog(wpl.RootPost().Id, targetRootPost.Id, targetRootPost.ChannelId)
+
 	p.API.LogInfo("Wrangler thread merge complete",
 		"user_id", extra.UserId,
 		"target_root_post_id", targetRootPost.Id,
 		"target_root_post_channel_id", targetRootPost.ChannelId,
 	)
 
+	operation := &WranglerOperation{
+		Type:              operationTypeMerge,
+		UserID:            extra.UserId,
+		OriginalChannelID: originalChannel.Id,
+		TargetChannelID:   targetChannel.Id,
+		OriginalRootID:    wpl.RootPost().Id,
+		NewRootID:         targetRootPost.Id,
+		Posts:             postIDs,
+	}
+	p.recordWranglerOperationOrLog(operation)
+	audit.OperationID = operation.ID
+	result := &wranglerResult{
+		PostID:       targetRootPost.Id,
+		ChannelID:    targetChannel.Id,
+		MessageCount: wpl.NumPosts(),
+		OperationID:  operation.ID,
+	}
+
 	newPostLink := makePostLink(*p.API.GetConfig().ServiceSettings.SiteURL, targetTeam.Name, targetRootPost.Id)
 
-	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("A thread with %d message(s) has been merged: %s\n", wpl.NumPosts(), newPostLink)), false, nil
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("A thread with %d message(s) has been merged: %s\n%s", wpl.NumPosts(), newPostLink, operationSuffix(operation))), result, false, nil
 }
 
-func (p *Plugin) mergeWranglerPostlist(wpl *WranglerPostList, targetRootPost *model.Post) error {
+func (p *Plugin) mergeWranglerPostlist(wpl *WranglerPostList, targetRootPost *model.Post, progress progressFunc) ([]postIDPair, error) {
 	var err error
 	var appErr *model.AppError
+	var postIDs []postIDPair
 
-	if wpl.ContainsFileAttachments() {
-		// The thread contains at least one attachment. To properly move the
-		// thread, the files will have to be re-uploaded. This is completed
-		// before any messages are moved.
-		// TODO: check number of files that need to be re-uploaded or file size?
-		p.API.LogInfo("Wrangler is re-uploading file attachments",
-			"file_count", wpl.FileAttachmentCount,
-		)
-
-		for _, post := range wpl.Posts {
-			var newFileIDs []string
-			var fileBytes []byte
-			var oldFileInfo, newFileInfo *model.FileInfo
-			for _, fileID := range post.FileIds {
-				oldFileInfo, appErr = p.API.GetFileInfo(fileID)
-				if appErr != nil {
-					return errors.Wrap(appErr, "unable to lookup file info to re-upload")
-				}
-				fileBytes, appErr = p.API.GetFile(fileID)
-				if appErr != nil {
-					return errors.Wrap(appErr, "unable to get file bytes to re-upload")
-				}
-				newFileInfo, appErr = p.API.UploadFile(fileBytes, targetRootPost.ChannelId, oldFileInfo.Name)
-				if appErr != nil {
-					return errors.Wrap(appErr, "unable to re-upload file")
-				}
-
-				newFileIDs = append(newFileIDs, newFileInfo.Id)
-			}
-
-			post.FileIds = newFileIDs
-		}
+	uploadedFileIDs, err := p.reuploadFileAttachments(wpl, targetRootPost.ChannelId)
+	if err != nil {
+		return nil, p.rollback(err, postIDs, uploadedFileIDs)
 	}
 
 	for _, post := range wpl.Posts {
@@ -158,9 +241,11 @@ func (p *Plugin) mergeWranglerPostlist(wpl *WranglerPostList, targetRootPost *mo
 
 		newPost, err = p.createPostWithRetries(newPost, 200*time.Millisecond, 3)
 		if err != nil {
-			return errors.Wrap(err, "unable to create new post")
+			return nil, p.rollback(errors.Wrap(err, "unable to create new post"), postIDs, uploadedFileIDs)
 		}
 
+		postIDs = append(postIDs, postIDPair{OriginalID: post.Id, NewID: newPost.Id})
+
 		for _, reaction := range reactions {
 			reaction.PostId = newPost.Id
 			_, appErr = p.API.AddReaction(reaction)
@@ -170,7 +255,14 @@ func (p *Plugin) mergeWranglerPostlist(wpl *WranglerPostList, targetRootPost *mo
 				p.API.LogError("Failed to reapply reactions to post", "err", appErr)
 			}
 		}
+
+		if progress != nil {
+			err = progress(len(postIDs))
+			if err != nil {
+				return nil, p.rollback(err, postIDs, uploadedFileIDs)
+			}
+		}
 	}
 
-	return nil
+	return postIDs, nil
 }
@@ -9,9 +9,10 @@ import (
 )
 
 const (
-	moveThreadUsage = `/wrangler move thread [MESSAGE_ID or MESSAGE_LINK] [CHANNEL_ID]
+	moveThreadUsage = `/wrangler move thread [MESSAGE_ID or MESSAGE_LINK] [CHANNEL]
   Move a given message, along with the thread it belongs to, to a given channel
     - This can be on any channel in any team that you have joined
+    - The channel can be a channel ID, ~channel-name, team-name:channel-name, display name or channel link
 	- Use the '/wrangler list' commands to get message and channel IDs
 	Flags:
 %s`
@@ -20,25 +21,43 @@ const (
 	flagMoveThreadSilent             = "silent"
 )
 
+type moveThreadOptions struct {
+	showRootMessageInSummary bool
+	silent                   bool
+	dryRun                   bool
+	notify                   string
+}
+
 func getMoveThreadFlagSet() *pflag.FlagSet {
 	flagSet := pflag.NewFlagSet("move thread", pflag.ContinueOnError)
 	flagSet.Bool(flagMoveThreadShowMessageSummary, true, "Show the root message in the post-move summary")
 	flagSet.Bool(flagMoveThreadSilent, false, "Silence all Wrangler summary messages and user DMs when moving the thread")
+	flagSet.Bool(flagDryRun, false, "Show what would be moved without moving anything")
+	flagSet.String(flagNotify, "", "Who is sent a DM about the moved thread: root-author, all-participants or none (defaults to the plugin setting)")
 
 	return flagSet
 }
 
-func parseMoveThreadFlagArgs(args []string) (bool, bool, error) {
+func parseMoveThreadFlagArgs(args []string) (moveThreadOptions, error) {
+	var options moveThreadOptions
+
 	flagSet := getMoveThreadFlagSet()
 	err := flagSet.Parse(args)
 	if err != nil {
-		return false, false, errors.Wrap(err, "unable to parse move thread flag args")
+		return options, errors.Wrap(err, "unable to parse move thread flag args")
 	}
 
-	showMessageSummary, _ := flagSet.GetBool(flagMoveThreadShowMessageSummary)
-	silent, _ := flagSet.GetBool(flagMoveThreadSilent)
+	options.showRootMessageInSummary, _ = flagSet.GetBool(flagMoveThreadShowMessageSummary)
+	options.silent, _ = flagSet.GetBool(flagMoveThreadSilent)
+	options.dryRun, _ = flagSet.GetBool(flagDryRun)
+	options.notify, _ = flagSet.GetString(flagNotify)
+	if len(options.notify) != 0 {
+		if _, err = parseNotifyPolicy(options.notify); err != nil {
+			return options, err
+		}
+	}
 
-	return showMessageSummary, silent, nil
+	return options, nil
 }
 
 func getMoveThreadUsage() string {
@@ -49,46 +68,104 @@ func getMoveThreadMessage() string {
 	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", getMoveThreadUsage()))
 }
 
-func (p *Plugin) runMoveThreadCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+func (p *Plugin) runMoveThreadCommand(args []string, extra *model.CommandArgs, audit *AuditRecord) (*model.CommandResponse, bool, error) {
+	return withoutWranglerResult(p.runMoveThreadCommandWithResult(args, extra, audit))
+}
+
+// runMoveThreadCommandWithResult runs the move thread command and also returns the thread
+// the messages were moved.
+func (p *Plugin) runMoveThreadCommandWithResult(args []string, extra *model.CommandArgs, audit *AuditRecord) (*model.CommandResponse, *wranglerResult, bool, error) {
 	if len(args) < 2 {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getMoveThreadMessage()), true, nil
+		if len(extra.TriggerId) != 0 {
+			return noWranglerResult(p.openWranglerDialog(p.getWranglerDialogCommand("move thread"), args, extra))
+		}
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getMoveThreadMessage()), nil, true, nil
 	}
-	showRootMessageInSummary, silent, err := parseMoveThreadFlagArgs(args)
+	options, err := parseMoveThreadFlagArgs(args)
 	if err != nil {
-		return nil, false, err
+		return nil, nil, true, err
+	}
+	options.notify = p.getNotifyPolicy(options.notify)
+	if options.silent {
+		options.notify = notifyPolicyNone
 	}
 	postID := cleanInputID(args[0], extra.SiteURL)
 	channelID := args[1]
 
 	postListResponse, appErr := p.API.GetPostThread(postID)
 	if appErr != nil {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get post with ID %s; ensure this is correct", postID)), true, nil
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get post with ID %s; ensure this is correct", postID)), nil, true, nil
 	}
 	wpl := buildWranglerPostList(postListResponse)
 
 	originalChannel, appErr := p.API.GetChannel(extra.ChannelId)
 	if appErr != nil {
-		return nil, false, fmt.Errorf("unable to get channel with ID %s", extra.ChannelId)
+		return nil, nil, false, fmt.Errorf("unable to get channel with ID %s", extra.ChannelId)
 	}
-	_, appErr = p.API.GetChannelMember(channelID, extra.UserId)
-	if appErr != nil {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: channel with ID %s doesn't exist or you are not a member", channelID)), true, nil
+	targetChannel, userMessage, err := p.resolveChannel(channelID, extra)
+	if err != nil {
+		return nil, nil, false, err
 	}
-	targetChannel, appErr := p.API.GetChannel(channelID)
-	if appErr != nil {
-		return nil, false, fmt.Errorf("unable to get channel with ID %s", channelID)
+	if len(userMessage) != 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, userMessage), nil, true, nil
 	}
 
 	response, userErr, err := p.validateMoveOrCopy(wpl, originalChannel, targetChannel, extra)
 	if response != nil || err != nil {
-		return response, userErr, err
+		return response, nil, userErr, err
 	}
 
 	targetTeam, appErr := p.API.GetTeam(targetChannel.TeamId)
 	if appErr != nil {
-		return nil, false, fmt.Errorf("unable to get team with ID %s", targetChannel.TeamId)
+		return nil, nil, false, fmt.Errorf("unable to get team with ID %s", targetChannel.TeamId)
+	}
+
+	audit.setWranglerDetails(wpl.RootPost().Id, originalChannel.Id, "", targetChannel.Id, wpl.NumPosts())
+
+	if options.dryRun {
+		dmUserIDs := p.getNotifiedUserIDs(wpl, options.notify, extra.UserId)
+		return noWranglerResult(p.buildDryRunResponse(wpl, "moved", fmt.Sprintf("to ~%s in team %s", targetChannel.Name, targetTeam.Name), dmUserIDs))
+	}
+
+	lock, response, err := p.acquireThreadLock(operationTypeMove, extra, wpl.RootPost().Id)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if response != nil {
+		return response, nil, true, nil
+	}
+
+	// The thread is read and validated again now that it is locked.
+	wpl, response, userErr, err = p.getLockedThread(wpl.RootPost().Id)
+	if response == nil && err == nil {
+		response, userErr, err = p.validateMoveOrCopy(wpl, originalChannel, targetChannel, extra)
+	}
+	if response != nil || err != nil {
+		p.releaseThreadLock(lock)
+		return response, nil, userErr, err
+	}
+	audit.MessageCount = wpl.NumPosts()
+
+	if p.shouldRunAsJob(wpl) {
+		job := &WranglerJob{
+			Type:       jobTypeMove,
+			UserID:     extra.UserId,
+			ChannelID:  extra.ChannelId,
+			RootPostID: wpl.RootPost().Id,
+			TotalPosts: wpl.NumPosts(),
+		}
+		return p.startWranglerJob(job, lock, audit, func(progress progressFunc, jobAudit *AuditRecord) (*model.CommandResponse, bool, error) {
+			return withoutWranglerResult(p.moveThread(wpl, originalChannel, targetChannel, targetTeam, options, extra, jobAudit, progress))
+		})
 	}
 
+	defer p.releaseThreadLock(lock)
+
+	return p.moveThread(wpl, originalChannel, targetChannel, targetTeam, options, extra, audit, nil)
+}
+
+// moveThread moves a validated thread to the target channel.
+func (p *Plugin) moveThread(wpl *WranglerPostList, originalChannel, targetChannel *model.Channel, targetTeam *model.Team, options moveThreadOptions, extra *model.CommandArgs, audit *AuditRecord, progress progressFunc) (*model.CommandResponse, *wranglerResult, bool, error) {
 	// Begin creating the new thread.
 	p.API.LogInfo("Wrangler is moving a thread",
 		"user_id", extra.UserId,
@@ -98,56 +175,78 @@ func (p *Plugin) runMoveThreadCommand(args []string, extra *model.CommandArgs) (
 
 	// To simulate the move, we first copy the original messages(s) to the
 	// new channel and later delete the original messages(s).
-	newRootPost, err := p.copyWranglerPostlist(wpl, targetChannel)
+	newRootPost, postIDs, err := p.copyWranglerPostlist(wpl, targetChannel, progress)
 	if err != nil {
-		return nil, false, err
+		return noWranglerResult(p.getRollbackResponse(err))
 	}
+	audit.TargetPostID = newRootPost.Id
 
-	if !silent {
-		_, appErr = p.API.CreatePost(&model.Post{
+	if !options.silent {
+		_, appErr := p.API.CreatePost(&model.Post{
 			UserId:    p.BotUserID,
 			RootId:    newRootPost.Id,
 			ParentId:  newRootPost.Id,
-			ChannelId: channelID,
+			ChannelId: targetChannel.Id,
 			Message:   "This thread was moved from another channel",
 		})
 		if appErr != nil {
-			return nil, false, errors.Wrap(appErr, "unable to create new bot post")
+			return noWranglerResult(p.getRollbackResponse(p.rollback(errors.Wrap(appErr, "unable to create new bot post"), postIDs, nil)))
 		}
 	}
 
 	// Cleanup is handled by simply deleting the root post. Any comments/replies
 	// are automatically marked as deleted for us.
-	appErr = p.API.DeletePost(wpl.RootPost().Id)
+	appErr := p.API.DeletePost(wpl.RootPost().Id)
 	if appErr != nil {
-		return nil, false, errors.Wrap(appErr, "unable to delete post")
+		return noWranglerResult(p.getRollbackResponse(p.rollback(errors.Wrap(appErr, "unable to delete post"), postIDs, nil)))
 	}
 
+	p.recordThreadRedirectOrLog(wpl.RootPost().Id, newRootPost.Id, targetChannel.Id)
+
 	p.API.LogInfo("Wrangler thread move complete",
 		"user_id", extra.UserId,
 		"new_post_id", newRootPost.Id,
-		"new_channel_id", channelID,
+		"new_channel_id", targetChannel.Id,
 	)
 
+	operation := &WranglerOperation{
+		Type:              operationTypeMove,
+		UserID:            extra.UserId,
+		OriginalChannelID: originalChannel.Id,
+		TargetChannelID:   targetChannel.Id,
+		OriginalRootID:    wpl.RootPost().Id,
+		NewRootID:         newRootPost.Id,
+		Posts:             postIDs,
+		TombstonePostID:   p.postMoveThreadTombstone(wpl, targetChannel, targetTeam, newRootPost, originalChannel.Id, extra.UserId, options),
+	}
+	p.recordWranglerOperationOrLog(operation)
+	audit.OperationID = operation.ID
+	result := &wranglerResult{
+		PostID:       newRootPost.Id,
+		ChannelID:    targetChannel.Id,
+		MessageCount: wpl.NumPosts(),
+		OperationID:  operation.ID,
+	}
+
 	newPostLink := makePostLink(*p.API.GetConfig().ServiceSettings.SiteURL, targetTeam.Name, newRootPost.Id)
 
-	if silent {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("A thread with %d message(s) has been silently moved: %s\n", wpl.NumPosts(), newPostLink)), false, nil
+	if options.silent {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("A thread with %d message(s) has been silently moved: %s\n%s", wpl.NumPosts(), newPostLink, operationSuffix(operation))), result, false, nil
 	}
 
 	executor, execError := p.API.GetUser(extra.UserId)
 	if execError != nil {
-		return nil, false, errors.Wrap(appErr, "unable to find executor")
+		return nil, nil, false, errors.Wrap(execError, "unable to find executor")
 	}
 
-	if extra.UserId != wpl.RootPost().UserId {
-		// The wrangled thread was not started by the user running the command.
-		// Send a DM to the user who created the root message to let them know.
-		err := p.postMoveThreadBotDM(wpl.RootPost().UserId, newPostLink, executor.Username)
+	// Let the users of the thread other than the one running the command know
+	// where it went.
+	for _, userID := range p.getNotifiedUserIDs(wpl, options.notify, extra.UserId) {
+		err := p.postMoveThreadBotDM(userID, newPostLink, executor.Username)
 		if err != nil {
 			p.API.LogError("Unable to send move-thread DM to user",
 				"error", err.Error(),
-				"user_id", wpl.RootPost().UserId,
+				"user_id", userID,
 			)
 		}
 	}
@@ -156,7 +255,7 @@ func (p *Plugin) runMoveThreadCommand(args []string, extra *model.CommandArgs) (
 	if wpl.NumPosts() == 1 {
 		msg = fmt.Sprintf("A message has been moved: %s\n", newPostLink)
 	}
-	if showRootMessageInSummary {
+	if options.showRootMessageInSummary {
 		msg += fmt.Sprintf("Original Thread Root Message:\n%s\n",
 			quoteBlock(cleanAndTrimMessage(
 				wpl.RootPost().Message, 500),
@@ -164,12 +263,59 @@ func (p *Plugin) runMoveThreadCommand(args []string, extra *model.CommandArgs) (
 		)
 	}
 
-	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_IN_CHANNEL, msg), false, nil
+	if len(operation.ID) != 0 {
+		// The revert instructions are only useful to the executor so they are
+		// sent separately from the in-channel summary.
+		p.API.SendEphemeralPost(extra.UserId, &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: extra.ChannelId,
+			Message:   operationSuffix(operation),
+		})
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_IN_CHANNEL, msg), result, false, nil
+}
+
+// postMoveThreadTombstone leaves a bot post where a moved thread used to be
+// that points at its new location. The ID of the post is returned, or an
+// empty string if no post was left.
+func (p *Plugin) postMoveThreadTombstone(wpl *WranglerPostList, targetChannel *model.Channel, targetTeam *model.Team, newRootPost *model.Post, originalChannelID, executorID string, options moveThreadOptions) string {
+	config := p.getConfiguration()
+	if !config.MoveThreadTombstoneEnable || options.silent {
+		return ""
+	}
+
+	message := makeTemplateMessage(config.MoveThreadTombstoneMessage, map[string]string{
+		"author":       p.getUsernameOrID(wpl.RootPost().UserId),
+		"executor":     p.getUsernameOrID(executorID),
+		"channel":      targetChannel.Name,
+		"team":         targetTeam.Name,
+		"replyCount":   fmt.Sprintf("%d", wpl.NumPosts()-1),
+		"messageCount": fmt.Sprintf("%d", wpl.NumPosts()),
+		"postLink":     makePostLink(*p.API.GetConfig().ServiceSettings.SiteURL, targetTeam.Name, newRootPost.Id),
+	})
+
+	tombstone, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: originalChannelID,
+		Message:   message,
+	})
+	if appErr != nil {
+		// The thread has already been moved at this point so the error is
+		// only logged.
+		p.API.LogError("Unable to post where the thread was moved",
+			"error", appErr.Error(),
+			"channel_id", originalChannelID,
+		)
+		return ""
+	}
+
+	return tombstone.Id
 }
 
 func (p *Plugin) postMoveThreadBotDM(userID, newPostLink, executor string) error {
 	config := p.getConfiguration()
 	message := makeBotDM(config.MoveThreadMessage, newPostLink, executor)
 
-	return p.PostBotDM(userID, message)
+	return p.notifyUser(userID, message)
 }
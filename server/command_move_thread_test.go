@@ -1,7 +1,9 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -99,6 +101,8 @@ func TestMoveThreadCommand(t *testing.T) {
 	api.On("GetReactions", mock.AnythingOfType("string")).Return(reactions, nil)
 	api.On("AddReaction", mock.Anything).Return(nil, nil)
 	api.On("GetConfig").Return(config)
+	kvStore := mockKVStore(api)
+	api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.Anything).Return(nil)
 	api.On("LogInfo",
 		mock.AnythingOfType("string"),
 		mock.AnythingOfType("string"),
@@ -113,14 +117,14 @@ func TestMoveThreadCommand(t *testing.T) {
 	plugin.SetAPI(api)
 
 	t.Run("no args", func(t *testing.T) {
-		resp, isUserError, err := plugin.runMoveThreadCommand([]string{}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runMoveThreadCommand([]string{}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: missing arguments")
 	})
 
 	t.Run("one arg", func(t *testing.T) {
-		resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1"}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1"}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: missing arguments")
@@ -131,7 +135,7 @@ func TestMoveThreadCommand(t *testing.T) {
 			plugin.setConfiguration(&configuration{MoveThreadFromPrivateChannelEnable: false})
 			require.NoError(t, plugin.configuration.IsValid())
 
-			resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: privateChannel.Id})
+			resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: privateChannel.Id}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.False(t, isUserError)
 			assert.Contains(t, resp.Text, "Wrangler is currently configured to not allow moving posts from private channels")
@@ -143,7 +147,7 @@ func TestMoveThreadCommand(t *testing.T) {
 			plugin.setConfiguration(&configuration{MoveThreadFromDirectMessageChannelEnable: false})
 			require.NoError(t, plugin.configuration.IsValid())
 
-			resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: directChannel.Id})
+			resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: directChannel.Id}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.False(t, isUserError)
 			assert.Contains(t, resp.Text, "Wrangler is currently configured to not allow moving posts from direct message channels")
@@ -156,7 +160,7 @@ func TestMoveThreadCommand(t *testing.T) {
 			})
 			require.NoError(t, plugin.configuration.IsValid())
 
-			resp, isUserError, err := plugin.runMoveThreadCommand([]string{directChannel.Id, "id2"}, &model.CommandArgs{ChannelId: directChannel.Id})
+			resp, isUserError, err := plugin.runMoveThreadCommand([]string{directChannel.Id, "id2"}, &model.CommandArgs{ChannelId: directChannel.Id}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.True(t, isUserError)
 			assert.Contains(t, resp.Text, "Error: this command must be run from the channel containing the post")
@@ -169,7 +173,7 @@ func TestMoveThreadCommand(t *testing.T) {
 			})
 			require.NoError(t, plugin.configuration.IsValid())
 
-			resp, isUserError, err := plugin.runMoveThreadCommand([]string{directChannel.Id, "id2"}, &model.CommandArgs{ChannelId: directChannel.Id})
+			resp, isUserError, err := plugin.runMoveThreadCommand([]string{directChannel.Id, "id2"}, &model.CommandArgs{ChannelId: directChannel.Id}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.True(t, isUserError)
 			assert.Contains(t, resp.Text, "Error: this command must be run from the channel containing the post")
@@ -181,7 +185,7 @@ func TestMoveThreadCommand(t *testing.T) {
 			plugin.setConfiguration(&configuration{MoveThreadFromGroupMessageChannelEnable: false})
 			require.NoError(t, plugin.configuration.IsValid())
 
-			resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: groupChannel.Id})
+			resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: groupChannel.Id}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.False(t, isUserError)
 			assert.Contains(t, resp.Text, "Wrangler is currently configured to not allow moving posts from group message channels")
@@ -194,7 +198,7 @@ func TestMoveThreadCommand(t *testing.T) {
 			})
 			require.NoError(t, plugin.configuration.IsValid())
 
-			resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: groupChannel.Id})
+			resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: groupChannel.Id}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.True(t, isUserError)
 			assert.Contains(t, resp.Text, "Error: this command must be run from the channel containing the post")
@@ -207,7 +211,7 @@ func TestMoveThreadCommand(t *testing.T) {
 			})
 			require.NoError(t, plugin.configuration.IsValid())
 
-			resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: groupChannel.Id})
+			resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: groupChannel.Id}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.True(t, isUserError)
 			assert.Contains(t, resp.Text, "Error: this command must be run from the channel containing the post")
@@ -219,7 +223,7 @@ func TestMoveThreadCommand(t *testing.T) {
 			plugin.setConfiguration(&configuration{MoveThreadToAnotherTeamEnable: false})
 			require.NoError(t, plugin.configuration.IsValid())
 
-			resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id})
+			resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.False(t, isUserError)
 			assert.Contains(t, resp.Text, "Wrangler is currently configured to not allow moving messages to different teams")
@@ -230,7 +234,7 @@ func TestMoveThreadCommand(t *testing.T) {
 		plugin.setConfiguration(&configuration{MoveThreadToAnotherTeamEnable: true})
 
 		t.Run("not in thread channel", func(t *testing.T) {
-			resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: model.NewId()})
+			resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: model.NewId()}, &AuditRecord{})
 			require.NoError(t, err)
 			assert.True(t, isUserError)
 			assert.Contains(t, resp.Text, "Error: this command must be run from the channel containing the post")
@@ -241,14 +245,14 @@ func TestMoveThreadCommand(t *testing.T) {
 
 		t.Run("in thread being moved", func(t *testing.T) {
 			t.Run("parentId matches", func(t *testing.T) {
-				resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id, ParentId: rootPostID})
+				resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id, ParentId: rootPostID}, &AuditRecord{})
 				require.NoError(t, err)
 				assert.True(t, isUserError)
 				assert.Contains(t, resp.Text, "Error: this command cannot be run from inside the thread; please run directly in the channel containing the thread")
 			})
 
 			t.Run("rootId matches", func(t *testing.T) {
-				resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id, RootId: rootPostID})
+				resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id, RootId: rootPostID}, &AuditRecord{})
 				require.NoError(t, err)
 				assert.True(t, isUserError)
 				assert.Contains(t, resp.Text, "Error: this command cannot be run from inside the thread; please run directly in the channel containing the thread")
@@ -262,7 +266,7 @@ func TestMoveThreadCommand(t *testing.T) {
 	targetCall.Return(nil, &model.AppError{})
 
 	t.Run("no target channel member", func(t *testing.T) {
-		resp, isUserError, err := plugin.runMoveThreadCommand([]string{originalPostID, targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runMoveThreadCommand([]string{originalPostID, targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: channel with ID")
@@ -272,52 +276,190 @@ func TestMoveThreadCommand(t *testing.T) {
 	api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(mockGenerateChannelMember(), nil)
 
 	t.Run("no permission to create posts in target channel", func(t *testing.T) {
-		resp, isUserError, err := plugin.runMoveThreadCommand([]string{originalPostID, readOnlyChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runMoveThreadCommand([]string{originalPostID, readOnlyChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: you don't have permissions to create posts in channel read-only")
 	})
 
+	t.Run("thread is locked by another operation", func(t *testing.T) {
+		rootPostID := buildWranglerPostList(generatedPosts).RootPost().Id
+		lock, response, err := plugin.acquireThreadLock(operationTypeCopy, &model.CommandArgs{UserId: model.NewId()}, rootPostID)
+		require.NoError(t, err)
+		require.Nil(t, response)
+		defer plugin.releaseThreadLock(lock)
+
+		resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "is currently running a copy operation on this thread")
+	})
+
 	t.Run("move thread successfully", func(t *testing.T) {
 		require.NoError(t, plugin.configuration.IsValid())
 
-		resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.False(t, isUserError)
 		assert.Contains(t, resp.Text, fmt.Sprintf("A thread with 3 messages has been moved: %s", makePostLink(*config.ServiceSettings.SiteURL, targetTeam.Name, "")))
 		assert.Contains(t, resp.Text, quoteBlock("This is message 1"))
+
+		// The moved root is remembered so that replies follow the thread.
+		var redirects []threadRedirect
+		for key, value := range kvStore {
+			if strings.HasPrefix(key, kvThreadRedirectPrefix) {
+				var redirect threadRedirect
+				require.NoError(t, json.Unmarshal(value, &redirect))
+				redirects = append(redirects, redirect)
+			}
+		}
+		require.NotEmpty(t, redirects)
+		assert.Equal(t, targetChannel.Id, redirects[0].ChannelID)
 	})
 
 	t.Run("move thread successfully, but don't show root message", func(t *testing.T) {
 		require.NoError(t, plugin.configuration.IsValid())
 
-		resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2", "--show-root-message-in-summary=false"}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2", "--show-root-message-in-summary=false"}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.False(t, isUserError)
 		assert.Contains(t, resp.Text, fmt.Sprintf("A thread with 3 messages has been moved: %s", makePostLink(*config.ServiceSettings.SiteURL, targetTeam.Name, "")))
 		assert.NotContains(t, resp.Text, "This is message 1")
 	})
 
+	t.Run("move thread dry run", func(t *testing.T) {
+		require.NoError(t, plugin.configuration.IsValid())
+
+		resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2", "--dry-run"}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Dry run: no messages were moved")
+		assert.Contains(t, resp.Text, "3 message(s) would be moved to ~target-channel in team target-team")
+		assert.Contains(t, resp.Text, "Reactions to reapply: 3")
+		assert.Contains(t, resp.Text, "Direct messages would be sent to: @")
+	})
+
+	t.Run("move thread dry run, but silenced", func(t *testing.T) {
+		require.NoError(t, plugin.configuration.IsValid())
+
+		resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2", "--dry-run", "--silent"}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Dry run: no messages were moved")
+		assert.Contains(t, resp.Text, "Direct messages: none")
+	})
+
+	t.Run("move thread dry run, without notifications", func(t *testing.T) {
+		require.NoError(t, plugin.configuration.IsValid())
+
+		resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2", "--dry-run", "--notify=none"}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Direct messages: none")
+	})
+
+	t.Run("invalid notification policy", func(t *testing.T) {
+		_, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2", "--notify=everyone"}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
+		require.Error(t, err)
+		assert.True(t, isUserError)
+	})
+
 	t.Run("move thread successfully, but silenced", func(t *testing.T) {
 		require.NoError(t, plugin.configuration.IsValid())
 
-		resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2", "--silent"}, &model.CommandArgs{ChannelId: originalChannel.Id})
+		resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2", "--silent"}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.False(t, isUserError)
 		assert.Contains(t, resp.Text, fmt.Sprintf("A thread with 3 message(s) has been silently moved: %s", makePostLink(*config.ServiceSettings.SiteURL, targetTeam.Name, "")))
 		assert.NotContains(t, resp.Text, "This is message 1")
 	})
 
+	t.Run("move thread successfully with a post left in the original channel", func(t *testing.T) {
+		plugin.setConfiguration(&configuration{
+			MoveThreadToAnotherTeamEnable: true,
+			MoveThreadTombstoneEnable:     true,
+			MoveThreadTombstoneMessage:    "Thread by @{author} with {replyCount} replies moved to ~{channel} by @{executor}: {postLink}",
+		})
+		defer plugin.setConfiguration(&configuration{MoveThreadToAnotherTeamEnable: true})
+
+		resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "A thread with 3 messages has been moved")
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == originalChannel.Id &&
+				strings.HasPrefix(post.Message, "Thread by @") &&
+				strings.Contains(post.Message, " with 2 replies moved to ~target-channel by @")
+		}))
+	})
+
 	t.Run("thread is above configuration move-maximum", func(t *testing.T) {
 		plugin.setConfiguration(&configuration{MoveThreadMaxCount: "1"})
 		require.NoError(t, plugin.configuration.IsValid())
-		resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: model.NewId()})
+		resp, isUserError, err := plugin.runMoveThreadCommand([]string{"id1", "id2"}, &model.CommandArgs{ChannelId: model.NewId()}, &AuditRecord{})
 		require.NoError(t, err)
 		assert.True(t, isUserError)
 		assert.Contains(t, resp.Text, "Error: the thread is 3 posts long, but this command is configured to only move threads of up to 1 posts")
 	})
 }
 
+func TestCopyWranglerPostlistRollback(t *testing.T) {
+	targetChannel := &model.Channel{
+		Id:   model.NewId(),
+		Name: "target-channel",
+	}
+	firstNewPost := mockGeneratePost()
+
+	setupAPI := func() *plugintest.API {
+		api := &plugintest.API{}
+		api.On("GetReactions", mock.AnythingOfType("string")).Return(nil, nil)
+		api.On("CreatePost", mock.Anything).Return(firstNewPost, nil).Once()
+		api.On("CreatePost", mock.Anything).Return(nil, &model.AppError{Message: "create failed"})
+		api.On("GetPost", firstNewPost.Id).Return(firstNewPost, nil)
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		return api
+	}
+
+	t.Run("rollback complete", func(t *testing.T) {
+		api := setupAPI()
+		api.On("DeletePost", firstNewPost.Id).Return(nil)
+
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		wpl := buildWranglerPostList(mockGeneratePostList(3, model.NewId(), false))
+		_, _, err := plugin.copyWranglerPostlist(wpl, targetChannel, nil)
+		require.Error(t, err)
+		api.AssertCalled(t, "DeletePost", firstNewPost.Id)
+		api.AssertNumberOfCalls(t, "DeletePost", 1)
+
+		resp, isUserError, err := plugin.getRollbackResponse(err)
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "rolled back cleanly")
+	})
+
+	t.Run("rollback failed", func(t *testing.T) {
+		api := setupAPI()
+		api.On("DeletePost", firstNewPost.Id).Return(&model.AppError{Message: "delete failed"})
+		api.On("LogError", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		var plugin Plugin
+		plugin.SetAPI(api)
+
+		wpl := buildWranglerPostList(mockGeneratePostList(3, model.NewId(), false))
+		_, _, err := plugin.copyWranglerPostlist(wpl, targetChannel, nil)
+		require.Error(t, err)
+
+		resp, isUserError, err := plugin.getRollbackResponse(err)
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "could not be fully rolled back")
+	})
+}
+
 func TestSortedPostsFromPostList(t *testing.T) {
 	tests := []struct {
 		count int
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const notificationsUsage = `/wrangler notifications [on|off|digest]
+  Choose how you receive the direct messages Wrangler sends when your threads are wrangled
+    - '/wrangler notifications' shows your current setting
+    - 'on' sends each message right away
+    - 'off' stops the messages
+    - 'digest' batches the messages into one direct message per day
+    - Any user can run this command, even if they are not permitted to use Wrangler`
+
+func (p *Plugin) runNotificationsCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	if len(args) < 1 {
+		mode, err := p.getNotificationMode(extra.UserId)
+		if err != nil {
+			return nil, false, err
+		}
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Your Wrangler notifications are %s", describeNotificationMode(mode))), false, nil
+	}
+
+	mode := args[0]
+	switch mode {
+	case notificationsOn, notificationsOff, notificationsDigest:
+	default:
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, codeBlock(notificationsUsage)), true, nil
+	}
+
+	err := p.setNotificationMode(extra.UserId, mode)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Your Wrangler notifications are now %s", describeNotificationMode(mode))), false, nil
+}
+
+func describeNotificationMode(mode string) string {
+	switch mode {
+	case notificationsOff:
+		return "off"
+	case notificationsDigest:
+		return "sent as a daily digest"
+	}
+
+	return "on"
+}
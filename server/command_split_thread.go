@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	splitThreadUsage = `/wrangler split thread [REPLY_ID or REPLY_LINK] [flags]
+  Split a thread into a new thread starting at the given reply
+    - The reply and every later reply become a new thread and are removed from the original thread
+    - The new thread is created in the same channel unless another channel is provided
+	Flags:
+%s`
+
+	flagSplitThreadTo = "to"
+)
+
+type splitThreadOptions struct {
+	targetChannel string
+}
+
+func getSplitThreadFlagSet() *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet("split thread", pflag.ContinueOnError)
+	flagSet.String(flagSplitThreadTo, "", "The channel where the new thread will be created, as an ID, ~channel-name, team-name:channel-name, display name or link (default: the current channel)")
+
+	return flagSet
+}
+
+func parseSplitThreadFlagArgs(args []string) (splitThreadOptions, error) {
+	var options splitThreadOptions
+
+	flagSet := getSplitThreadFlagSet()
+	err := flagSet.Parse(args)
+	if err != nil {
+		return options, errors.Wrap(err, "unable to parse split thread flag args")
+	}
+
+	options.targetChannel, _ = flagSet.GetString(flagSplitThreadTo)
+
+	return options, nil
+}
+
+func getSplitThreadUsage() string {
+	return fmt.Sprintf(splitThreadUsage, getSplitThreadFlagSet().FlagUsages())
+}
+
+func getSplitThreadMessage() string {
+	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", getSplitThreadUsage()))
+}
+
+func (p *Plugin) runSplitThreadCommand(args []string, extra *model.CommandArgs, audit *AuditRecord) (*model.CommandResponse, bool, error) {
+	if len(args) < 1 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getSplitThreadMessage()), true, nil
+	}
+	options, err := parseSplitThreadFlagArgs(args)
+	if err != nil {
+		return nil, true, err
+	}
+	postID := cleanInputID(args[0], extra.SiteURL)
+
+	splitPost, appErr := p.API.GetPost(postID)
+	if appErr != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get message with ID %s; ensure this is correct", postID)), true, nil
+	}
+	if len(splitPost.RootId) == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the message is the root of its thread; provide a reply to split the thread at"), true, nil
+	}
+
+	postListResponse, appErr := p.API.GetPostThread(splitPost.RootId)
+	if appErr != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to get post with ID %s; ensure this is correct", splitPost.RootId)), true, nil
+	}
+	wpl := buildWranglerPostList(postListResponse)
+	splitWpl := wpl.SplitAt(splitPost.Id)
+	if splitWpl.NumPosts() == 0 {
+		return nil, false, errors.Errorf("unable to find post %s in thread %s", splitPost.Id, splitPost.RootId)
+	}
+
+	if extra.RootId == wpl.RootPost().Id || extra.ParentId == wpl.RootPost().Id {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: this command cannot be run from inside the thread; please run directly in the channel containing the thread"), true, nil
+	}
+
+	originalChannel, appErr := p.API.GetChannel(wpl.RootPost().ChannelId)
+	if appErr != nil {
+		return nil, false, errors.Errorf("unable to get channel with ID %s", wpl.RootPost().ChannelId)
+	}
+	targetChannel := originalChannel
+	if len(options.targetChannel) != 0 && options.targetChannel != originalChannel.Id {
+		var userMessage string
+		targetChannel, userMessage, err = p.resolveChannel(options.targetChannel, extra)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(userMessage) != 0 {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, userMessage), true, nil
+		}
+	}
+
+	response, userErr, err := p.validateMoveOrCopy(splitWpl, originalChannel, targetChannel, extra)
+	if response != nil || err != nil {
+		return response, userErr, err
+	}
+
+	audit.setWranglerDetails(splitPost.Id, originalChannel.Id, "", targetChannel.Id, splitWpl.NumPosts())
+
+	lock, response, err := p.acquireThreadLock(operationTypeSplit, extra, wpl.RootPost().Id)
+	if err != nil {
+		return nil, false, err
+	}
+	if response != nil {
+		return response, true, nil
+	}
+	defer p.releaseThreadLock(lock)
+
+	// The thread is read and validated again now that it is locked.
+	wpl, response, userErr, err = p.getLockedThread(wpl.RootPost().Id)
+	if response != nil || err != nil {
+		return response, userErr, err
+	}
+	splitWpl = wpl.SplitAt(splitPost.Id)
+	if splitWpl.NumPosts() == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getThreadChangedMessage(splitPost.Id)), true, nil
+	}
+	response, userErr, err = p.validateMoveOrCopy(splitWpl, originalChannel, targetChannel, extra)
+	if response != nil || err != nil {
+		return response, userErr, err
+	}
+	audit.MessageCount = splitWpl.NumPosts()
+
+	p.API.LogInfo("Wrangler is splitting a thread",
+		"user_id", extra.UserId,
+		"original_root_post_id", wpl.RootPost().Id,
+		"split_post_id", splitPost.Id,
+	)
+
+	newRootPost, postIDs, err := p.copyWranglerPostlist(splitWpl, targetChannel, nil)
+	if err != nil {
+		return p.getRollbackResponse(err)
+	}
+	audit.TargetPostID = newRootPost.Id
+
+	// The split posts are replies so they are deleted individually, newest
+	// first, to leave the rest of the original thread intact.
+	for i := len(splitWpl.Posts) - 1; i >= 0; i-- {
+		appErr = p.API.DeletePost(splitWpl.Posts[i].Id)
+		if appErr == nil {
+			continue
+		}
+		if i == len(splitWpl.Posts)-1 {
+			// Nothing has been deleted yet so the copies can be removed.
+			return p.getRollbackResponse(p.rollback(errors.Wrap(appErr, "unable to delete post"), postIDs, nil))
+		}
+
+		// Some of the original messages are already gone, so removing the
+		// copies would lose them. The copies are kept and the messages that
+		// remain in the original thread are reported instead.
+		var remaining []string
+		for _, post := range splitWpl.Posts[:i+1] {
+			remaining = append(remaining, post.Id)
+		}
+		p.API.LogError("Wrangler thread split only partially completed",
+			"error", appErr.Error(),
+			"user_id", extra.UserId,
+			"new_post_id", newRootPost.Id,
+			"remaining_post_ids", strings.Join(remaining, ","),
+		)
+
+		newPostLink, err := p.getPostLinkForChannel(targetChannel, extra.TeamId, newRootPost.Id)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: the thread was only partially split. All %d message(s) were copied to a new thread: %s\nThe following message(s) could not be removed from the original thread and should be deleted manually: %s", splitWpl.NumPosts(), newPostLink, strings.Join(remaining, ", "))), false, nil
+	}
+
+	p.API.LogInfo("Wrangler thread split complete",
+		"user_id", extra.UserId,
+		"new_post_id", newRootPost.Id,
+		"new_channel_id", targetChannel.Id,
+	)
+
+	operation := &WranglerOperation{
+		Type:              operationTypeSplit,
+		UserID:            extra.UserId,
+		OriginalChannelID: originalChannel.Id,
+		TargetChannelID:   targetChannel.Id,
+		OriginalRootID:    wpl.RootPost().Id,
+		NewRootID:         newRootPost.Id,
+		Posts:             postIDs,
+	}
+	p.recordWranglerOperationOrLog(operation)
+	audit.OperationID = operation.ID
+
+	newPostLink, err := p.getPostLinkForChannel(targetChannel, extra.TeamId, newRootPost.Id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// The split has completed at this point so failing to link the threads
+	// to each other is only logged.
+	p.postSplitThreadLinks(wpl, splitWpl, newRootPost, originalChannel, targetChannel, newPostLink, extra.TeamId)
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("%d message(s) have been split into a new thread: %s\n%s", splitWpl.NumPosts(), newPostLink, operationSuffix(operation))), false, nil
+}
+
+// postSplitThreadLinks posts a link to the other thread in both the original
+// and the new thread of a split.
+func (p *Plugin) postSplitThreadLinks(wpl, splitWpl *WranglerPostList, newRootPost *model.Post, originalChannel, targetChannel *model.Channel, newPostLink, fallbackTeamID string) {
+	originalPostLink, err := p.getPostLinkForChannel(originalChannel, fallbackTeamID, wpl.RootPost().Id)
+	if err != nil {
+		p.API.LogError("Unable to get link to the original thread of a split", "error", err.Error())
+	} else {
+		_, appErr := p.API.CreatePost(&model.Post{
+			UserId:    p.BotUserID,
+			RootId:    newRootPost.Id,
+			ParentId:  newRootPost.Id,
+			ChannelId: targetChannel.Id,
+			Message:   fmt.Sprintf("This thread was split from another thread: %s", originalPostLink),
+		})
+		if appErr != nil {
+			p.API.LogError("Unable to post link to the original thread of a split", "error", appErr.Error())
+		}
+	}
+
+	_, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.BotUserID,
+		RootId:    wpl.RootPost().Id,
+		ParentId:  wpl.RootPost().Id,
+		ChannelId: originalChannel.Id,
+		Message:   fmt.Sprintf("%d message(s) from this thread were split into a new thread: %s", splitWpl.NumPosts(), newPostLink),
+	})
+	if appErr != nil {
+		p.API.LogError("Unable to post link to the new thread of a split", "error", appErr.Error())
+	}
+}
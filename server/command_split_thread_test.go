@@ -0,0 +1,209 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitThreadCommand(t *testing.T) {
+	team := &model.Team{
+		Id:   model.NewId(),
+		Name: "team-1",
+	}
+	originalChannel := &model.Channel{
+		Id:     model.NewId(),
+		TeamId: team.Id,
+		Name:   "original-channel",
+		Type:   model.CHANNEL_OPEN,
+	}
+	targetChannel := &model.Channel{
+		Id:     model.NewId(),
+		TeamId: team.Id,
+		Name:   "target-channel",
+		Type:   model.CHANNEL_OPEN,
+	}
+
+	config := &model.Config{
+		ServiceSettings: model.ServiceSettings{
+			SiteURL: NewString("test.sampledomain.com"),
+		},
+	}
+
+	generatedPosts := mockGeneratePostList(4, originalChannel.Id, false)
+	wpl := buildWranglerPostList(generatedPosts)
+	rootPost := wpl.Posts[0]
+	for _, post := range wpl.Posts[1:] {
+		post.RootId = rootPost.Id
+		post.ParentId = rootPost.Id
+	}
+	splitPost := wpl.Posts[2]
+
+	api := &plugintest.API{}
+	mockKVStore(api)
+	api.On("GetPost", rootPost.Id).Return(rootPost, nil)
+	api.On("GetPost", splitPost.Id).Return(splitPost, nil)
+	api.On("GetPostThread", rootPost.Id).Return(generatedPosts, nil)
+	api.On("GetChannel", originalChannel.Id).Return(originalChannel, nil)
+	api.On("GetChannel", targetChannel.Id).Return(targetChannel, nil)
+	api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(mockGenerateChannelMember(), nil)
+	api.On("HasPermissionToChannel", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.Anything).Return(true)
+	api.On("GetTeam", team.Id).Return(team, nil)
+	api.On("GetConfig").Return(config)
+	api.On("GetReactions", mock.AnythingOfType("string")).Return(nil, nil)
+	api.On("CreatePost", mock.Anything).Return(mockGeneratePost(), nil)
+	api.On("DeletePost", mock.AnythingOfType("string")).Return(nil)
+	api.On("LogInfo",
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+	).Return(nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+	plugin.setConfiguration(&configuration{})
+
+	t.Run("no args", func(t *testing.T) {
+		resp, isUserError, err := plugin.runSplitThreadCommand([]string{}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: missing arguments")
+	})
+
+	t.Run("root post", func(t *testing.T) {
+		resp, isUserError, err := plugin.runSplitThreadCommand([]string{rootPost.Id}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: the message is the root of its thread")
+	})
+
+	t.Run("run from inside the thread", func(t *testing.T) {
+		resp, isUserError, err := plugin.runSplitThreadCommand([]string{splitPost.Id}, &model.CommandArgs{ChannelId: originalChannel.Id, RootId: rootPost.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: this command cannot be run from inside the thread")
+	})
+
+	t.Run("split thread successfully", func(t *testing.T) {
+		audit := &AuditRecord{}
+		resp, isUserError, err := plugin.runSplitThreadCommand([]string{splitPost.Id}, &model.CommandArgs{ChannelId: originalChannel.Id}, audit)
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "2 message(s) have been split into a new thread")
+		assert.Contains(t, resp.Text, "/wrangler undo")
+		assert.NotEmpty(t, audit.OperationID)
+		assert.Contains(t, resp.Text, audit.OperationID)
+	})
+
+	t.Run("split thread to another channel successfully", func(t *testing.T) {
+		resp, isUserError, err := plugin.runSplitThreadCommand([]string{splitPost.Id, "--to", targetChannel.Id}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "2 message(s) have been split into a new thread")
+	})
+}
+
+func TestSplitThreadCommandRollback(t *testing.T) {
+	team := &model.Team{Id: model.NewId(), Name: "team-1"}
+	originalChannel := &model.Channel{Id: model.NewId(), TeamId: team.Id, Type: model.CHANNEL_OPEN}
+	config := &model.Config{
+		ServiceSettings: model.ServiceSettings{
+			SiteURL: NewString("test.sampledomain.com"),
+		},
+	}
+
+	generatedPosts := mockGeneratePostList(3, originalChannel.Id, false)
+	wpl := buildWranglerPostList(generatedPosts)
+	rootPost := wpl.Posts[0]
+	for _, post := range wpl.Posts[1:] {
+		post.RootId = rootPost.Id
+		post.ParentId = rootPost.Id
+	}
+	splitPost := wpl.Posts[1]
+	newPost := mockGeneratePost()
+
+	setupAPI := func() *plugintest.API {
+		api := &plugintest.API{}
+		mockKVStore(api)
+		api.On("GetPost", rootPost.Id).Return(rootPost, nil)
+		api.On("GetPost", splitPost.Id).Return(splitPost, nil)
+		api.On("GetPost", newPost.Id).Return(newPost, nil)
+		api.On("GetPostThread", rootPost.Id).Return(generatedPosts, nil)
+		api.On("GetChannel", originalChannel.Id).Return(originalChannel, nil)
+		api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(mockGenerateChannelMember(), nil)
+		api.On("HasPermissionToChannel", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.Anything).Return(true)
+		api.On("GetTeam", team.Id).Return(team, nil)
+		api.On("GetConfig").Return(config)
+		api.On("GetReactions", mock.AnythingOfType("string")).Return(nil, nil)
+		api.On("CreatePost", mock.Anything).Return(newPost, nil)
+		api.On("DeletePost", newPost.Id).Return(nil)
+		api.On("LogInfo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		api.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		return api
+	}
+
+	t.Run("nothing deleted", func(t *testing.T) {
+		api := setupAPI()
+		api.On("DeletePost", wpl.Posts[2].Id).Return(&model.AppError{Message: "failed"})
+
+		var plugin Plugin
+		plugin.SetAPI(api)
+		plugin.setConfiguration(&configuration{})
+
+		resp, isUserError, err := plugin.runSplitThreadCommand([]string{splitPost.Id}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "rolled back cleanly")
+		api.AssertCalled(t, "DeletePost", newPost.Id)
+		api.AssertNotCalled(t, "DeletePost", splitPost.Id)
+	})
+
+	t.Run("some originals deleted", func(t *testing.T) {
+		api := setupAPI()
+		api.On("DeletePost", wpl.Posts[2].Id).Return(nil)
+		api.On("DeletePost", splitPost.Id).Return(&model.AppError{Message: "failed"})
+
+		var plugin Plugin
+		plugin.SetAPI(api)
+		plugin.setConfiguration(&configuration{})
+
+		resp, isUserError, err := plugin.runSplitThreadCommand([]string{splitPost.Id}, &model.CommandArgs{ChannelId: originalChannel.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "only partially split")
+		assert.Contains(t, resp.Text, splitPost.Id)
+		assert.NotContains(t, resp.Text, wpl.Posts[2].Id)
+		api.AssertNotCalled(t, "DeletePost", newPost.Id)
+	})
+}
+
+func TestWranglerPostListSplitAt(t *testing.T) {
+	wpl := buildWranglerPostList(mockGeneratePostList(5, model.NewId(), false))
+
+	t.Run("split at root", func(t *testing.T) {
+		split := wpl.SplitAt(wpl.Posts[0].Id)
+		assert.Equal(t, wpl.NumPosts(), split.NumPosts())
+	})
+
+	t.Run("split at reply", func(t *testing.T) {
+		split := wpl.SplitAt(wpl.Posts[3].Id)
+		require.Equal(t, 2, split.NumPosts())
+		assert.Equal(t, wpl.Posts[3].Id, split.RootPost().Id)
+		assert.Equal(t, wpl.Posts[4].Id, split.Posts[1].Id)
+	})
+
+	t.Run("post not found", func(t *testing.T) {
+		split := wpl.SplitAt(model.NewId())
+		assert.Equal(t, 0, split.NumPosts())
+	})
+}
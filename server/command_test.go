@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/mattermost/mattermost-server/v5/model"
@@ -29,6 +30,7 @@ func TestCommand(t *testing.T) {
 	api.On("GetUser", user.Id).Return(user, nil)
 	api.On("GetUser", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(nil, &model.AppError{DetailedError: "invalid user"})
 	api.On("LogWarn", mock.AnythingOfType("string")).Return(nil)
+	mockKVStore(api)
 
 	var plugin Plugin
 	plugin.SetAPI(api)
@@ -55,87 +57,119 @@ func TestCommand(t *testing.T) {
 			args := &model.CommandArgs{UserId: user.Id, Command: "one two"}
 			resp, appErr := plugin.ExecuteCommand(context, args)
 			require.Nil(t, appErr)
-			require.Equal(t, plugin.getHelp(), resp.Text)
+			assert.Contains(t, resp.Text, "Error: unknown command `/wrangler two`.")
+			assert.Contains(t, resp.Text, "Available commands: `/wrangler move`, `/wrangler copy`")
 		})
 
-		t.Run("move command", func(t *testing.T) {
-			t.Run("missing extra args", func(t *testing.T) {
-				args := &model.CommandArgs{UserId: user.Id, Command: "wrangler move"}
-				resp, appErr := plugin.ExecuteCommand(context, args)
-				require.Nil(t, appErr)
-				require.Equal(t, plugin.getHelp(), resp.Text)
-			})
+		t.Run("misspelled command", func(t *testing.T) {
+			args := &model.CommandArgs{UserId: user.Id, Command: "wrangler mvoe thread"}
+			resp, appErr := plugin.ExecuteCommand(context, args)
+			require.Nil(t, appErr)
+			assert.Contains(t, resp.Text, "Error: unknown command `/wrangler mvoe`. Did you mean `/wrangler move`?")
+		})
 
-			t.Run("invalid extra args", func(t *testing.T) {
-				args := &model.CommandArgs{UserId: user.Id, Command: "wrangler move invalid"}
-				resp, appErr := plugin.ExecuteCommand(context, args)
-				require.Nil(t, appErr)
-				require.Equal(t, plugin.getHelp(), resp.Text)
-			})
+		t.Run("merge command when merging is disabled", func(t *testing.T) {
+			args := &model.CommandArgs{UserId: user.Id, Command: "wrangler merge thread id1 id2"}
+			resp, appErr := plugin.ExecuteCommand(context, args)
+			require.Nil(t, appErr)
+			assert.Contains(t, resp.Text, "Error: unknown command `/wrangler merge`.")
+			assert.NotContains(t, resp.Text, "`/wrangler merge`?")
+
+			args = &model.CommandArgs{UserId: user.Id, Command: "wrangler mrege thread"}
+			resp, appErr = plugin.ExecuteCommand(context, args)
+			require.Nil(t, appErr)
+			assert.Contains(t, resp.Text, "Error: unknown command `/wrangler mrege`.")
+			assert.NotContains(t, resp.Text, "merge")
 		})
 
-		t.Run("copy command", func(t *testing.T) {
-			t.Run("missing extra args", func(t *testing.T) {
-				args := &model.CommandArgs{UserId: user.Id, Command: "wrangler copy"}
-				resp, appErr := plugin.ExecuteCommand(context, args)
-				require.Nil(t, appErr)
-				require.Equal(t, plugin.getHelp(), resp.Text)
+		t.Run("merge command when merging is enabled", func(t *testing.T) {
+			plugin.setConfiguration(&configuration{
+				PermittedWranglerUsers: permittedUserAllUsers,
+				MergeThreadEnable:      true,
 			})
-
-			t.Run("invalid extra args", func(t *testing.T) {
-				args := &model.CommandArgs{UserId: user.Id, Command: "wrangler copy invalid"}
-				resp, appErr := plugin.ExecuteCommand(context, args)
-				require.Nil(t, appErr)
-				require.Equal(t, plugin.getHelp(), resp.Text)
+			defer plugin.setConfiguration(&configuration{
+				PermittedWranglerUsers: permittedUserAllUsers,
 			})
+
+			args := &model.CommandArgs{UserId: user.Id, Command: "wrangler mrege thread"}
+			resp, appErr := plugin.ExecuteCommand(context, args)
+			require.Nil(t, appErr)
+			assert.Contains(t, resp.Text, "Did you mean `/wrangler merge`?")
 		})
 
-		t.Run("attach command", func(t *testing.T) {
-			t.Run("missing extra args", func(t *testing.T) {
-				args := &model.CommandArgs{UserId: user.Id, Command: "wrangler attach"}
-				resp, appErr := plugin.ExecuteCommand(context, args)
-				require.Nil(t, appErr)
-				require.Equal(t, plugin.getHelp(), resp.Text)
-			})
+		for _, command := range []string{"move", "copy", "attach", "list"} {
+			t.Run(command+" command", func(t *testing.T) {
+				t.Run("missing extra args", func(t *testing.T) {
+					args := &model.CommandArgs{UserId: user.Id, Command: "wrangler " + command}
+					resp, appErr := plugin.ExecuteCommand(context, args)
+					require.Nil(t, appErr)
+					assert.Contains(t, resp.Text, "Error: `/wrangler "+command+"` requires a subcommand.")
+					assert.Contains(t, resp.Text, "Run `/wrangler help "+command+"` for usage instructions.")
+				})
 
-			t.Run("invalid extra args", func(t *testing.T) {
-				args := &model.CommandArgs{UserId: user.Id, Command: "wrangler attach invalid"}
-				resp, appErr := plugin.ExecuteCommand(context, args)
-				require.Nil(t, appErr)
-				require.Equal(t, plugin.getHelp(), resp.Text)
+				t.Run("invalid extra args", func(t *testing.T) {
+					args := &model.CommandArgs{UserId: user.Id, Command: "wrangler " + command + " invalid"}
+					resp, appErr := plugin.ExecuteCommand(context, args)
+					require.Nil(t, appErr)
+					assert.Contains(t, resp.Text, "Error: unknown command `/wrangler "+command+" invalid`.")
+				})
 			})
+		}
+
+		t.Run("misspelled subcommand", func(t *testing.T) {
+			args := &model.CommandArgs{UserId: user.Id, Command: "wrangler list channel"}
+			resp, appErr := plugin.ExecuteCommand(context, args)
+			require.Nil(t, appErr)
+			assert.Contains(t, resp.Text, "Did you mean `/wrangler list channels`?")
 		})
+	})
 
-		t.Run("merge command", func(t *testing.T) {
-			t.Run("missing extra args", func(t *testing.T) {
-				args := &model.CommandArgs{UserId: user.Id, Command: "wrangler merge"}
-				resp, appErr := plugin.ExecuteCommand(context, args)
-				require.Nil(t, appErr)
-				require.Equal(t, plugin.getHelp(), resp.Text)
-			})
+	t.Run("help command", func(t *testing.T) {
+		t.Run("all commands", func(t *testing.T) {
+			args := &model.CommandArgs{UserId: user.Id, Command: "wrangler help"}
+			resp, appErr := plugin.ExecuteCommand(context, args)
+			require.Nil(t, appErr)
+			require.Equal(t, plugin.getHelp(), resp.Text)
+		})
 
-			t.Run("invalid extra args", func(t *testing.T) {
-				args := &model.CommandArgs{UserId: user.Id, Command: "wrangler merge invalid"}
-				resp, appErr := plugin.ExecuteCommand(context, args)
-				require.Nil(t, appErr)
-				require.Equal(t, plugin.getHelp(), resp.Text)
-			})
+		t.Run("command group", func(t *testing.T) {
+			args := &model.CommandArgs{UserId: user.Id, Command: "wrangler help list"}
+			resp, appErr := plugin.ExecuteCommand(context, args)
+			require.Nil(t, appErr)
+			assert.Contains(t, resp.Text, "/wrangler list channels")
+			assert.Contains(t, resp.Text, "/wrangler list messages")
+			assert.NotContains(t, resp.Text, "/wrangler move thread")
 		})
 
-		t.Run("list command", func(t *testing.T) {
-			t.Run("missing extra args", func(t *testing.T) {
-				args := &model.CommandArgs{UserId: user.Id, Command: "wrangler list"}
-				resp, appErr := plugin.ExecuteCommand(context, args)
-				require.Nil(t, appErr)
-				require.Equal(t, plugin.getHelp(), resp.Text)
-			})
+		t.Run("single command", func(t *testing.T) {
+			args := &model.CommandArgs{UserId: user.Id, Command: "wrangler help move thread"}
+			resp, appErr := plugin.ExecuteCommand(context, args)
+			require.Nil(t, appErr)
+			assert.Contains(t, resp.Text, getMoveThreadUsage())
+			assert.Contains(t, resp.Text, "Examples:")
+			assert.NotContains(t, resp.Text, "/wrangler copy thread")
+		})
 
-			t.Run("invalid extra args", func(t *testing.T) {
-				args := &model.CommandArgs{UserId: user.Id, Command: "wrangler list invalid"}
-				resp, appErr := plugin.ExecuteCommand(context, args)
-				require.Nil(t, appErr)
-				require.Equal(t, plugin.getHelp(), resp.Text)
-			})
+		t.Run("single command in a group", func(t *testing.T) {
+			args := &model.CommandArgs{UserId: user.Id, Command: "wrangler help move"}
+			resp, appErr := plugin.ExecuteCommand(context, args)
+			require.Nil(t, appErr)
+			assert.Contains(t, resp.Text, getMoveThreadUsage())
+			assert.Contains(t, resp.Text, "Examples:")
+		})
+
+		t.Run("unknown command", func(t *testing.T) {
+			args := &model.CommandArgs{UserId: user.Id, Command: "wrangler help list chanels"}
+			resp, appErr := plugin.ExecuteCommand(context, args)
+			require.Nil(t, appErr)
+			assert.Contains(t, resp.Text, "Error: unknown command `/wrangler list chanels`. Did you mean `/wrangler list channels`?")
+		})
+
+		t.Run("disabled merge", func(t *testing.T) {
+			args := &model.CommandArgs{UserId: user.Id, Command: "wrangler help merge"}
+			resp, appErr := plugin.ExecuteCommand(context, args)
+			require.Nil(t, appErr)
+			assert.Contains(t, resp.Text, "Error: unknown command `/wrangler merge`.")
 		})
 	})
 
@@ -147,6 +181,30 @@ func TestCommand(t *testing.T) {
 		require.NoError(t, err)
 		assert.False(t, userError)
 		assert.Equal(t, infoResp, resp)
+
+		t.Run("json", func(t *testing.T) {
+			resp, userError, err := plugin.runInfoCommand([]string{"--format", "json"}, nil)
+			require.NoError(t, err)
+			assert.False(t, userError)
+
+			var info pluginInfo
+			require.NoError(t, json.Unmarshal([]byte(resp.Text), &info))
+			assert.Equal(t, manifest.Version, info.Version)
+		})
+
+		t.Run("extra whitespace", func(t *testing.T) {
+			args := &model.CommandArgs{UserId: user.Id, Command: "wrangler  info "}
+			resp, appErr := plugin.ExecuteCommand(context, args)
+			require.Nil(t, appErr)
+			assert.Equal(t, infoResp, resp)
+		})
+	})
+
+	t.Run("unterminated quote", func(t *testing.T) {
+		args := &model.CommandArgs{UserId: user.Id, Command: `wrangler list channels --channel-filter "dev`}
+		resp, appErr := plugin.ExecuteCommand(context, args)
+		require.Nil(t, appErr)
+		assert.Contains(t, resp.Text, "missing closing \" quote")
 	})
 
 	t.Run("permissions", func(t *testing.T) {
@@ -209,6 +267,19 @@ func TestCommand(t *testing.T) {
 			assert.Equal(t, "Permission denied. Please talk to your system administrator to get access.", resp.Text)
 		})
 
+		t.Run("notifications without permission", func(t *testing.T) {
+			plugin.setConfiguration(&configuration{
+				PermittedWranglerUsers: permittedUserSystemAdmins,
+			})
+			args := &model.CommandArgs{
+				UserId:  user.Id,
+				Command: "wrangler notifications off",
+			}
+			resp, appErr := plugin.ExecuteCommand(context, args)
+			require.Nil(t, appErr)
+			assert.Equal(t, "Your Wrangler notifications are now off", resp.Text)
+		})
+
 		t.Run("allowed email domain", func(t *testing.T) {
 			t.Run("enabled, user not in domain", func(t *testing.T) {
 				plugin.setConfiguration(&configuration{
@@ -0,0 +1,543 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+// commandHandler runs a Wrangler command with the arguments that follow its
+// name. Commands that wrangle messages add the details of the messages to the
+// audit record of the run. The bool is true when a failure is the fault of
+// the user.
+type commandHandler func(*Plugin, []string, *model.CommandArgs, *AuditRecord) (*model.CommandResponse, bool, error)
+
+// withoutAuditDetails adapts the handler of a command that doesn't wrangle
+// messages, and so has nothing to add to its audit record.
+func withoutAuditDetails(handler func(*Plugin, []string, *model.CommandArgs) (*model.CommandResponse, bool, error)) commandHandler {
+	return func(p *Plugin, args []string, extra *model.CommandArgs, _ *AuditRecord) (*model.CommandResponse, bool, error) {
+		return handler(p, args, extra)
+	}
+}
+
+// commandArgument is a positional argument of a Wrangler command shown in
+// command autocomplete.
+type commandArgument struct {
+	helpText string
+	hint     string
+	// fetchRoute is the plugin route suggesting values for the argument.
+	fetchRoute string
+}
+
+// wranglerCommand is a node of the /wrangler command tree. The tree is the
+// single description of the commands used for routing, help text and command
+// autocomplete.
+//
+// Routing descends into subcommands until it reaches a command with a
+// handler, which receives all remaining arguments. The subcommands of a
+// command with a handler only describe the arguments it handles itself.
+type wranglerCommand struct {
+	name        string
+	hint        string
+	description string
+	usage       func() string
+	examples    []string
+	arguments   []commandArgument
+	handler     commandHandler
+	subcommands []*wranglerCommand
+	// mergeOnly commands are only shown when merging threads is enabled.
+	mergeOnly bool
+	// allUsers commands can be run by users who are not permitted to use
+	// Wrangler, such as the authors of wrangled threads.
+	allUsers bool
+	// wrangles commands change messages. Their runs are recorded in the
+	// wrangle category of the audit log.
+	wrangles bool
+}
+
+func staticUsage(usage string) func() string {
+	return func() string { return usage }
+}
+
+func getCommandTree() *wranglerCommand {
+	messageArgument := func(helpText string) commandArgument {
+		return commandArgument{helpText: helpText, fetchRoute: routeAPIAutocompleteMessages}
+	}
+	channelArgument := func(helpText string) commandArgument {
+		return commandArgument{helpText: helpText, fetchRoute: routeAPIAutocompleteChannels}
+	}
+
+	return &wranglerCommand{
+		name:        "wrangler",
+		hint:        "[command]",
+		description: "Manage Mattermost messages!",
+		subcommands: []*wranglerCommand{
+			{
+				name:        "move",
+				hint:        "[subcommand]",
+				description: "Move messages",
+				subcommands: []*wranglerCommand{
+					{
+						name:        "thread",
+						hint:        "[MESSAGE_ID or MESSAGE_LINK] [CHANNEL]",
+						description: "Move a message and the thread it belongs to",
+						usage:       getMoveThreadUsage,
+						examples: []string{
+							"/wrangler move thread 8w89igrsffyt3ghmwsmsgyeoqe ~incidents",
+							"/wrangler move thread https://example.com/team/pl/8w89igrsffyt3ghmwsmsgyeoqe team-name:town-square --silent",
+							"/wrangler move thread 8w89igrsffyt3ghmwsmsgyeoqe ~incidents --notify=all-participants",
+						},
+						arguments: []commandArgument{
+							messageArgument("The ID of the message or a direct link to the message to be moved"),
+							channelArgument("The channel where the message will be moved to"),
+						},
+						handler:  (*Plugin).runMoveThreadCommand,
+						wrangles: true,
+					},
+				},
+			},
+			{
+				name:        "copy",
+				hint:        "[subcommand]",
+				description: "Copy messages",
+				subcommands: []*wranglerCommand{
+					{
+						name:        "thread",
+						hint:        "[MESSAGE_ID or MESSAGE_LINK] [CHANNEL]",
+						description: "Copy a message and the thread it belongs to",
+						usage:       getCopyThreadUsage,
+						examples: []string{
+							"/wrangler copy thread 8w89igrsffyt3ghmwsmsgyeoqe ~archive",
+							"/wrangler copy thread 8w89igrsffyt3ghmwsmsgyeoqe \"Support Escalations\" --dry-run",
+						},
+						arguments: []commandArgument{
+							messageArgument("The ID of the message or a direct link to the message to be copied"),
+							channelArgument("The channel where the message will be copied to"),
+						},
+						handler:  (*Plugin).runCopyThreadCommand,
+						wrangles: true,
+					},
+				},
+			},
+			{
+				name:        "split",
+				hint:        "[subcommand]",
+				description: "Split threads",
+				subcommands: []*wranglerCommand{
+					{
+						name:        "thread",
+						hint:        "[REPLY_ID or REPLY_LINK] [optional flags]",
+						description: "Split a thread into a new thread starting at the given reply",
+						usage:       getSplitThreadUsage,
+						examples: []string{
+							"/wrangler split thread 8w89igrsffyt3ghmwsmsgyeoqe",
+							"/wrangler split thread 8w89igrsffyt3ghmwsmsgyeoqe --to ~off-topic",
+						},
+						arguments: []commandArgument{
+							messageArgument("The ID of the reply or a direct link to the reply where the thread will be split"),
+						},
+						handler:  (*Plugin).runSplitThreadCommand,
+						wrangles: true,
+					},
+				},
+			},
+			{
+				name:        "attach",
+				hint:        "[subcommand]",
+				description: "Attach messages",
+				subcommands: []*wranglerCommand{
+					{
+						name:        "message",
+						hint:        "[MESSAGE_ID_TO_ATTACH or MESSAGE_LINK_TO_ATTACH] [ROOT_MESSAGE_ID or ROOT_MESSAGE_LINK]",
+						description: "Attach a message to a thread in the channel",
+						usage:       staticUsage(attachMessageUsage),
+						examples: []string{
+							"/wrangler attach message 8w89igrsffyt3ghmwsmsgyeoqe 4xp9fdt77pncbef59f4k1qe83o",
+						},
+						arguments: []commandArgument{
+							messageArgument("The ID of the message or a direct link to the message to be attached"),
+							messageArgument("The root message ID or a direct link to the root message of the thread"),
+						},
+						handler:  (*Plugin).runAttachMessageCommand,
+						wrangles: true,
+					},
+				},
+			},
+			{
+				name:        "detach",
+				hint:        "[subcommand]",
+				description: "Detach messages",
+				subcommands: []*wranglerCommand{
+					{
+						name:        "message",
+						hint:        "[MESSAGE_ID or MESSAGE_LINK]",
+						description: "Detach a reply from its thread into a new message in the channel",
+						usage:       staticUsage(detachMessageUsage),
+						examples: []string{
+							"/wrangler detach message 8w89igrsffyt3ghmwsmsgyeoqe",
+						},
+						arguments: []commandArgument{
+							messageArgument("The ID of the reply or a direct link to the reply to be detached"),
+						},
+						handler:  (*Plugin).runDetachMessageCommand,
+						wrangles: true,
+					},
+				},
+			},
+			{
+				name:        "merge",
+				hint:        "[subcommand]",
+				description: "Merge threads",
+				mergeOnly:   true,
+				subcommands: []*wranglerCommand{
+					{
+						name:        "thread",
+						hint:        "[ROOT_MESSAGE_ID or ROOT_MESSAGE_LINK] [TARGET_ROOT_MESSAGE_ID or TARGET_MESSAGE_LINK]",
+						description: "Merge a thread's messages into another existing thread",
+						usage:       getMergeThreadUsage,
+						examples: []string{
+							"/wrangler merge thread 8w89igrsffyt3ghmwsmsgyeoqe 4xp9fdt77pncbef59f4k1qe83o --dry-run",
+						},
+						arguments: []commandArgument{
+							messageArgument("The root message ID or a direct link to the root message of the thread to be merged"),
+							messageArgument("The root message ID or a direct link to the root message of the thread to merge into"),
+						},
+						handler:  (*Plugin).runMergeThreadCommand,
+						wrangles: true,
+					},
+				},
+			},
+			{
+				name:        "list",
+				hint:        "[subcommand]",
+				description: "Lists IDs for channels, messages and operations",
+				subcommands: []*wranglerCommand{
+					{
+						name:        "channels",
+						hint:        "[optional flags]",
+						description: "List channel IDs that you have joined",
+						usage:       getListChannelsUsage,
+						examples: []string{
+							"/wrangler list channels --team-filter engineering --channel-filter \"dev ops\"",
+							"/wrangler list channels --format markdown",
+							"/wrangler list channels --include-direct --include-archived --sort activity",
+							"/wrangler list channels --wrangle-target",
+						},
+						handler: withoutAuditDetails((*Plugin).runListChannelsCommand),
+					},
+					{
+						name:        "messages",
+						hint:        "[optional flags]",
+						description: "List message IDs in this channel",
+						usage:       getListMessagesUsage,
+						examples: []string{
+							"/wrangler list messages --count 50 --trim-length 100",
+							"/wrangler list messages --format json",
+							"/wrangler list messages --user @alice --after 7d --threads-only",
+							"/wrangler list messages --roots-only --count 100 --page 1",
+						},
+						handler: withoutAuditDetails((*Plugin).runListMessagesCommand),
+					},
+					{
+						name:        "operations",
+						description: "List recent operations that can be reverted",
+						usage:       staticUsage(listOperationsUsage),
+						handler:     withoutAuditDetails((*Plugin).runListOperationsCommand),
+					},
+				},
+			},
+			{
+				name:        "find",
+				hint:        "[subcommand]",
+				description: "Search for messages to wrangle",
+				subcommands: []*wranglerCommand{
+					{
+						name:        "messages",
+						hint:        "[TERMS] [optional flags]",
+						description: "Find message IDs in the channels you have joined",
+						usage:       getFindMessagesUsage,
+						examples: []string{
+							"/wrangler find messages \"release notes\" --in ~announcements",
+							"/wrangler find messages --from @alice --after 2021-06-01 --has-files",
+						},
+						handler: withoutAuditDetails((*Plugin).runFindMessagesCommand),
+					},
+				},
+			},
+			{
+				name:        "undo",
+				hint:        "[OPERATION_ID]",
+				description: "Revert a move, merge, split, attach or detach operation",
+				usage:       staticUsage(undoUsage),
+				examples: []string{
+					"/wrangler undo 8w89igrsffyt3ghmwsmsgyeoqe",
+				},
+				arguments: []commandArgument{
+					{helpText: "The ID of the operation to revert", hint: "[OPERATION_ID]"},
+				},
+				handler:  (*Plugin).runUndoCommand,
+				wrangles: true,
+			},
+			{
+				name:        "jobs",
+				hint:        "[subcommand]",
+				description: "Manage background jobs",
+				usage:       staticUsage(jobsUsage),
+				examples: []string{
+					"/wrangler jobs list",
+					"/wrangler jobs cancel 8w89igrsffyt3ghmwsmsgyeoqe",
+				},
+				handler: withoutAuditDetails((*Plugin).runJobsCommand),
+				subcommands: []*wranglerCommand{
+					{
+						name:        "list",
+						description: "List recent background jobs",
+					},
+					{
+						name:        "info",
+						hint:        "[JOB_ID]",
+						description: "Show the details of a background job",
+						arguments: []commandArgument{
+							{helpText: "The ID of the job", hint: "[JOB_ID]"},
+						},
+					},
+					{
+						name:        "cancel",
+						hint:        "[JOB_ID]",
+						description: "Cancel a running background job",
+						arguments: []commandArgument{
+							{helpText: "The ID of the job to cancel", hint: "[JOB_ID]"},
+						},
+					},
+				},
+			},
+			{
+				name:        "notifications",
+				hint:        "[on|off|digest]",
+				description: "Choose how you receive Wrangler direct messages",
+				usage:       staticUsage(notificationsUsage),
+				examples: []string{
+					"/wrangler notifications digest",
+				},
+				handler:  withoutAuditDetails((*Plugin).runNotificationsCommand),
+				allUsers: true,
+				subcommands: []*wranglerCommand{
+					{
+						name:        notificationsOn,
+						description: "Send each direct message right away",
+					},
+					{
+						name:        notificationsOff,
+						description: "Stop sending direct messages",
+					},
+					{
+						name:        notificationsDigest,
+						description: "Batch direct messages into one per day",
+					},
+				},
+			},
+			{
+				name:        "audit",
+				hint:        "[optional flags]",
+				description: "List recorded Wrangler commands (system admins only)",
+				usage:       getAuditUsage,
+				examples: []string{
+					"/wrangler audit --user @alice --since 30d",
+				},
+				handler: withoutAuditDetails((*Plugin).runAuditCommand),
+			},
+			{
+				name:        "info",
+				hint:        "[optional flags]",
+				description: "Shows plugin information",
+				usage:       getInfoUsage,
+				examples: []string{
+					"/wrangler info --format json",
+				},
+				handler: withoutAuditDetails((*Plugin).runInfoCommand),
+			},
+			{
+				name:        "help",
+				hint:        "[COMMAND]",
+				description: "Shows detailed help information",
+				usage:       staticUsage(helpUsage),
+				examples: []string{
+					"/wrangler help move thread",
+					"/wrangler help list",
+				},
+				handler: withoutAuditDetails((*Plugin).runHelpCommand),
+			},
+		},
+	}
+}
+
+// subcommand returns the subcommand with the given name or nil if there is
+// none.
+func (c *wranglerCommand) subcommand(name string) *wranglerCommand {
+	for _, subcommand := range c.subcommands {
+		if subcommand.name == name {
+			return subcommand
+		}
+	}
+
+	return nil
+}
+
+// visibleSubcommand returns the visible subcommand with the given name or nil
+// if there is none.
+func (c *wranglerCommand) visibleSubcommand(name string, mergeEnabled bool) *wranglerCommand {
+	subcommand := c.subcommand(name)
+	if subcommand == nil || (subcommand.mergeOnly && !mergeEnabled) {
+		return nil
+	}
+
+	return subcommand
+}
+
+// visibleSubcommands returns the subcommands that are shown in help text and
+// command autocomplete.
+func (c *wranglerCommand) visibleSubcommands(mergeEnabled bool) []*wranglerCommand {
+	var subcommands []*wranglerCommand
+	for _, subcommand := range c.subcommands {
+		if subcommand.mergeOnly && !mergeEnabled {
+			continue
+		}
+		subcommands = append(subcommands, subcommand)
+	}
+
+	return subcommands
+}
+
+// findCommand walks the visible commands of the command tree with the given
+// arguments. It returns the deepest command that was found, the words that
+// named it and the remaining arguments.
+func (c *wranglerCommand) findCommand(args []string, mergeEnabled bool) (*wranglerCommand, []string, []string) {
+	command := c
+	var path []string
+	for command.handler == nil && len(args) != 0 {
+		subcommand := command.visibleSubcommand(args[0], mergeEnabled)
+		if subcommand == nil {
+			break
+		}
+		command = subcommand
+		path = append(path, args[0])
+		args = args[1:]
+	}
+
+	return command, path, args
+}
+
+// documentedCommands returns this command, or the visible commands below it,
+// that have usage text.
+func (c *wranglerCommand) documentedCommands(mergeEnabled bool) []*wranglerCommand {
+	if c.usage != nil {
+		return []*wranglerCommand{c}
+	}
+
+	var commands []*wranglerCommand
+	for _, subcommand := range c.visibleSubcommands(mergeEnabled) {
+		commands = append(commands, subcommand.documentedCommands(mergeEnabled)...)
+	}
+
+	return commands
+}
+
+// usages returns the usage text of every documented command below this one.
+func (c *wranglerCommand) usages(mergeEnabled bool) []string {
+	var usages []string
+	for _, command := range c.documentedCommands(mergeEnabled) {
+		usages = append(usages, strings.Trim(command.usage(), "\n"))
+	}
+
+	return usages
+}
+
+// commandNames returns the full names of the visible commands below this one
+// that have a handler.
+func (c *wranglerCommand) commandNames(mergeEnabled bool) []string {
+	var names []string
+	for _, subcommand := range c.visibleSubcommands(mergeEnabled) {
+		if subcommand.handler != nil {
+			names = append(names, subcommand.name)
+			continue
+		}
+		for _, name := range subcommand.commandNames(mergeEnabled) {
+			names = append(names, subcommand.name+" "+name)
+		}
+	}
+
+	return names
+}
+
+// autocompleteData converts the command and its visible subcommands to
+// command autocomplete data.
+func (c *wranglerCommand) autocompleteData(mergeEnabled bool) *model.AutocompleteData {
+	data := model.NewAutocompleteData(c.name, c.hint, c.description)
+	for _, argument := range c.arguments {
+		if len(argument.fetchRoute) != 0 {
+			data.AddDynamicListArgument(argument.helpText, fmt.Sprintf("/plugins/%s%s", manifest.Id, argument.fetchRoute), true)
+		} else {
+			data.AddTextArgument(argument.helpText, argument.hint, "")
+		}
+	}
+	for _, subcommand := range c.visibleSubcommands(mergeEnabled) {
+		data.AddCommand(subcommand.autocompleteData(mergeEnabled))
+	}
+
+	return data
+}
+
+// splitCommandArgs splits a command into arguments like a shell would. Runs
+// of whitespace separate arguments, single and double quotes group words into
+// a single argument and a backslash outside of single quotes escapes the next
+// character.
+func splitCommandArgs(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	var inArg, escaped bool
+
+	for _, r := range command {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.Errorf("missing closing %c quote", quote)
+	}
+	if escaped {
+		current.WriteRune('\\')
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCommandArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		expected []string
+	}{
+		{
+			name:     "empty",
+			command:  "",
+			expected: nil,
+		},
+		{
+			name:     "single spaces",
+			command:  "/wrangler list channels",
+			expected: []string{"/wrangler", "list", "channels"},
+		},
+		{
+			name:     "repeated and trailing whitespace",
+			command:  "  /wrangler   list\tchannels  ",
+			expected: []string{"/wrangler", "list", "channels"},
+		},
+		{
+			name:     "double quotes",
+			command:  `/wrangler list channels --channel-filter "dev ops"`,
+			expected: []string{"/wrangler", "list", "channels", "--channel-filter", "dev ops"},
+		},
+		{
+			name:     "single quotes",
+			command:  `/wrangler list channels --channel-filter='dev "ops"'`,
+			expected: []string{"/wrangler", "list", "channels", `--channel-filter=dev "ops"`},
+		},
+		{
+			name:     "empty quotes",
+			command:  `/wrangler list channels --channel-filter ""`,
+			expected: []string{"/wrangler", "list", "channels", "--channel-filter", ""},
+		},
+		{
+			name:     "escaped characters",
+			command:  `/wrangler list channels --channel-filter dev\ ops\"`,
+			expected: []string{"/wrangler", "list", "channels", "--channel-filter", `dev ops"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := splitCommandArgs(tt.command)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, args)
+		})
+	}
+
+	t.Run("missing closing quote", func(t *testing.T) {
+		_, err := splitCommandArgs(`/wrangler list channels --channel-filter "dev ops`)
+		require.EqualError(t, err, `missing closing " quote`)
+	})
+}
+
+func TestCommandTree(t *testing.T) {
+	tree := getCommandTree()
+
+	t.Run("find command", func(t *testing.T) {
+		command, path, args := tree.findCommand([]string{"move", "thread", "id1", "id2", "--silent"}, false)
+		require.NotNil(t, command.handler)
+		assert.Equal(t, []string{"move", "thread"}, path)
+		assert.Equal(t, []string{"id1", "id2", "--silent"}, args)
+
+		command, path, args = tree.findCommand([]string{"jobs", "info", "id1"}, false)
+		require.NotNil(t, command.handler)
+		assert.Equal(t, []string{"jobs"}, path)
+		assert.Equal(t, []string{"info", "id1"}, args)
+
+		command, path, _ = tree.findCommand([]string{"list", "invalid"}, false)
+		assert.Nil(t, command.handler)
+		assert.Equal(t, []string{"list"}, path)
+
+		command, path, _ = tree.findCommand([]string{"merge", "thread", "id1", "id2"}, true)
+		require.NotNil(t, command.handler)
+		assert.Equal(t, []string{"merge", "thread"}, path)
+
+		command, path, _ = tree.findCommand([]string{"merge", "thread", "id1", "id2"}, false)
+		assert.Nil(t, command.handler)
+		assert.Empty(t, path)
+	})
+
+	t.Run("every command is documented", func(t *testing.T) {
+		var check func(command *wranglerCommand, path string)
+		check = func(command *wranglerCommand, path string) {
+			if command.handler != nil {
+				require.NotNil(t, command.usage, path)
+				assert.True(t, strings.HasPrefix(command.usage(), "/"+path), path)
+				return
+			}
+			for _, subcommand := range command.subcommands {
+				check(subcommand, path+" "+subcommand.name)
+			}
+		}
+		check(tree, tree.name)
+	})
+
+	t.Run("merge is hidden when disabled", func(t *testing.T) {
+		assert.NotContains(t, tree.commandNames(false), "merge thread")
+		assert.Contains(t, tree.commandNames(true), "merge thread")
+
+		triggers := func(mergeEnabled bool) []string {
+			var names []string
+			for _, subcommand := range getAutocompleteData(mergeEnabled).SubCommands {
+				names = append(names, subcommand.Trigger)
+			}
+			return names
+		}
+		assert.NotContains(t, triggers(false), "merge")
+		assert.Contains(t, triggers(true), "merge")
+	})
+}
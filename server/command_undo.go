@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const undoUsage = `/wrangler undo [OPERATION_ID]
+  Revert a move thread, merge thread, split thread, attach message or detach message operation
+    - The thread or message is restored to its original channel or thread and the copies are removed
+    - Obtain the operation ID from the command response or by running '/wrangler list operations'
+    - System admins can revert operations run by any user`
+
+const listOperationsUsage = `/wrangler list operations
+  List recent Wrangler operations that can be reverted`
+
+func getUndoMessage() string {
+	return codeBlock(fmt.Sprintf("`Error: missing arguments\n\n%s", undoUsage))
+}
+
+func (p *Plugin) runUndoCommand(args []string, extra *model.CommandArgs, audit *AuditRecord) (*model.CommandResponse, bool, error) {
+	if len(args) < 1 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getUndoMessage()), true, nil
+	}
+	operationID := args[0]
+
+	operation, err := p.getWranglerOperation(operationID)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "unable to get operation")
+	}
+	if operation == nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to find operation with ID %s", operationID)), true, nil
+	}
+	if operation.IsReverted() {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: operation %s has already been reverted", operationID)), true, nil
+	}
+
+	executor, appErr := p.API.GetUser(extra.UserId)
+	if appErr != nil {
+		return nil, false, errors.Wrap(appErr, "unable to find executor")
+	}
+	if !executor.IsSystemAdmin() {
+		if operation.UserID != extra.UserId {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: only system admins can revert operations run by other users"), true, nil
+		}
+		err = p.ensureChannelMember(operation.OriginalChannelID, extra.UserId)
+		if err != nil {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: %s", err.Error())), true, nil
+		}
+	}
+
+	originalChannel, appErr := p.API.GetChannel(operation.OriginalChannelID)
+	if appErr != nil {
+		return nil, false, errors.Wrapf(appErr, "unable to get channel with ID %s", operation.OriginalChannelID)
+	}
+
+	// Split and detached replies are restored to the thread they were taken
+	// from so that thread is locked too.
+	lockRootIDs := []string{operation.NewRootID}
+	if operation.Type == operationTypeSplit || operation.Type == operationTypeDetach {
+		lockRootIDs = append(lockRootIDs, operation.OriginalRootID)
+	}
+	lock, response, err := p.acquireThreadLock(operationTypeUndo, extra, lockRootIDs...)
+	if err != nil {
+		return nil, false, err
+	}
+	if response != nil {
+		return response, true, nil
+	}
+	defer p.releaseThreadLock(lock)
+
+	// The operation is read again now that the threads are locked as it may
+	// have been reverted in the meantime.
+	operation, err = p.getWranglerOperation(operationID)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "unable to get operation")
+	}
+	if operation == nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: unable to find operation with ID %s", operationID)), true, nil
+	}
+	if operation.IsReverted() {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: operation %s has already been reverted", operationID)), true, nil
+	}
+
+	copies, err := p.getOperationCopies(operation)
+	if err != nil {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: %s; the operation can no longer be reverted", err.Error())), true, nil
+	}
+
+	audit.setWranglerDetails(operation.NewRootID, operation.TargetChannelID, "", operation.OriginalChannelID, copies.NumPosts())
+
+	p.API.LogInfo("Wrangler is reverting an operation",
+		"user_id", extra.UserId,
+		"operation_id", operation.ID,
+		"operation_type", operation.Type,
+	)
+
+	var restoredRootID string
+	var restoredPostIDs []postIDPair
+	if operation.Type == operationTypeSplit || operation.Type == operationTypeDetach {
+		originalRootPost, appErr := p.API.GetPost(operation.OriginalRootID)
+		if appErr != nil {
+			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the original thread no longer exists; the operation can no longer be reverted"), true, nil
+		}
+		restoredPostIDs, err = p.mergeWranglerPostlist(copies, originalRootPost, nil)
+		if err != nil {
+			return p.getRollbackResponse(err)
+		}
+		restoredRootID = originalRootPost.Id
+	} else {
+		var restoredRootPost *model.Post
+		restoredRootPost, restoredPostIDs, err = p.copyWranglerPostlist(copies, originalChannel, nil)
+		if err != nil {
+			return p.getRollbackResponse(err)
+		}
+		restoredRootID = restoredRootPost.Id
+
+		// Replies and links to the thread follow it back to the original
+		// channel.
+		if operation.Type == operationTypeMove || operation.Type == operationTypeMerge {
+			p.recordThreadRedirectOrLog(operation.OriginalRootID, restoredRootID, originalChannel.Id)
+		}
+		if operation.Type == operationTypeMove {
+			p.recordThreadRedirectOrLog(operation.NewRootID, restoredRootID, originalChannel.Id)
+		}
+	}
+
+	var partialFailure string
+	remaining, err := p.deleteOperationCopies(copies)
+	if err != nil {
+		if len(remaining) == copies.NumPosts() {
+			// None of the copies were deleted so the restored messages can be
+			// removed again.
+			return p.getRollbackResponse(p.rollback(err, restoredPostIDs, nil))
+		}
+
+		// Some copies are already gone, so the restored messages are kept
+		// and the copies that are left are reported instead.
+		p.API.LogError("Wrangler operation revert only partially completed",
+			"error", err.Error(),
+			"operation_id", operation.ID,
+			"remaining_post_ids", strings.Join(remaining, ","),
+		)
+		partialFailure = fmt.Sprintf("\nThe following copied message(s) could not be removed and should be deleted manually: %s", strings.Join(remaining, ", "))
+	}
+
+	if len(operation.TombstonePostID) != 0 {
+		appErr := p.API.DeletePost(operation.TombstonePostID)
+		if appErr != nil {
+			p.API.LogError("Unable to delete post left where the thread was moved",
+				"error", appErr.Error(),
+				"post_id", operation.TombstonePostID,
+			)
+		}
+	}
+
+	operation.RevertedAt = model.GetMillis()
+	operation.RevertedBy = extra.UserId
+	err = p.updateWranglerOperation(operation)
+	if err != nil {
+		p.API.LogError("Unable to mark operation as reverted",
+			"error", err.Error(),
+			"operation_id", operation.ID,
+		)
+	}
+
+	p.API.LogInfo("Wrangler operation revert complete",
+		"user_id", extra.UserId,
+		"operation_id", operation.ID,
+		"restored_post_id", restoredRootID,
+	)
+
+	audit.TargetPostID = restoredRootID
+
+	restoredPostLink, err := p.getPostLinkForChannel(originalChannel, extra.TeamId, restoredRootID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Operation %s has been reverted: %s\n%s", operation.ID, restoredPostLink, partialFailure)), false, nil
+}
+
+// getOperationCopies builds a post list from the posts that were created by an
+// operation. Operations that created a new thread are read from the thread as
+// it is now, so that replies added since the operation are restored with it.
+// Notes that Wrangler itself left in the thread are not restored.
+func (p *Plugin) getOperationCopies(operation *WranglerOperation) (*WranglerPostList, error) {
+	copyIDs := make(map[string]bool)
+	for _, pair := range operation.Posts {
+		copyIDs[pair.NewID] = true
+	}
+
+	postList := model.NewPostList()
+	switch operation.Type {
+	case operationTypeMove, operationTypeSplit, operationTypeDetach:
+		thread, appErr := p.API.GetPostThread(operation.NewRootID)
+		if appErr != nil {
+			return nil, errors.Errorf("unable to get thread with ID %s", operation.NewRootID)
+		}
+		for _, pair := range operation.Posts {
+			if _, ok := thread.Posts[pair.NewID]; !ok {
+				return nil, errors.Errorf("unable to get message with ID %s", pair.NewID)
+			}
+		}
+		for _, post := range thread.Posts {
+			if post.UserId == p.BotUserID && !copyIDs[post.Id] {
+				continue
+			}
+			postList.AddPost(post)
+			postList.AddOrder(post.Id)
+		}
+	default:
+		for _, pair := range operation.Posts {
+			post, appErr := p.API.GetPost(pair.NewID)
+			if appErr != nil {
+				return nil, errors.Errorf("unable to get message with ID %s", pair.NewID)
+			}
+			postList.AddPost(post)
+			postList.AddOrder(post.Id)
+		}
+	}
+
+	wpl := buildWranglerPostList(postList)
+	if wpl.NumPosts() == 0 {
+		return nil, errors.New("the operation contains no messages")
+	}
+
+	return wpl, nil
+}
+
+// deleteOperationCopies deletes the copies that are being reverted. Replies
+// are deleted before root posts as deleting a root post also deletes its
+// replies. If a post can't be deleted, the IDs of the posts that were not
+// deleted are returned with the error.
+func (p *Plugin) deleteOperationCopies(copies *WranglerPostList) ([]string, error) {
+	for i := len(copies.Posts) - 1; i >= 0; i-- {
+		appErr := p.API.DeletePost(copies.Posts[i].Id)
+		if appErr != nil {
+			var remaining []string
+			for _, post := range copies.Posts[:i+1] {
+				remaining = append(remaining, post.Id)
+			}
+
+			return remaining, errors.Wrap(appErr, "unable to delete post")
+		}
+	}
+
+	return nil, nil
+}
+
+func (p *Plugin) runListOperationsCommand(args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	executor, appErr := p.API.GetUser(extra.UserId)
+	if appErr != nil {
+		return nil, false, errors.Wrap(appErr, "unable to find executor")
+	}
+
+	operations, err := p.getRecentWranglerOperations()
+	if err != nil {
+		return nil, false, err
+	}
+
+	msg := "Recent Wrangler operations:\n"
+	var count int
+	for _, operation := range operations {
+		// System admins can see and revert all operations.
+		if !executor.IsSystemAdmin() && operation.UserID != extra.UserId {
+			continue
+		}
+
+		status := "active"
+		if operation.IsReverted() {
+			status = "reverted"
+		}
+
+		username := operation.UserID
+		user, appErr := p.API.GetUser(operation.UserID)
+		if appErr == nil {
+			username = user.Username
+		}
+
+		msg += fmt.Sprintf("%s - %-6s - %d message(s) - %s - @%s - %s\n",
+			operation.ID,
+			operation.Type,
+			len(operation.Posts),
+			time.Unix(0, operation.CreateAt*int64(time.Millisecond)).UTC().Format(time.RFC822),
+			username,
+			status,
+		)
+		count++
+	}
+
+	if count == 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "No results found"), false, nil
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, codeBlock(strings.TrimRight(msg, "\n"))), false, nil
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndoCommand(t *testing.T) {
+	team := &model.Team{
+		Id:   model.NewId(),
+		Name: "team-1",
+	}
+	originalChannel := &model.Channel{
+		Id:     model.NewId(),
+		TeamId: team.Id,
+		Name:   "original-channel",
+		Type:   model.CHANNEL_OPEN,
+	}
+	targetChannel := &model.Channel{
+		Id:     model.NewId(),
+		TeamId: team.Id,
+		Name:   "target-channel",
+		Type:   model.CHANNEL_OPEN,
+	}
+
+	user := &model.User{
+		Id:       model.NewId(),
+		Username: "user",
+	}
+	otherUser := &model.User{
+		Id:       model.NewId(),
+		Username: "other-user",
+	}
+	adminUser := &model.User{
+		Id:       model.NewId(),
+		Username: "admin",
+		Roles:    model.SYSTEM_ADMIN_ROLE_ID,
+	}
+
+	config := &model.Config{
+		ServiceSettings: model.ServiceSettings{
+			SiteURL: NewString("test.sampledomain.com"),
+		},
+	}
+
+	api := &plugintest.API{}
+	mockKVStore(api)
+
+	// newCopiedThread creates the thread of copies left by an operation.
+	newCopiedThread := func(total int) (*model.PostList, []postIDPair) {
+		thread := model.NewPostList()
+		var pairs []postIDPair
+		var rootID string
+		for i := 0; i < total; i++ {
+			post := &model.Post{
+				Id:        model.NewId(),
+				UserId:    model.NewId(),
+				ChannelId: targetChannel.Id,
+				RootId:    rootID,
+				Message:   fmt.Sprintf("This is copied message %d", i),
+				CreateAt:  int64(i + 1),
+			}
+			if i == 0 {
+				rootID = post.Id
+			}
+			thread.AddPost(post)
+			thread.AddOrder(post.Id)
+			pairs = append(pairs, postIDPair{OriginalID: model.NewId(), NewID: post.Id})
+		}
+		api.On("GetPostThread", rootID).Return(thread, nil)
+
+		return thread, pairs
+	}
+
+	_, pairs := newCopiedThread(3)
+
+	// Replies added after the operation and a note left by Wrangler.
+	laterThread, laterPairs := newCopiedThread(2)
+	laterReply := &model.Post{Id: model.NewId(), UserId: user.Id, RootId: laterPairs[0].NewID, Message: "A reply added later", CreateAt: 10}
+	botNote := &model.Post{Id: model.NewId(), UserId: "bot-user", RootId: laterPairs[0].NewID, Message: "This thread was moved from another channel", CreateAt: 3}
+	laterThread.AddPost(laterReply)
+	laterThread.AddOrder(laterReply.Id)
+	laterThread.AddPost(botNote)
+	laterThread.AddOrder(botNote.Id)
+
+	failedThread, failedPairs := newCopiedThread(2)
+	api.On("DeletePost", failedPairs[1].NewID).Return(&model.AppError{Message: "failed"})
+	partialThread, partialPairs := newCopiedThread(3)
+	api.On("DeletePost", partialPairs[1].NewID).Return(&model.AppError{Message: "failed"})
+	_, _ = failedThread, partialThread
+
+	api.On("GetPostThread", mock.AnythingOfType("string")).Return(nil, &model.AppError{})
+	api.On("GetPost", mock.AnythingOfType("string")).Return(nil, &model.AppError{})
+	api.On("GetUser", user.Id).Return(user, nil)
+	api.On("GetUser", otherUser.Id).Return(otherUser, nil)
+	api.On("GetUser", adminUser.Id).Return(adminUser, nil)
+	api.On("GetChannel", originalChannel.Id).Return(originalChannel, nil)
+	api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(mockGenerateChannelMember(), nil)
+	api.On("GetTeam", team.Id).Return(team, nil)
+	api.On("GetConfig").Return(config)
+	api.On("CreatePost", mock.Anything).Return(mockGeneratePost(), nil)
+	api.On("DeletePost", mock.AnythingOfType("string")).Return(nil)
+	api.On("GetReactions", mock.AnythingOfType("string")).Return(nil, nil)
+	api.On("LogInfo",
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+	).Return(nil)
+
+	api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	api.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+	plugin.BotUserID = "bot-user"
+	plugin.setConfiguration(&configuration{})
+
+	newOperation := func(userID string, posts []postIDPair) *WranglerOperation {
+		operation := &WranglerOperation{
+			Type:              operationTypeMove,
+			UserID:            userID,
+			OriginalChannelID: originalChannel.Id,
+			TargetChannelID:   targetChannel.Id,
+			OriginalRootID:    posts[0].OriginalID,
+			NewRootID:         posts[0].NewID,
+			Posts:             posts,
+		}
+		require.NoError(t, plugin.recordWranglerOperation(operation))
+
+		return operation
+	}
+
+	t.Run("no args", func(t *testing.T) {
+		resp, isUserError, err := plugin.runUndoCommand([]string{}, &model.CommandArgs{UserId: user.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: missing arguments")
+	})
+
+	t.Run("operation not found", func(t *testing.T) {
+		resp, isUserError, err := plugin.runUndoCommand([]string{"invalid"}, &model.CommandArgs{UserId: user.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: unable to find operation with ID invalid")
+	})
+
+	t.Run("operation run by another user", func(t *testing.T) {
+		operation := newOperation(otherUser.Id, pairs)
+
+		resp, isUserError, err := plugin.runUndoCommand([]string{operation.ID}, &model.CommandArgs{UserId: user.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: only system admins can revert operations run by other users")
+	})
+
+	t.Run("copies no longer exist", func(t *testing.T) {
+		operation := newOperation(user.Id, []postIDPair{{OriginalID: model.NewId(), NewID: model.NewId()}})
+
+		resp, isUserError, err := plugin.runUndoCommand([]string{operation.ID}, &model.CommandArgs{UserId: user.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "the operation can no longer be reverted")
+	})
+
+	t.Run("revert successfully", func(t *testing.T) {
+		operation := newOperation(user.Id, pairs)
+
+		resp, isUserError, err := plugin.runUndoCommand([]string{operation.ID}, &model.CommandArgs{UserId: user.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Operation "+operation.ID+" has been reverted")
+
+		stored, err := plugin.getWranglerOperation(operation.ID)
+		require.NoError(t, err)
+		assert.True(t, stored.IsReverted())
+		assert.Equal(t, user.Id, stored.RevertedBy)
+
+		t.Run("already reverted", func(t *testing.T) {
+			resp, isUserError, err := plugin.runUndoCommand([]string{operation.ID}, &model.CommandArgs{UserId: user.Id}, &AuditRecord{})
+			require.NoError(t, err)
+			assert.True(t, isUserError)
+			assert.Contains(t, resp.Text, "has already been reverted")
+		})
+	})
+
+	t.Run("revert removes the post left in the original channel", func(t *testing.T) {
+		operation := &WranglerOperation{
+			Type:              operationTypeMove,
+			UserID:            user.Id,
+			OriginalChannelID: originalChannel.Id,
+			TargetChannelID:   targetChannel.Id,
+			OriginalRootID:    pairs[0].OriginalID,
+			NewRootID:         pairs[0].NewID,
+			Posts:             pairs,
+			TombstonePostID:   model.NewId(),
+		}
+		require.NoError(t, plugin.recordWranglerOperation(operation))
+
+		_, isUserError, err := plugin.runUndoCommand([]string{operation.ID}, &model.CommandArgs{UserId: user.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		api.AssertCalled(t, "DeletePost", operation.TombstonePostID)
+	})
+
+	t.Run("replies added after the operation are restored", func(t *testing.T) {
+		operation := newOperation(user.Id, laterPairs)
+
+		resp, isUserError, err := plugin.runUndoCommand([]string{operation.ID}, &model.CommandArgs{UserId: user.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Operation "+operation.ID+" has been reverted")
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.Message == laterReply.Message
+		}))
+		api.AssertNotCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.Message == botNote.Message
+		}))
+	})
+
+	t.Run("thread is locked", func(t *testing.T) {
+		operation := newOperation(user.Id, pairs)
+
+		lock, response, err := plugin.acquireThreadLock(operationTypeMove, &model.CommandArgs{UserId: otherUser.Id}, operation.NewRootID)
+		require.NoError(t, err)
+		require.Nil(t, response)
+		defer plugin.releaseThreadLock(lock)
+
+		resp, isUserError, err := plugin.runUndoCommand([]string{operation.ID}, &model.CommandArgs{UserId: user.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "@other-user is currently running a move operation")
+
+		stored, err := plugin.getWranglerOperation(operation.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.IsReverted())
+	})
+
+	t.Run("copies can't be deleted", func(t *testing.T) {
+		operation := newOperation(user.Id, failedPairs)
+
+		resp, isUserError, err := plugin.runUndoCommand([]string{operation.ID}, &model.CommandArgs{UserId: user.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "rolled back cleanly")
+
+		stored, err := plugin.getWranglerOperation(operation.ID)
+		require.NoError(t, err)
+		assert.False(t, stored.IsReverted())
+	})
+
+	t.Run("some copies can't be deleted", func(t *testing.T) {
+		operation := newOperation(user.Id, partialPairs)
+
+		resp, isUserError, err := plugin.runUndoCommand([]string{operation.ID}, &model.CommandArgs{UserId: user.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Operation "+operation.ID+" has been reverted")
+		assert.Contains(t, resp.Text, "could not be removed and should be deleted manually: "+partialPairs[0].NewID+", "+partialPairs[1].NewID)
+		assert.NotContains(t, resp.Text, partialPairs[2].NewID)
+	})
+
+	t.Run("admin reverts another user's operation", func(t *testing.T) {
+		operation := newOperation(otherUser.Id, pairs)
+
+		resp, isUserError, err := plugin.runUndoCommand([]string{operation.ID}, &model.CommandArgs{UserId: adminUser.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Contains(t, resp.Text, "Operation "+operation.ID+" has been reverted")
+	})
+
+	t.Run("list operations", func(t *testing.T) {
+		t.Run("user sees own operations", func(t *testing.T) {
+			resp, isUserError, err := plugin.runListOperationsCommand([]string{}, &model.CommandArgs{UserId: user.Id})
+			require.NoError(t, err)
+			assert.False(t, isUserError)
+			assert.Contains(t, resp.Text, "@user")
+			assert.NotContains(t, resp.Text, "@other-user")
+		})
+
+		t.Run("admin sees all operations", func(t *testing.T) {
+			resp, isUserError, err := plugin.runListOperationsCommand([]string{}, &model.CommandArgs{UserId: adminUser.Id})
+			require.NoError(t, err)
+			assert.False(t, isUserError)
+			assert.Contains(t, resp.Text, "@user")
+			assert.Contains(t, resp.Text, "@other-user")
+			assert.Contains(t, resp.Text, "reverted")
+		})
+	})
+}
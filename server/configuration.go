@@ -40,10 +40,17 @@ type configuration struct {
 	MoveThreadFromDirectMessageChannelEnable bool
 	MoveThreadFromGroupMessageChannelEnable  bool
 	MergeThreadEnable                        bool
+	BackgroundJobThreshold                   string
 
 	ThreadAttachMessage string
+	ThreadDetachMessage string
 	MoveThreadMessage   string
 	CopyThreadMessage   string
+
+	MoveThreadTombstoneEnable  bool
+	MoveThreadTombstoneMessage string
+
+	NotifyThreadPolicy string
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -74,6 +81,16 @@ func (c *configuration) IsValid() error {
 		return errors.Wrap(err, "invalid MoveThreadMaxSize")
 	}
 
+	_, err = parseAndValidateBackgroundJobThreshold(c.BackgroundJobThreshold)
+	if err != nil {
+		return errors.Wrap(err, "invalid BackgroundJobThreshold")
+	}
+
+	_, err = parseNotifyPolicy(c.NotifyThreadPolicy)
+	if err != nil {
+		return errors.Wrap(err, "invalid NotifyThreadPolicy")
+	}
+
 	return nil
 }
 
@@ -104,6 +121,33 @@ func parseAndValidateMaxThreadCountMoveSize(s string) (int, error) {
 	return max, nil
 }
 
+func (c *configuration) BackgroundJobThresholdInt() int {
+	// Use the parseAndValidate function, but ignore the error.
+	i, _ := parseAndValidateBackgroundJobThreshold(c.BackgroundJobThreshold)
+
+	return i
+}
+
+// parseAndValidateBackgroundJobThreshold parses the background job threshold
+// config value and returns an error if the value is invalid or cannot be
+// parsed. If BackgroundJobThreshold is not configured, set it to 0 which
+// stands for never running commands as background jobs.
+func parseAndValidateBackgroundJobThreshold(s string) (int, error) {
+	if len(s) == 0 {
+		return 0, nil
+	}
+
+	threshold, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.Wrapf(err, "BackgroundJobThreshold value %s is not a valid integer", s)
+	}
+	if threshold < 1 {
+		return 0, fmt.Errorf("BackgroundJobThreshold (%d) must be greater than 0", threshold)
+	}
+
+	return threshold, nil
+}
+
 // getConfiguration retrieves the active configuration under lock, making it safe to use
 // concurrently. The active configuration may change underneath the client of this method, but
 // the struct returned by this API call is considered immutable.
@@ -60,4 +60,49 @@ func TestConfigurationIsValid(t *testing.T) {
 			require.NoError(t, config.IsValid())
 		})
 	})
+
+	t.Run("BackgroundJobThreshold", func(t *testing.T) {
+		config := baseConfiguration
+
+		t.Run("valid integer", func(t *testing.T) {
+			config.BackgroundJobThreshold = "100"
+			require.NoError(t, config.IsValid())
+			require.Equal(t, 100, config.BackgroundJobThresholdInt())
+		})
+
+		t.Run("invalid integer", func(t *testing.T) {
+			config.BackgroundJobThreshold = "many"
+			require.Error(t, config.IsValid())
+		})
+
+		t.Run("zero", func(t *testing.T) {
+			config.BackgroundJobThreshold = "0"
+			require.Error(t, config.IsValid())
+		})
+
+		t.Run("unset value", func(t *testing.T) {
+			config.BackgroundJobThreshold = ""
+			require.NoError(t, config.IsValid())
+			require.Equal(t, 0, config.BackgroundJobThresholdInt())
+		})
+	})
+
+	t.Run("NotifyThreadPolicy", func(t *testing.T) {
+		config := baseConfiguration
+
+		t.Run("valid policy", func(t *testing.T) {
+			config.NotifyThreadPolicy = notifyPolicyAllParticipants
+			require.NoError(t, config.IsValid())
+		})
+
+		t.Run("invalid policy", func(t *testing.T) {
+			config.NotifyThreadPolicy = "everyone"
+			require.Error(t, config.IsValid())
+		})
+
+		t.Run("unset value", func(t *testing.T) {
+			config.NotifyThreadPolicy = ""
+			require.NoError(t, config.IsValid())
+		})
+	})
 }
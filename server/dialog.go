@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	dialogElementPostID    = "post_id"
+	dialogElementChannelID = "channel_id"
+)
+
+// wranglerDialogState is passed through an interactive dialog so that the
+// submission can be run as the command that opened it.
+type wranglerDialogState struct {
+	Command string `json:"command"`
+}
+
+// wranglerDialogCommand describes a command that can be run from an
+// interactive dialog.
+type wranglerDialogCommand struct {
+	name    string
+	title   string
+	verb    string
+	flagSet func() *pflag.FlagSet
+	handler func([]string, *model.CommandArgs, *AuditRecord) (*model.CommandResponse, bool, error)
+}
+
+func (p *Plugin) getWranglerDialogCommand(name string) *wranglerDialogCommand {
+	switch name {
+	case "move thread":
+		return &wranglerDialogCommand{
+			name:    name,
+			title:   "Move Thread",
+			verb:    "Move",
+			flagSet: getMoveThreadFlagSet,
+			handler: p.runMoveThreadCommand,
+		}
+	case "copy thread":
+		return &wranglerDialogCommand{
+			name:    name,
+			title:   "Copy Thread",
+			verb:    "Copy",
+			flagSet: getCopyThreadFlagSet,
+			handler: p.runCopyThreadCommand,
+		}
+	}
+
+	return nil
+}
+
+// openWranglerDialog opens an interactive dialog asking for the missing
+// arguments of a move or copy command. The message is prefilled with the
+// given argument or with the thread the command was run from.
+func (p *Plugin) openWranglerDialog(command *wranglerDialogCommand, args []string, extra *model.CommandArgs) (*model.CommandResponse, bool, error) {
+	postID := extra.RootId
+	if len(args) > 0 {
+		postID = cleanInputID(args[0], extra.SiteURL)
+	}
+
+	state, err := json.Marshal(&wranglerDialogState{Command: command.name})
+	if err != nil {
+		return nil, false, errors.Wrap(err, "unable to marshal dialog state")
+	}
+
+	channelElement, err := p.getDialogChannelElement(postID, extra)
+	if err != nil {
+		return nil, false, err
+	}
+
+	elements := []model.DialogElement{
+		{
+			DisplayName: "Message",
+			Name:        dialogElementPostID,
+			Type:        "text",
+			Default:     postID,
+			Placeholder: "Message ID or link",
+			HelpText:    "The message, along with the thread it belongs to, to " + command.verb + "; obtain the link via the 'Copy Link' message dropdown option",
+		},
+		*channelElement,
+	}
+	command.flagSet().VisitAll(func(flag *pflag.Flag) {
+		if flag.Value.Type() != "bool" {
+			return
+		}
+		elements = append(elements, model.DialogElement{
+			DisplayName: dialogFlagLabel(flag.Name),
+			Name:        flag.Name,
+			Type:        "bool",
+			Default:     flag.DefValue,
+			HelpText:    flag.Usage,
+			Optional:    true,
+		})
+	})
+
+	appErr := p.API.OpenInteractiveDialog(model.OpenDialogRequest{
+		TriggerId: extra.TriggerId,
+		URL:       fmt.Sprintf("/plugins/%s%s", manifest.Id, routeAPIDialogSubmit),
+		Dialog: model.Dialog{
+			CallbackId:  command.name,
+			Title:       "Wrangler - " + command.title,
+			IconURL:     fmt.Sprintf("/plugins/%s/profile.png", manifest.Id),
+			SubmitLabel: command.verb,
+			Elements:    elements,
+			State:       string(state),
+		},
+	})
+	if appErr != nil {
+		return nil, false, errors.Wrap(appErr, "unable to open dialog")
+	}
+
+	return &model.CommandResponse{}, false, nil
+}
+
+// dialogFlagLabel turns the name of a command flag into the label of its dialog
+// element, e.g. dry-run into "Dry run".
+func dialogFlagLabel(name string) string {
+	label := strings.ReplaceAll(name, "-", " ")
+	if len(label) == 0 {
+		return label
+	}
+
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+// getDialogChannelElement returns the channel selector of a move or copy
+// dialog. Only the eligible channels are offered when the message is already
+// known, otherwise the channels of the current team are offered.
+func (p *Plugin) getDialogChannelElement(postID string, extra *model.CommandArgs) (*model.DialogElement, error) {
+	element := &model.DialogElement{
+		DisplayName: "Channel",
+		Name:        dialogElementChannelID,
+		Type:        "select",
+		Placeholder: "Choose a channel",
+	}
+
+	if !model.IsValidId(postID) {
+		element.DataSource = "channels"
+		return element, nil
+	}
+
+	postListResponse, appErr := p.API.GetPostThread(postID)
+	if appErr != nil {
+		element.DataSource = "channels"
+		return element, nil
+	}
+	wpl := buildWranglerPostList(postListResponse)
+	if wpl.NumPosts() == 0 {
+		element.DataSource = "channels"
+		return element, nil
+	}
+	sourceChannel, appErr := p.API.GetChannel(wpl.RootPost().ChannelId)
+	if appErr != nil {
+		return nil, errors.Wrapf(appErr, "unable to get channel with ID %s", wpl.RootPost().ChannelId)
+	}
+
+	channels, err := p.getEligibleChannels(wpl, sourceChannel, extra.UserId, "", "")
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range channels {
+		element.Options = append(element.Options, &model.PostActionOptions{
+			Text:  fmt.Sprintf("%s - %s", channel.TeamDisplayName, channel.DisplayName),
+			Value: channel.ID,
+		})
+	}
+
+	return element, nil
+}
+
+// getDialogCommandArgs converts a dialog submission to the arguments of the
+// command that opened the dialog.
+func getDialogCommandArgs(command *wranglerDialogCommand, submission map[string]interface{}) []string {
+	postID, _ := submission[dialogElementPostID].(string)
+	channelID, _ := submission[dialogElementChannelID].(string)
+	args := []string{postID, channelID}
+
+	command.flagSet().VisitAll(func(flag *pflag.Flag) {
+		value, ok := submission[flag.Name].(bool)
+		if !ok {
+			return
+		}
+		args = append(args, "--"+flag.Name+"="+strconv.FormatBool(value))
+	})
+
+	return args
+}
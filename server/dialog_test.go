@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWranglerDialog(t *testing.T) {
+	team1 := &model.Team{
+		Id:          model.NewId(),
+		Name:        "team-1",
+		DisplayName: "Team 1",
+	}
+	sourceChannel := &model.Channel{
+		Id:     model.NewId(),
+		TeamId: team1.Id,
+		Name:   "source",
+		Type:   model.CHANNEL_OPEN,
+	}
+	targetChannel := &model.Channel{
+		Id:          model.NewId(),
+		TeamId:      team1.Id,
+		Name:        "target",
+		DisplayName: "Target",
+		Type:        model.CHANNEL_OPEN,
+	}
+	user := &model.User{
+		Id:       model.NewId(),
+		Username: "user",
+	}
+
+	postList := mockGeneratePostList(1, sourceChannel.Id, false)
+	rootPost := postList.Posts[postList.Order[0]]
+	rootPost.UserId = user.Id
+
+	config := &model.Config{
+		ServiceSettings: model.ServiceSettings{
+			SiteURL: NewString("https://test.sampledomain.com"),
+		},
+	}
+
+	var openedDialog model.OpenDialogRequest
+	var ephemeralPost *model.Post
+
+	api := &plugintest.API{}
+	api.On("OpenInteractiveDialog", mock.Anything).Run(func(args mock.Arguments) {
+		openedDialog = args.Get(0).(model.OpenDialogRequest)
+	}).Return(nil)
+	api.On("SendEphemeralPost", user.Id, mock.Anything).Run(func(args mock.Arguments) {
+		ephemeralPost = args.Get(1).(*model.Post)
+	}).Return(nil)
+	api.On("GetPostThread", rootPost.Id).Return(postList, nil)
+	api.On("GetPostThread", mock.AnythingOfType("string")).Return(nil, model.NewAppError("where", model.NewId(), nil, "not found", 0))
+	api.On("GetChannel", sourceChannel.Id).Return(sourceChannel, nil)
+	api.On("GetChannel", targetChannel.Id).Return(targetChannel, nil)
+	api.On("GetChannelMember", mock.AnythingOfType("string"), user.Id).Return(&model.ChannelMember{}, nil)
+	api.On("HasPermissionToChannel", user.Id, mock.AnythingOfType("string"), model.PERMISSION_CREATE_POST).Return(true)
+	api.On("GetTeamsForUser", user.Id).Return([]*model.Team{team1}, nil)
+	api.On("GetChannelsForTeamForUser", team1.Id, user.Id, false).Return([]*model.Channel{sourceChannel, targetChannel}, nil)
+	api.On("GetTeam", team1.Id).Return(team1, nil)
+	api.On("GetUser", user.Id).Return(user, nil)
+	api.On("GetReactions", mock.AnythingOfType("string")).Return([]*model.Reaction{}, nil)
+	api.On("GetConfig").Return(config)
+	mockKVStore(api)
+	api.On("LogError", mock.AnythingOfType("string")).Return(nil)
+	api.On("LogError",
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"), mock.AnythingOfType("string"),
+	).Return(nil)
+
+	var p Plugin
+	p.SetAPI(api)
+	p.setConfiguration(&configuration{
+		PermittedWranglerUsers:        permittedUserAllUsers,
+		MoveThreadToAnotherTeamEnable: true,
+	})
+
+	t.Run("missing arguments without a trigger", func(t *testing.T) {
+		resp, isUserError, err := p.runMoveThreadCommand([]string{}, &model.CommandArgs{UserId: user.Id}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.True(t, isUserError)
+		assert.Contains(t, resp.Text, "Error: missing arguments")
+	})
+
+	t.Run("open dialog without a message", func(t *testing.T) {
+		resp, isUserError, err := p.runMoveThreadCommand([]string{}, &model.CommandArgs{UserId: user.Id, TriggerId: "trigger"}, &AuditRecord{})
+		require.NoError(t, err)
+		assert.False(t, isUserError)
+		assert.Empty(t, resp.Text)
+
+		assert.Equal(t, "trigger", openedDialog.TriggerId)
+		assert.Equal(t, "/plugins/"+manifest.Id+routeAPIDialogSubmit, openedDialog.URL)
+		assert.Equal(t, "move thread", openedDialog.Dialog.CallbackId)
+
+		elements := openedDialog.Dialog.Elements
+		require.Len(t, elements, 5)
+		assert.Equal(t, dialogElementPostID, elements[0].Name)
+		assert.Empty(t, elements[0].Default)
+		assert.Equal(t, dialogElementChannelID, elements[1].Name)
+		assert.Equal(t, "channels", elements[1].DataSource)
+		assert.Equal(t, flagDryRun, elements[2].Name)
+		assert.Equal(t, "Dry run", elements[2].DisplayName)
+		assert.Equal(t, "bool", elements[2].Type)
+		assert.Equal(t, "false", elements[2].Default)
+		assert.Equal(t, flagMoveThreadShowMessageSummary, elements[3].Name)
+		assert.Equal(t, "Show root message in summary", elements[3].DisplayName)
+		assert.Equal(t, "true", elements[3].Default)
+		assert.Equal(t, flagMoveThreadSilent, elements[4].Name)
+		assert.Equal(t, "Silent", elements[4].DisplayName)
+	})
+
+	t.Run("open dialog with a message", func(t *testing.T) {
+		_, _, err := p.runCopyThreadCommand([]string{rootPost.Id}, &model.CommandArgs{UserId: user.Id, TriggerId: "trigger"}, &AuditRecord{})
+		require.NoError(t, err)
+
+		assert.Equal(t, "copy thread", openedDialog.Dialog.CallbackId)
+		elements := openedDialog.Dialog.Elements
+		require.Len(t, elements, 3)
+		assert.Equal(t, rootPost.Id, elements[0].Default)
+		assert.Empty(t, elements[1].DataSource)
+		require.Len(t, elements[1].Options, 1)
+		assert.Equal(t, "Team 1 - Target", elements[1].Options[0].Text)
+		assert.Equal(t, targetChannel.Id, elements[1].Options[0].Value)
+	})
+
+	submit := func(userID string, request *model.SubmitDialogRequest) (*httptest.ResponseRecorder, *model.SubmitDialogResponse) {
+		data, err := json.Marshal(request)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, routeAPIDialogSubmit, bytes.NewReader(data))
+		r.Header.Set("Mattermost-User-Id", userID)
+		p.ServeHTTP(&plugin.Context{}, w, r)
+
+		var response model.SubmitDialogResponse
+		if w.Code == http.StatusOK && w.Body.Len() != 0 {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		}
+		return w, &response
+	}
+	state := func(command string) string {
+		data, err := json.Marshal(&wranglerDialogState{Command: command})
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("submitted by another user", func(t *testing.T) {
+		w, _ := submit(model.NewId(), &model.SubmitDialogRequest{UserId: user.Id, State: state("move thread")})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		w, _ := submit(user.Id, &model.SubmitDialogRequest{UserId: user.Id, Cancelled: true})
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("unknown command", func(t *testing.T) {
+		w, _ := submit(user.Id, &model.SubmitDialogRequest{UserId: user.Id, State: state("split thread")})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("missing message", func(t *testing.T) {
+		w, response := submit(user.Id, &model.SubmitDialogRequest{
+			UserId: user.Id,
+			State:  state("move thread"),
+			Submission: map[string]interface{}{
+				dialogElementChannelID: targetChannel.Id,
+			},
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, response.Errors, dialogElementPostID)
+	})
+
+	t.Run("rejected by the command", func(t *testing.T) {
+		w, response := submit(user.Id, &model.SubmitDialogRequest{
+			UserId:    user.Id,
+			ChannelId: sourceChannel.Id,
+			State:     state("move thread"),
+			Submission: map[string]interface{}{
+				dialogElementPostID:    model.NewId(),
+				dialogElementChannelID: targetChannel.Id,
+			},
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, response.Error, "Error: unable to get post with ID")
+	})
+
+	t.Run("invalid arguments", func(t *testing.T) {
+		w, response := submit(user.Id, &model.SubmitDialogRequest{
+			UserId:    user.Id,
+			ChannelId: sourceChannel.Id,
+			State:     state("move thread"),
+			Submission: map[string]interface{}{
+				dialogElementPostID:    "--unknown",
+				dialogElementChannelID: targetChannel.Id,
+			},
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "Error: unable to parse move thread flag args: unknown flag: --unknown", response.Error)
+	})
+
+	t.Run("run the command", func(t *testing.T) {
+		w, response := submit(user.Id, &model.SubmitDialogRequest{
+			UserId:    user.Id,
+			ChannelId: sourceChannel.Id,
+			TeamId:    team1.Id,
+			State:     state("move thread"),
+			Submission: map[string]interface{}{
+				dialogElementPostID:              rootPost.Id,
+				dialogElementChannelID:           targetChannel.Id,
+				flagDryRun:                       true,
+				flagMoveThreadShowMessageSummary: false,
+			},
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, response.Error)
+
+		require.NotNil(t, ephemeralPost)
+		assert.Equal(t, sourceChannel.Id, ephemeralPost.ChannelId)
+		assert.Contains(t, ephemeralPost.Message, "Dry run: no messages were moved")
+		assert.Contains(t, ephemeralPost.Message, "to ~target in team team-1")
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const flagDryRun = "dry-run"
+
+// threadSummary contains details about the work required to wrangle a post
+// list.
+type threadSummary struct {
+	MessageCount    int
+	Participants    []string
+	AttachmentCount int64
+	AttachmentBytes int64
+	ReactionCount   int
+}
+
+// summarizeWranglerPostList gathers the details of a post list that are
+// relevant when previewing what a Wrangler operation would do.
+func (p *Plugin) summarizeWranglerPostList(wpl *WranglerPostList) (*threadSummary, error) {
+	summary := &threadSummary{
+		MessageCount:    wpl.NumPosts(),
+		AttachmentCount: wpl.FileAttachmentCount,
+	}
+
+	for _, userID := range wpl.ThreadUserIDs {
+		user, appErr := p.API.GetUser(userID)
+		if appErr != nil {
+			return nil, errors.Wrapf(appErr, "unable to get user with ID %s", userID)
+		}
+		summary.Participants = append(summary.Participants, user.Username)
+	}
+
+	for _, post := range wpl.Posts {
+		for _, fileID := range post.FileIds {
+			fileInfo, appErr := p.API.GetFileInfo(fileID)
+			if appErr != nil {
+				return nil, errors.Wrapf(appErr, "unable to get file info with ID %s", fileID)
+			}
+			summary.AttachmentBytes += fileInfo.Size
+		}
+
+		reactions, appErr := p.API.GetReactions(post.Id)
+		if appErr != nil {
+			return nil, errors.Wrapf(appErr, "unable to get reactions for post with ID %s", post.Id)
+		}
+		summary.ReactionCount += len(reactions)
+	}
+
+	return summary, nil
+}
+
+// buildDryRunResponse creates the command response describing what a Wrangler
+// operation would do if it was run without the dry-run flag.
+func (p *Plugin) buildDryRunResponse(wpl *WranglerPostList, action, target string, dmUserIDs []string) (*model.CommandResponse, bool, error) {
+	summary, err := p.summarizeWranglerPostList(wpl)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "unable to summarize thread")
+	}
+
+	var dmUsernames []string
+	for _, userID := range dmUserIDs {
+		user, appErr := p.API.GetUser(userID)
+		if appErr != nil {
+			return nil, false, errors.Wrapf(appErr, "unable to get user with ID %s", userID)
+		}
+		dmUsernames = append(dmUsernames, "@"+user.Username)
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, summary.dryRunReport(action, target, dmUsernames)), false, nil
+}
+
+func (s *threadSummary) dryRunReport(action, target string, dmUsernames []string) string {
+	msg := fmt.Sprintf("__Dry run: no messages were %s__\n\n", action)
+	msg += fmt.Sprintf("%d message(s) would be %s %s\n", s.MessageCount, action, target)
+
+	var participants []string
+	for _, username := range s.Participants {
+		participants = append(participants, "@"+username)
+	}
+	msg += fmt.Sprintf("Participants: %s\n", strings.Join(participants, ", "))
+
+	msg += fmt.Sprintf("File attachments to re-upload: %d (%s)\n", s.AttachmentCount, formatBytes(s.AttachmentBytes))
+	msg += fmt.Sprintf("Reactions to reapply: %d\n", s.ReactionCount)
+
+	if len(dmUsernames) == 0 {
+		msg += "Direct messages: none\n"
+	} else {
+		msg += fmt.Sprintf("Direct messages would be sent to: %s\n", strings.Join(dmUsernames, ", "))
+	}
+
+	return msg
+}
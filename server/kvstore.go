@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+const maxKVIndexUpdateAttempts = 5
+
+// kvGetJSON loads the value stored under the given key into v. The returned
+// bool is false if no value exists for the key.
+func (p *Plugin) kvGetJSON(key string, v interface{}) (bool, error) {
+	data, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return false, errors.Wrapf(appErr, "unable to get KV value for key %s", key)
+	}
+	if data == nil {
+		return false, nil
+	}
+
+	err := json.Unmarshal(data, v)
+	if err != nil {
+		return false, errors.Wrapf(err, "unable to unmarshal KV value for key %s", key)
+	}
+
+	return true, nil
+}
+
+// kvSetJSON stores v under the given key.
+func (p *Plugin) kvSetJSON(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrapf(err, "unable to marshal KV value for key %s", key)
+	}
+
+	appErr := p.API.KVSet(key, data)
+	if appErr != nil {
+		return errors.Wrapf(appErr, "unable to set KV value for key %s", key)
+	}
+
+	return nil
+}
+
+// kvAddToIndex prepends an ID to the list of IDs stored under the given index
+// key. Only the most recent max IDs are kept. The update is performed with
+// compare-and-set to remain safe when multiple plugin instances are running.
+func (p *Plugin) kvAddToIndex(indexKey, id string, max int) error {
+	for i := 0; i < maxKVIndexUpdateAttempts; i++ {
+		oldData, appErr := p.API.KVGet(indexKey)
+		if appErr != nil {
+			return errors.Wrapf(appErr, "unable to get KV index %s", indexKey)
+		}
+
+		var ids []string
+		if oldData != nil {
+			err := json.Unmarshal(oldData, &ids)
+			if err != nil {
+				return errors.Wrapf(err, "unable to unmarshal KV index %s", indexKey)
+			}
+		}
+
+		ids = append([]string{id}, ids...)
+		if len(ids) > max {
+			ids = ids[:max]
+		}
+
+		newData, err := json.Marshal(ids)
+		if err != nil {
+			return errors.Wrapf(err, "unable to marshal KV index %s", indexKey)
+		}
+
+		saved, appErr := p.API.KVCompareAndSet(indexKey, oldData, newData)
+		if appErr != nil {
+			return errors.Wrapf(appErr, "unable to update KV index %s", indexKey)
+		}
+		if saved {
+			return nil
+		}
+	}
+
+	return errors.Errorf("unable to update KV index %s after %d attempts", indexKey, maxKVIndexUpdateAttempts)
+}
+
+// kvGetIndex returns the list of IDs stored under the given index key.
+func (p *Plugin) kvGetIndex(indexKey string) ([]string, error) {
+	var ids []string
+	_, err := p.kvGetJSON(indexKey, &ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// kvAppendJSON appends v to the JSON array stored under the given key. The
+// update is performed with compare-and-set to remain safe when multiple plugin
+// instances are running.
+func (p *Plugin) kvAppendJSON(key string, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrapf(err, "unable to marshal KV value for key %s", key)
+	}
+
+	for i := 0; i < maxKVIndexUpdateAttempts; i++ {
+		oldData, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return errors.Wrapf(appErr, "unable to get KV value for key %s", key)
+		}
+
+		var values []json.RawMessage
+		if oldData != nil {
+			err = json.Unmarshal(oldData, &values)
+			if err != nil {
+				return errors.Wrapf(err, "unable to unmarshal KV value for key %s", key)
+			}
+		}
+		values = append(values, value)
+
+		newData, err := json.Marshal(values)
+		if err != nil {
+			return errors.Wrapf(err, "unable to marshal KV value for key %s", key)
+		}
+
+		saved, appErr := p.API.KVCompareAndSet(key, oldData, newData)
+		if appErr != nil {
+			return errors.Wrapf(appErr, "unable to update KV value for key %s", key)
+		}
+		if saved {
+			return nil
+		}
+	}
+
+	return errors.Errorf("unable to update KV value for key %s after %d attempts", key, maxKVIndexUpdateAttempts)
+}
+
+// kvUpdateSet adds an ID to, or removes it from, the set of IDs stored under
+// the given key. The update is performed with compare-and-set to remain safe
+// when multiple plugin instances are running.
+func (p *Plugin) kvUpdateSet(key, id string, add bool) error {
+	for i := 0; i < maxKVIndexUpdateAttempts; i++ {
+		oldData, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return errors.Wrapf(appErr, "unable to get KV set %s", key)
+		}
+
+		var ids []string
+		if oldData != nil {
+			err := json.Unmarshal(oldData, &ids)
+			if err != nil {
+				return errors.Wrapf(err, "unable to unmarshal KV set %s", key)
+			}
+		}
+
+		var found bool
+		newIDs := make([]string, 0, len(ids)+1)
+		for _, existing := range ids {
+			if existing == id {
+				found = true
+				if !add {
+					continue
+				}
+			}
+			newIDs = append(newIDs, existing)
+		}
+		if found == add {
+			return nil
+		}
+		if add {
+			newIDs = append(newIDs, id)
+		}
+
+		newData, err := json.Marshal(newIDs)
+		if err != nil {
+			return errors.Wrapf(err, "unable to marshal KV set %s", key)
+		}
+
+		saved, appErr := p.API.KVCompareAndSet(key, oldData, newData)
+		if appErr != nil {
+			return errors.Wrapf(appErr, "unable to update KV set %s", key)
+		}
+		if saved {
+			return nil
+		}
+	}
+
+	return errors.Errorf("unable to update KV set %s after %d attempts", key, maxKVIndexUpdateAttempts)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVIndex(t *testing.T) {
+	api := &plugintest.API{}
+	mockKVStore(api)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	t.Run("empty index", func(t *testing.T) {
+		ids, err := plugin.kvGetIndex("index")
+		require.NoError(t, err)
+		assert.Empty(t, ids)
+	})
+
+	t.Run("add to index", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			require.NoError(t, plugin.kvAddToIndex("index", fmt.Sprintf("id%d", i), 3))
+		}
+
+		ids, err := plugin.kvGetIndex("index")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"id4", "id3", "id2"}, ids)
+	})
+}
+
+func TestKVUpdateSet(t *testing.T) {
+	api := &plugintest.API{}
+	mockKVStore(api)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	require.NoError(t, plugin.kvUpdateSet("set", "id1", true))
+	require.NoError(t, plugin.kvUpdateSet("set", "id2", true))
+	require.NoError(t, plugin.kvUpdateSet("set", "id1", true))
+	ids, err := plugin.kvGetIndex("set")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id1", "id2"}, ids)
+
+	require.NoError(t, plugin.kvUpdateSet("set", "id1", false))
+	require.NoError(t, plugin.kvUpdateSet("set", "id3", false))
+	ids, err = plugin.kvGetIndex("set")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id2"}, ids)
+}
+
+// mockKVStore sets up the KV store methods of a mock API with an in-memory
+// implementation.
+func mockKVStore(api *plugintest.API) map[string][]byte {
+	var lock sync.Mutex
+	store := make(map[string][]byte)
+
+	api.On("KVGet", mock.AnythingOfType("string")).Return(
+		func(key string) []byte {
+			lock.Lock()
+			defer lock.Unlock()
+			return store[key]
+		},
+		nil,
+	)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(
+		func(key string, value []byte) *model.AppError {
+			lock.Lock()
+			defer lock.Unlock()
+			store[key] = value
+			return nil
+		},
+	)
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(
+		func(key string) *model.AppError {
+			lock.Lock()
+			defer lock.Unlock()
+			delete(store, key)
+			return nil
+		},
+	)
+	api.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(
+		func(key string, oldValue, newValue []byte) bool {
+			lock.Lock()
+			defer lock.Unlock()
+			if !bytes.Equal(store[key], oldValue) {
+				return false
+			}
+			store[key] = newValue
+			return true
+		},
+		nil,
+	)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(
+		func(key string, value []byte, options model.PluginKVSetOptions) bool {
+			lock.Lock()
+			defer lock.Unlock()
+			if options.Atomic && !bytes.Equal(store[key], options.OldValue) {
+				return false
+			}
+			store[key] = value
+			return true
+		},
+		nil,
+	)
+	api.On("KVCompareAndDelete", mock.AnythingOfType("string"), mock.Anything).Return(
+		func(key string, oldValue []byte) bool {
+			lock.Lock()
+			defer lock.Unlock()
+			if !bytes.Equal(store[key], oldValue) {
+				return false
+			}
+			delete(store, key)
+			return true
+		},
+		nil,
+	)
+	api.On("KVList", mock.AnythingOfType("int"), mock.AnythingOfType("int")).Return(
+		func(page, perPage int) []string {
+			lock.Lock()
+			defer lock.Unlock()
+			keys := make([]string, 0, len(store))
+			for key := range store {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if page*perPage >= len(keys) {
+				return []string{}
+			}
+			keys = keys[page*perPage:]
+			if len(keys) > perPage {
+				keys = keys[:perPage]
+			}
+			return keys
+		},
+		nil,
+	)
+
+	return store
+}
@@ -88,6 +88,14 @@ const manifestStr = `
         "placeholder": "",
         "default": null
       },
+      {
+        "key": "BackgroundJobThreshold",
+        "display_name": "Background Job Threshold",
+        "type": "text",
+        "help_text": "Threads with at least this many messages and file attachments combined are moved, copied or merged as background jobs with progress updates sent by the Wrangler bot. Leave empty to always run these commands immediately.",
+        "placeholder": "",
+        "default": null
+      },
       {
         "key": "MoveThreadToAnotherTeamEnable",
         "display_name": "Enable Moving Threads To Different Teams",
@@ -136,6 +144,14 @@ const manifestStr = `
         "placeholder": "",
         "default": "@{executor} wrangled one of your messages into a thread for you: {postLink}"
       },
+      {
+        "key": "ThreadDetachMessage",
+        "display_name": "Info-Message: Detached a Message",
+        "type": "text",
+        "help_text": "The message being sent to the user after detaching their message from a thread. Allowed variables: {executor}, {postLink}",
+        "placeholder": "",
+        "default": "@{executor} wrangled one of your messages out of a thread for you: {postLink}"
+      },
       {
         "key": "MoveThreadMessage",
         "display_name": "Info-Message: Moved a Thread",
@@ -151,6 +167,44 @@ const manifestStr = `
         "help_text": "The message being sent to the user after copying a message. Allowed variables: {executor}, {postLink}",
         "placeholder": "",
         "default": "@{executor} wrangled a thread you started to a new channel for you: {postLink}"
+      },
+      {
+        "key": "MoveThreadTombstoneEnable",
+        "display_name": "Leave a Message Where Moved Threads Were",
+        "type": "bool",
+        "help_text": "Control whether the Wrangler bot posts a message in the original channel of a thread after it was moved, so readers of the channel know where the conversation went. No message is posted for moves with --silent.",
+        "placeholder": "",
+        "default": false
+      },
+      {
+        "key": "MoveThreadTombstoneMessage",
+        "display_name": "Info-Message: Where a Thread Was Moved",
+        "type": "text",
+        "help_text": "The message posted in the original channel of a thread after it was moved. Allowed variables: {author}, {executor}, {channel}, {team}, {replyCount}, {messageCount}, {postLink}",
+        "placeholder": "",
+        "default": "Thread by @{author} with {replyCount} replies moved to ~{channel} by @{executor}: {postLink}"
+      },
+      {
+        "key": "NotifyThreadPolicy",
+        "display_name": "Notify Thread Participants",
+        "type": "dropdown",
+        "help_text": "Choose who the Wrangler bot sends a direct message to after a thread is moved or copied. The user running the command and bots are never notified. Can be overridden with the --notify flag of the move and copy commands.",
+        "placeholder": "",
+        "default": "root-author",
+        "options": [
+          {
+            "display_name": "Root message author only",
+            "value": "root-author"
+          },
+          {
+            "display_name": "All thread participants",
+            "value": "all-participants"
+          },
+          {
+            "display_name": "Nobody",
+            "value": "none"
+          }
+        ]
       }
     ]
   }
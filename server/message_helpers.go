@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/mattermost/mattermost-server/v5/model"
@@ -22,31 +23,12 @@ func (p *Plugin) validateMoveOrCopy(wpl *WranglerPostList, originalChannel *mode
 		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: you don't have permissions to create posts in channel %s", targetChannel.Name)), true, nil
 	}
 
-	config := p.getConfiguration()
-
-	switch originalChannel.Type {
-	case model.CHANNEL_PRIVATE:
-		if !config.MoveThreadFromPrivateChannelEnable {
-			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Wrangler is currently configured to not allow moving posts from private channels"), false, nil
-		}
-	case model.CHANNEL_DIRECT:
-		if !config.MoveThreadFromDirectMessageChannelEnable {
-			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Wrangler is currently configured to not allow moving posts from direct message channels"), false, nil
-		}
-	case model.CHANNEL_GROUP:
-		if !config.MoveThreadFromGroupMessageChannelEnable {
-			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Wrangler is currently configured to not allow moving posts from group message channels"), false, nil
-		}
-	}
-
-	if !originalChannel.IsGroupOrDirect() {
-		// DM and GM channels are "teamless" so it doesn't make sense to check
-		// the MoveThreadToAnotherTeamEnable config when dealing with those.
-		if !config.MoveThreadToAnotherTeamEnable && targetChannel.TeamId != originalChannel.TeamId {
-			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Wrangler is currently configured to not allow moving messages to different teams"), false, nil
-		}
+	msg := p.getMovePolicyViolation(originalChannel, targetChannel)
+	if len(msg) != 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
 	}
 
+	config := p.getConfiguration()
 	if config.MaxThreadCountMoveSizeInt() != 0 && config.MaxThreadCountMoveSizeInt() < wpl.NumPosts() {
 		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: the thread is %d posts long, but this command is configured to only move threads of up to %d posts", wpl.NumPosts(), config.MaxThreadCountMoveSizeInt())), true, nil
 	}
@@ -86,20 +68,45 @@ func (p *Plugin) validateMerge(wpl *WranglerPostList, targetRootPost *model.Post
 		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, err.Error()), true, nil
 	}
 
+	msg := p.getMovePolicyViolation(originalChannel, targetChannel)
+	if len(msg) != 0 {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), false, nil
+	}
+
+	config := p.getConfiguration()
+	if config.MaxThreadCountMoveSizeInt() != 0 && config.MaxThreadCountMoveSizeInt() < wpl.NumPosts() {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: the thread is %d posts long, but this command is configured to only move threads of up to %d posts", wpl.NumPosts(), config.MaxThreadCountMoveSizeInt())), true, nil
+	}
+
+	if wpl.RootPost().CreateAt < targetRootPost.CreateAt {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: Cannot merge older threads into newer threads. The destination thread must be older than the thread being moved."), true, nil
+	}
+
+	if extra.RootId == wpl.RootPost().Id || extra.ParentId == wpl.RootPost().Id {
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: this command cannot be run from inside the thread; please run directly in the channel containing the thread"), true, nil
+	}
+
+	return nil, false, nil
+}
+
+// getMovePolicyViolation returns the reason the plugin configuration doesn't
+// allow messages to be moved from the original channel to the target channel,
+// or an empty string if they can be moved.
+func (p *Plugin) getMovePolicyViolation(originalChannel, targetChannel *model.Channel) string {
 	config := p.getConfiguration()
 
 	switch originalChannel.Type {
 	case model.CHANNEL_PRIVATE:
 		if !config.MoveThreadFromPrivateChannelEnable {
-			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Wrangler is currently configured to not allow moving posts from private channels"), false, nil
+			return "Wrangler is currently configured to not allow moving posts from private channels"
 		}
 	case model.CHANNEL_DIRECT:
 		if !config.MoveThreadFromDirectMessageChannelEnable {
-			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Wrangler is currently configured to not allow moving posts from direct message channels"), false, nil
+			return "Wrangler is currently configured to not allow moving posts from direct message channels"
 		}
 	case model.CHANNEL_GROUP:
 		if !config.MoveThreadFromGroupMessageChannelEnable {
-			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Wrangler is currently configured to not allow moving posts from group message channels"), false, nil
+			return "Wrangler is currently configured to not allow moving posts from group message channels"
 		}
 	}
 
@@ -107,23 +114,11 @@ func (p *Plugin) validateMerge(wpl *WranglerPostList, targetRootPost *model.Post
 		// DM and GM channels are "teamless" so it doesn't make sense to check
 		// the MoveThreadToAnotherTeamEnable config when dealing with those.
 		if !config.MoveThreadToAnotherTeamEnable && targetChannel.TeamId != originalChannel.TeamId {
-			return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Wrangler is currently configured to not allow moving messages to different teams"), false, nil
+			return "Wrangler is currently configured to not allow moving messages to different teams"
 		}
 	}
 
-	if config.MaxThreadCountMoveSizeInt() != 0 && config.MaxThreadCountMoveSizeInt() < wpl.NumPosts() {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, fmt.Sprintf("Error: the thread is %d posts long, but this command is configured to only move threads of up to %d posts", wpl.NumPosts(), config.MaxThreadCountMoveSizeInt())), true, nil
-	}
-
-	if wpl.RootPost().CreateAt < targetRootPost.CreateAt {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: Cannot merge older threads into newer threads. The destination thread must be older than the thread being moved."), true, nil
-	}
-
-	if extra.RootId == wpl.RootPost().Id || extra.ParentId == wpl.RootPost().Id {
-		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: this command cannot be run from inside the thread; please run directly in the channel containing the thread"), true, nil
-	}
-
-	return nil, false, nil
+	return ""
 }
 
 func (p *Plugin) ensureOriginalAndTargetChannelMember(originalChannelID, targetChannelID, userID string) error {
@@ -148,43 +143,28 @@ func (p *Plugin) ensureChannelMember(channelID, userID string) error {
 	return nil
 }
 
-func (p *Plugin) copyWranglerPostlist(wpl *WranglerPostList, targetChannel *model.Channel) (*model.Post, error) {
+// progressFunc is called with the number of posts created so far while a post
+// list is being copied or merged. Returning an error aborts the operation and
+// rolls back the posts that were already created.
+type progressFunc func(created int) error
+
+func (p *Plugin) copyWranglerPostlist(wpl *WranglerPostList, targetChannel *model.Channel, progress progressFunc) (*model.Post, []postIDPair, error) {
 	var err error
 	var appErr *model.AppError
 	var newRootPost *model.Post
+	var postIDs []postIDPair
+	var newPosts []*model.Post
 
-	if wpl.ContainsFileAttachments() {
-		// The thread contains at least one attachment. To properly move the
-		// thread, the files will have to be re-uploaded. This is completed
-		// before any messages are moved.
-		// TODO: check number of files that need to be re-uploaded or file size?
-		p.API.LogInfo("Wrangler is re-uploading file attachments",
-			"file_count", wpl.FileAttachmentCount,
-		)
-
-		for _, post := range wpl.Posts {
-			var newFileIDs []string
-			var fileBytes []byte
-			var oldFileInfo, newFileInfo *model.FileInfo
-			for _, fileID := range post.FileIds {
-				oldFileInfo, appErr = p.API.GetFileInfo(fileID)
-				if appErr != nil {
-					return nil, errors.Wrap(appErr, "unable to lookup file info to re-upload")
-				}
-				fileBytes, appErr = p.API.GetFile(fileID)
-				if appErr != nil {
-					return nil, errors.Wrap(appErr, "unable to get file bytes to re-upload")
-				}
-				newFileInfo, appErr = p.API.UploadFile(fileBytes, targetChannel.Id, oldFileInfo.Name)
-				if appErr != nil {
-					return nil, errors.Wrap(appErr, "unable to re-upload file")
-				}
-
-				newFileIDs = append(newFileIDs, newFileInfo.Id)
-			}
+	// Replies may link to other posts of the thread, and those links have to
+	// point at the new posts once the thread is copied.
+	rewriter, err := p.getPermalinkRewriter(wpl, targetChannel)
+	if err != nil {
+		return nil, nil, err
+	}
 
-			post.FileIds = newFileIDs
-		}
+	uploadedFileIDs, err := p.reuploadFileAttachments(wpl, targetChannel.Id)
+	if err != nil {
+		return nil, nil, p.rollback(err, postIDs, uploadedFileIDs)
 	}
 
 	for i, post := range wpl.Posts {
@@ -201,11 +181,18 @@ func (p *Plugin) copyWranglerPostlist(wpl *WranglerPostList, targetChannel *mode
 		newPost := post.Clone()
 		cleanPost(newPost)
 		newPost.ChannelId = targetChannel.Id
+		if rewriter != nil {
+			newPost.Message = rewriter.rewrite(newPost.Message)
+		}
 
 		if i == 0 {
+			// The first post may be a reply in its original thread, but it
+			// becomes the root of the new thread.
+			newPost.RootId = ""
+			newPost.ParentId = ""
 			newPost, err = p.createPostWithRetries(newPost, 200*time.Millisecond, 3)
 			if err != nil {
-				return nil, errors.Wrap(err, "unable to create new root post")
+				return nil, nil, p.rollback(errors.Wrap(err, "unable to create new root post"), postIDs, uploadedFileIDs)
 			}
 			newRootPost = newPost.Clone()
 		} else {
@@ -213,10 +200,16 @@ func (p *Plugin) copyWranglerPostlist(wpl *WranglerPostList, targetChannel *mode
 			newPost.ParentId = newRootPost.Id
 			newPost, err = p.createPostWithRetries(newPost, 200*time.Millisecond, 3)
 			if err != nil {
-				return nil, errors.Wrap(err, "unable to create new post")
+				return nil, nil, p.rollback(errors.Wrap(err, "unable to create new post"), postIDs, uploadedFileIDs)
 			}
 		}
 
+		postIDs = append(postIDs, postIDPair{OriginalID: post.Id, NewID: newPost.Id})
+		newPosts = append(newPosts, newPost)
+		if rewriter != nil {
+			rewriter.addPost(post.Id, newPost.Id)
+		}
+
 		for _, reaction := range reactions {
 			reaction.PostId = newPost.Id
 			_, appErr = p.API.AddReaction(reaction)
@@ -226,9 +219,188 @@ func (p *Plugin) copyWranglerPostlist(wpl *WranglerPostList, targetChannel *mode
 				p.API.LogError("Failed to reapply reactions to post", "err", appErr)
 			}
 		}
+
+		if progress != nil {
+			err = progress(len(postIDs))
+			if err != nil {
+				return nil, nil, p.rollback(err, postIDs, uploadedFileIDs)
+			}
+		}
+	}
+
+	if rewriter != nil {
+		p.rewriteForwardPermalinks(wpl, newPosts, rewriter)
+		newRootPost.Message = newPosts[0].Message
+	}
+
+	return newRootPost, postIDs, nil
+}
+
+// rewriteForwardPermalinks updates the new posts that link to posts of the
+// thread that were copied after them, which couldn't be rewritten when the
+// new posts were created.
+func (p *Plugin) rewriteForwardPermalinks(wpl *WranglerPostList, newPosts []*model.Post, rewriter *permalinkRewriter) {
+	for i, post := range wpl.Posts {
+		message := rewriter.rewrite(post.Message)
+		if message == newPosts[i].Message {
+			continue
+		}
+
+		updatedPost := newPosts[i].Clone()
+		updatedPost.Message = message
+		updatedPost, appErr := p.API.UpdatePost(updatedPost)
+		if appErr != nil {
+			// Permalink errors are logged, but do not cause the plugin to
+			// abort the move thread process.
+			p.API.LogError("Failed to rewrite permalinks of new post", "err", appErr)
+			continue
+		}
+		newPosts[i] = updatedPost
+	}
+}
+
+// reuploadFileAttachments re-uploads all file attachments of a post list to
+// the given channel and updates the posts with the new file IDs. The IDs of
+// all uploaded files are returned, even on error, so that they can be tracked
+// for rollback.
+func (p *Plugin) reuploadFileAttachments(wpl *WranglerPostList, channelID string) ([]string, error) {
+	if !wpl.ContainsFileAttachments() {
+		return nil, nil
+	}
+
+	// The thread contains at least one attachment. To properly move the
+	// thread, the files will have to be re-uploaded. This is completed
+	// before any messages are moved.
+	// TODO: check number of files that need to be re-uploaded or file size?
+	p.API.LogInfo("Wrangler is re-uploading file attachments",
+		"file_count", wpl.FileAttachmentCount,
+	)
+
+	var uploadedFileIDs []string
+	for _, post := range wpl.Posts {
+		var newFileIDs []string
+		for _, fileID := range post.FileIds {
+			oldFileInfo, appErr := p.API.GetFileInfo(fileID)
+			if appErr != nil {
+				return uploadedFileIDs, errors.Wrap(appErr, "unable to lookup file info to re-upload")
+			}
+			fileBytes, appErr := p.API.GetFile(fileID)
+			if appErr != nil {
+				return uploadedFileIDs, errors.Wrap(appErr, "unable to get file bytes to re-upload")
+			}
+			newFileInfo, appErr := p.API.UploadFile(fileBytes, channelID, oldFileInfo.Name)
+			if appErr != nil {
+				return uploadedFileIDs, errors.Wrap(appErr, "unable to re-upload file")
+			}
+
+			newFileIDs = append(newFileIDs, newFileInfo.Id)
+			uploadedFileIDs = append(uploadedFileIDs, newFileInfo.Id)
+		}
+
+		post.FileIds = newFileIDs
+	}
+
+	return uploadedFileIDs, nil
+}
+
+// rollbackError is returned when a Wrangler operation failed partway through
+// and the posts it had already created were rolled back.
+type rollbackError struct {
+	cause       error
+	rollbackErr error
+}
+
+func (e *rollbackError) Error() string {
+	if e.rollbackErr != nil {
+		return fmt.Sprintf("%s; rollback failed: %s", e.cause.Error(), e.rollbackErr.Error())
+	}
+
+	return fmt.Sprintf("%s; rollback complete", e.cause.Error())
+}
+
+// Cause returns the error that caused the rollback.
+func (e *rollbackError) Cause() error {
+	return e.cause
+}
+
+// rollback deletes the posts created by a failed operation and returns an
+// error describing both the failure and the result of the rollback.
+func (p *Plugin) rollback(cause error, postIDs []postIDPair, uploadedFileIDs []string) error {
+	p.API.LogWarn("Wrangler operation failed; rolling back",
+		"error", cause.Error(),
+		"post_count", fmt.Sprintf("%d", len(postIDs)),
+		"file_count", fmt.Sprintf("%d", len(uploadedFileIDs)),
+	)
+
+	return &rollbackError{
+		cause:       cause,
+		rollbackErr: p.rollbackCreatedPosts(postIDs, uploadedFileIDs),
+	}
+}
+
+// rollbackCreatedPosts deletes created posts, newest first. Files attached to
+// the deleted posts are removed by the server along with them. Files that
+// were uploaded, but never attached to a post, are not visible in the channel
+// and cannot be deleted through the plugin API so they are only logged.
+func (p *Plugin) rollbackCreatedPosts(postIDs []postIDPair, uploadedFileIDs []string) error {
+	var failed []string
+	attachedFileIDs := make(map[string]bool)
+	for i := len(postIDs) - 1; i >= 0; i-- {
+		post, appErr := p.API.GetPost(postIDs[i].NewID)
+		if appErr == nil {
+			for _, fileID := range post.FileIds {
+				attachedFileIDs[fileID] = true
+			}
+		}
+
+		appErr = p.API.DeletePost(postIDs[i].NewID)
+		if appErr != nil {
+			failed = append(failed, postIDs[i].NewID)
+		}
+	}
+
+	for _, fileID := range uploadedFileIDs {
+		if !attachedFileIDs[fileID] {
+			p.API.LogWarn("Unattached file left behind after rollback", "file_id", fileID)
+		}
+	}
+
+	if len(failed) != 0 {
+		return errors.Errorf("unable to delete posts %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// getRollbackResponse converts an error returned while wrangling posts into a
+// command response that lets the executor know the state of the rollback.
+func (p *Plugin) getRollbackResponse(err error) (*model.CommandResponse, bool, error) {
+	var rbErr *rollbackError
+	if !errors.As(err, &rbErr) {
+		return nil, false, err
+	}
+
+	if rbErr.rollbackErr != nil {
+		p.API.LogError("Wrangler rollback failed", "error", err.Error())
+		return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the operation failed and could not be fully rolled back; some copied messages may remain in the target location. Please talk to your administrator for help."), false, nil
+	}
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, "Error: the operation failed, but was rolled back cleanly. The original messages and the target location are unchanged."), false, nil
+}
+
+// getPostLinkForChannel returns a permalink to a post in the given channel.
+// DM and GM channels have no team so the fallback team is used for those.
+func (p *Plugin) getPostLinkForChannel(channel *model.Channel, fallbackTeamID, postID string) (string, error) {
+	teamID := channel.TeamId
+	if len(teamID) == 0 {
+		teamID = fallbackTeamID
+	}
+	team, appErr := p.API.GetTeam(teamID)
+	if appErr != nil {
+		return "", errors.Wrapf(appErr, "unable to get team with ID %s", teamID)
 	}
 
-	return newRootPost, nil
+	return makePostLink(*p.API.GetConfig().ServiceSettings.SiteURL, team.Name, postID), nil
 }
 
 func (p *Plugin) createPostWithRetries(post *model.Post, retryDuration time.Duration, maxRetries int) (*model.Post, error) {
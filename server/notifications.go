@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	notifyPolicyRootAuthor      = "root-author"
+	notifyPolicyAllParticipants = "all-participants"
+	notifyPolicyNone            = "none"
+
+	flagNotify = "notify"
+
+	notificationsOn     = "on"
+	notificationsOff    = "off"
+	notificationsDigest = "digest"
+
+	kvNotificationsPrefix = "notifications_"
+	kvDigestPrefix        = "digest_"
+	kvDigestUsersKey      = "digest_users"
+
+	// digestInterval is how long notifications are batched before a digest
+	// is sent.
+	digestInterval = 24 * time.Hour
+	// digestCheckInterval is how often the digests are checked for whether
+	// they are due.
+	digestCheckInterval = time.Hour
+)
+
+// notificationPreference is how a user wants to receive the direct messages
+// Wrangler sends about wrangled threads.
+type notificationPreference struct {
+	Mode     string `json:"mode"`
+	UpdateAt int64  `json:"update_at"`
+}
+
+// digestEntry is a notification waiting to be sent in a digest.
+type digestEntry struct {
+	Message  string `json:"message"`
+	CreateAt int64  `json:"create_at"`
+}
+
+func notificationPreferenceKey(userID string) string {
+	return kvNotificationsPrefix + userID
+}
+
+func digestKey(userID string) string {
+	return kvDigestPrefix + userID
+}
+
+// parseNotifyPolicy validates a notification policy. An empty policy is
+// valid and stands for the root author only.
+func parseNotifyPolicy(s string) (string, error) {
+	switch s {
+	case "":
+		return notifyPolicyRootAuthor, nil
+	case notifyPolicyRootAuthor, notifyPolicyAllParticipants, notifyPolicyNone:
+		return s, nil
+	}
+
+	return "", fmt.Errorf("notification policy %s must be one of %s, %s or %s", s, notifyPolicyRootAuthor, notifyPolicyAllParticipants, notifyPolicyNone)
+}
+
+// getNotifyPolicy returns the notification policy given with the notify flag
+// of a command, or the configured policy if the flag was not set.
+func (p *Plugin) getNotifyPolicy(flagValue string) string {
+	if len(flagValue) != 0 {
+		return flagValue
+	}
+
+	// The configuration is validated when it changes.
+	policy, _ := parseNotifyPolicy(p.getConfiguration().NotifyThreadPolicy)
+
+	return policy
+}
+
+// getNotifiedUserIDs returns the users that are sent a direct message about a
+// wrangled thread under the given notification policy. The user running the
+// command and bots are never notified.
+func (p *Plugin) getNotifiedUserIDs(wpl *WranglerPostList, policy, executorID string) []string {
+	var candidates []string
+	switch policy {
+	case notifyPolicyRootAuthor:
+		candidates = []string{wpl.RootPost().UserId}
+	case notifyPolicyAllParticipants:
+		candidates = wpl.ThreadUserIDs
+	default:
+		return nil
+	}
+
+	var userIDs []string
+	for _, userID := range candidates {
+		if userID == executorID || userID == p.BotUserID {
+			continue
+		}
+		user, appErr := p.API.GetUser(userID)
+		if appErr != nil {
+			p.API.LogError("Unable to get user to notify",
+				"error", appErr.Error(),
+				"user_id", userID,
+			)
+			continue
+		}
+		if user.IsBot {
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs
+}
+
+// getNotificationMode returns how a user wants to receive Wrangler
+// notifications. Users who never chose are notified right away.
+func (p *Plugin) getNotificationMode(userID string) (string, error) {
+	var preference notificationPreference
+	found, err := p.kvGetJSON(notificationPreferenceKey(userID), &preference)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get notification preference")
+	}
+	if !found {
+		return notificationsOn, nil
+	}
+
+	return preference.Mode, nil
+}
+
+// setNotificationMode stores how a user wants to receive Wrangler
+// notifications. Pending digest notifications are sent right away when the
+// user turns notifications back on and dropped when they are turned off.
+func (p *Plugin) setNotificationMode(userID, mode string) error {
+	err := p.kvSetJSON(notificationPreferenceKey(userID), &notificationPreference{
+		Mode:     mode,
+		UpdateAt: model.GetMillis(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to set notification preference")
+	}
+
+	err = p.kvUpdateSet(kvDigestUsersKey, userID, mode == notificationsDigest)
+	if err != nil {
+		return errors.Wrap(err, "unable to update digest users")
+	}
+
+	switch mode {
+	case notificationsOn:
+		return p.sendDigest(userID, true)
+	case notificationsOff:
+		appErr := p.API.KVDelete(digestKey(userID))
+		if appErr != nil {
+			return errors.Wrap(appErr, "unable to delete pending digest")
+		}
+	}
+
+	return nil
+}
+
+// notifyUser sends a Wrangler notification to a user according to their
+// notification preference.
+func (p *Plugin) notifyUser(userID, message string) error {
+	mode, err := p.getNotificationMode(userID)
+	if err != nil {
+		// Notify the user as if they never chose rather than not at all.
+		p.API.LogError("Unable to get notification preference",
+			"error", err.Error(),
+			"user_id", userID,
+		)
+		mode = notificationsOn
+	}
+
+	switch mode {
+	case notificationsOff:
+		return nil
+	case notificationsDigest:
+		return p.kvAppendJSON(digestKey(userID), &digestEntry{
+			Message:  message,
+			CreateAt: model.GetMillis(),
+		})
+	}
+
+	return p.PostBotDM(userID, message)
+}
+
+// sendDigest sends the pending notifications of a user as one direct message
+// once the oldest of them has waited for the digest interval, or right away if
+// force is set.
+func (p *Plugin) sendDigest(userID string, force bool) error {
+	key := digestKey(userID)
+	data, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return errors.Wrapf(appErr, "unable to get digest for user %s", userID)
+	}
+	if data == nil {
+		return nil
+	}
+
+	var entries []digestEntry
+	err := json.Unmarshal(data, &entries)
+	if err != nil {
+		return errors.Wrapf(err, "unable to unmarshal digest for user %s", userID)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	if !force && model.GetMillis()-entries[0].CreateAt < digestInterval.Milliseconds() {
+		return nil
+	}
+
+	// Clearing the digest with compare-and-set ensures only one plugin
+	// instance sends it. If a notification was added in the meantime the
+	// digest is sent on the next check instead.
+	cleared, appErr := p.API.KVCompareAndSet(key, data, nil)
+	if appErr != nil {
+		return errors.Wrapf(appErr, "unable to clear digest for user %s", userID)
+	}
+	if !cleared {
+		return nil
+	}
+
+	location := p.getUserLocation(userID)
+	lines := []string{fmt.Sprintf("Your Wrangler digest of %d notification(s) since %s:", len(entries), formatListMessagesTime(entries[0].CreateAt, location))}
+	for _, entry := range entries {
+		lines = append(lines, fmt.Sprintf("- %s", entry.Message))
+	}
+
+	return p.PostBotDM(userID, strings.Join(lines, "\n"))
+}
+
+// sendDueDigests sends the digests of all users who have waited for the digest
+// interval.
+func (p *Plugin) sendDueDigests() {
+	userIDs, err := p.kvGetIndex(kvDigestUsersKey)
+	if err != nil {
+		p.API.LogError("Unable to get digest users", "error", err.Error())
+		return
+	}
+
+	for _, userID := range userIDs {
+		err = p.sendDigest(userID, false)
+		if err != nil {
+			p.API.LogError("Unable to send notification digest",
+				"error", err.Error(),
+				"user_id", userID,
+			)
+		}
+	}
+}
+
+// runDigestLoop periodically sends the digests that are due until the stop
+// channel is closed.
+func (p *Plugin) runDigestLoop(stop <-chan struct{}) {
+	ticker := time.NewTicker(digestCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			p.sendDueDigests()
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNotifiedUserIDs(t *testing.T) {
+	executor := &model.User{Id: model.NewId(), Username: "executor"}
+	author := &model.User{Id: model.NewId(), Username: "author"}
+	participant := &model.User{Id: model.NewId(), Username: "participant"}
+	bot := &model.User{Id: model.NewId(), Username: "bot", IsBot: true}
+
+	postList := mockGeneratePostList(5, model.NewId(), false)
+	wpl := buildWranglerPostList(postList)
+	for i, userID := range []string{author.Id, participant.Id, bot.Id, executor.Id, participant.Id} {
+		wpl.Posts[i].UserId = userID
+	}
+	wpl.ThreadUserIDs = []string{author.Id, participant.Id, bot.Id, executor.Id}
+
+	api := &plugintest.API{}
+	for _, user := range []*model.User{executor, author, participant, bot} {
+		api.On("GetUser", user.Id).Return(user, nil)
+	}
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	t.Run("root author", func(t *testing.T) {
+		assert.Equal(t, []string{author.Id}, plugin.getNotifiedUserIDs(wpl, notifyPolicyRootAuthor, executor.Id))
+	})
+
+	t.Run("root author running the command", func(t *testing.T) {
+		assert.Empty(t, plugin.getNotifiedUserIDs(wpl, notifyPolicyRootAuthor, author.Id))
+	})
+
+	t.Run("all participants", func(t *testing.T) {
+		assert.Equal(t, []string{author.Id, participant.Id}, plugin.getNotifiedUserIDs(wpl, notifyPolicyAllParticipants, executor.Id))
+	})
+
+	t.Run("none", func(t *testing.T) {
+		assert.Empty(t, plugin.getNotifiedUserIDs(wpl, notifyPolicyNone, executor.Id))
+	})
+}
+
+func TestGetNotifyPolicy(t *testing.T) {
+	var plugin Plugin
+
+	plugin.setConfiguration(&configuration{})
+	assert.Equal(t, notifyPolicyRootAuthor, plugin.getNotifyPolicy(""))
+
+	plugin.setConfiguration(&configuration{NotifyThreadPolicy: notifyPolicyAllParticipants})
+	assert.Equal(t, notifyPolicyAllParticipants, plugin.getNotifyPolicy(""))
+	assert.Equal(t, notifyPolicyNone, plugin.getNotifyPolicy(notifyPolicyNone))
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	user := &model.User{Id: model.NewId(), Username: "user"}
+	directChannel := &model.Channel{Id: model.NewId()}
+
+	setupPlugin := func() (*Plugin, *plugintest.API, map[string][]byte) {
+		api := &plugintest.API{}
+		kvStore := mockKVStore(api)
+		api.On("GetUser", user.Id).Return(user, nil)
+		api.On("GetDirectChannel", user.Id, mock.AnythingOfType("string")).Return(directChannel, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(mockGeneratePost(), nil)
+
+		plugin := &Plugin{BotUserID: model.NewId()}
+		plugin.SetAPI(api)
+
+		return plugin, api, kvStore
+	}
+
+	t.Run("on by default", func(t *testing.T) {
+		plugin, api, _ := setupPlugin()
+
+		mode, err := plugin.getNotificationMode(user.Id)
+		require.NoError(t, err)
+		assert.Equal(t, notificationsOn, mode)
+
+		require.NoError(t, plugin.notifyUser(user.Id, "moved"))
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+	})
+
+	t.Run("off", func(t *testing.T) {
+		plugin, api, _ := setupPlugin()
+
+		require.NoError(t, plugin.setNotificationMode(user.Id, notificationsOff))
+		require.NoError(t, plugin.notifyUser(user.Id, "moved"))
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+
+	t.Run("digest", func(t *testing.T) {
+		plugin, api, kvStore := setupPlugin()
+
+		require.NoError(t, plugin.setNotificationMode(user.Id, notificationsDigest))
+		require.NoError(t, plugin.notifyUser(user.Id, "moved https://example.com/team/pl/1"))
+		require.NoError(t, plugin.notifyUser(user.Id, "copied https://example.com/team/pl/2"))
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+
+		// The digest is not sent before it is due.
+		plugin.sendDueDigests()
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+
+		var entries []digestEntry
+		_, err := plugin.kvGetJSON(digestKey(user.Id), &entries)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		entries[0].CreateAt -= (25 * time.Hour).Milliseconds()
+		require.NoError(t, plugin.kvSetJSON(digestKey(user.Id), entries))
+
+		plugin.sendDueDigests()
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+		api.AssertCalled(t, "CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == directChannel.Id &&
+				strings.HasPrefix(post.Message, "Your Wrangler digest of 2 notification(s) since ") &&
+				strings.HasSuffix(post.Message, "\n- moved https://example.com/team/pl/1\n- copied https://example.com/team/pl/2")
+		}))
+		assert.Nil(t, kvStore[digestKey(user.Id)])
+
+		// Sent digests are not sent again.
+		plugin.sendDueDigests()
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+	})
+
+	t.Run("turning notifications on sends the pending digest", func(t *testing.T) {
+		plugin, api, _ := setupPlugin()
+
+		require.NoError(t, plugin.setNotificationMode(user.Id, notificationsDigest))
+		require.NoError(t, plugin.notifyUser(user.Id, "moved"))
+		require.NoError(t, plugin.setNotificationMode(user.Id, notificationsOn))
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+
+		userIDs, err := plugin.kvGetIndex(kvDigestUsersKey)
+		require.NoError(t, err)
+		assert.Empty(t, userIDs)
+	})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+// permalinkIDRegex matches the post ID of a permalink on any site and team.
+var permalinkIDRegex = regexp.MustCompile(`/[a-z0-9\-_]+/pl/([a-z0-9]{26})\b`)
+
+// permalinkRewriter rewrites permalinks to the posts of a copied post list so
+// that they point at the copies instead of the original posts.
+type permalinkRewriter struct {
+	siteURL string
+	// teamName replaces the team of rewritten permalinks. The original team
+	// is kept when it is empty.
+	teamName  string
+	linkRegex *regexp.Regexp
+	newIDs    map[string]string
+}
+
+func newPermalinkRewriter(siteURL, teamName string) *permalinkRewriter {
+	siteURL = strings.TrimRight(siteURL, "/")
+
+	return &permalinkRewriter{
+		siteURL:   siteURL,
+		teamName:  teamName,
+		linkRegex: regexp.MustCompile(fmt.Sprintf(`%s/([a-z0-9\-_]+)/pl/([a-z0-9]{26})\b`, regexp.QuoteMeta(siteURL))),
+		newIDs:    make(map[string]string),
+	}
+}
+
+// addPost records the ID of the copy of a post.
+func (r *permalinkRewriter) addPost(originalID, newID string) {
+	r.newIDs[originalID] = newID
+}
+
+// rewrite returns the message with the permalinks to posts that have already
+// been copied pointing at their copies.
+func (r *permalinkRewriter) rewrite(message string) string {
+	return r.linkRegex.ReplaceAllStringFunc(message, func(link string) string {
+		match := r.linkRegex.FindStringSubmatch(link)
+		newID, ok := r.newIDs[match[2]]
+		if !ok {
+			return link
+		}
+
+		teamName := r.teamName
+		if len(teamName) == 0 {
+			teamName = match[1]
+		}
+
+		return makePostLink(r.siteURL, teamName, newID)
+	})
+}
+
+// getPermalinkRewriter returns a rewriter for the permalinks between the posts
+// of a post list that is copied to the target channel, or nil if no post of
+// the list links to another one.
+func (p *Plugin) getPermalinkRewriter(wpl *WranglerPostList, targetChannel *model.Channel) (*permalinkRewriter, error) {
+	if !wpl.ContainsInternalPermalinks() {
+		return nil, nil
+	}
+
+	// DM and GM channels have no team so links to them keep their team.
+	var teamName string
+	if len(targetChannel.TeamId) != 0 {
+		team, appErr := p.API.GetTeam(targetChannel.TeamId)
+		if appErr != nil {
+			return nil, errors.Wrapf(appErr, "unable to get team with ID %s", targetChannel.TeamId)
+		}
+		teamName = team.Name
+	}
+
+	return newPermalinkRewriter(*p.API.GetConfig().ServiceSettings.SiteURL, teamName), nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermalinkRewriter(t *testing.T) {
+	originalID := model.NewId()
+	newID := model.NewId()
+	otherID := model.NewId()
+
+	rewriter := newPermalinkRewriter("https://example.com/", "new-team")
+	rewriter.addPost(originalID, newID)
+
+	t.Run("rewrites copied posts", func(t *testing.T) {
+		message := rewriter.rewrite("see https://example.com/old-team/pl/" + originalID + ".")
+		assert.Equal(t, "see https://example.com/new-team/pl/"+newID+".", message)
+	})
+
+	t.Run("keeps other links", func(t *testing.T) {
+		message := "https://example.com/old-team/pl/" + otherID + " https://other.com/old-team/pl/" + originalID
+		assert.Equal(t, message, rewriter.rewrite(message))
+	})
+
+	t.Run("keeps team without target team", func(t *testing.T) {
+		rewriter := newPermalinkRewriter("https://example.com", "")
+		rewriter.addPost(originalID, newID)
+		message := rewriter.rewrite("https://example.com/old-team/pl/" + originalID)
+		assert.Equal(t, "https://example.com/old-team/pl/"+newID, message)
+	})
+}
+
+func TestContainsInternalPermalinks(t *testing.T) {
+	postList := mockGeneratePostList(3, model.NewId(), false)
+	wpl := buildWranglerPostList(postList)
+	assert.False(t, wpl.ContainsInternalPermalinks())
+
+	wpl.Posts[1].Message = "https://example.com/team/pl/" + wpl.Posts[1].Id
+	assert.False(t, wpl.ContainsInternalPermalinks())
+
+	wpl.Posts[1].Message = "https://example.com/team/pl/" + model.NewId()
+	assert.False(t, wpl.ContainsInternalPermalinks())
+
+	wpl.Posts[1].Message = "https://example.com/team/pl/" + wpl.Posts[0].Id
+	assert.True(t, wpl.ContainsInternalPermalinks())
+}
+
+func TestCopyWranglerPostlistRewritesPermalinks(t *testing.T) {
+	siteURL := "https://example.com"
+	targetTeam := &model.Team{Id: model.NewId(), Name: "target-team"}
+	targetChannel := &model.Channel{Id: model.NewId(), TeamId: targetTeam.Id}
+	config := &model.Config{}
+	config.ServiceSettings.SiteURL = &siteURL
+
+	wpl := buildWranglerPostList(mockGeneratePostList(3, model.NewId(), false))
+	root, reply1, reply2 := wpl.Posts[0], wpl.Posts[1], wpl.Posts[2]
+	reply1.Message = "see https://example.com/old-team/pl/" + root.Id + " and https://example.com/old-team/pl/" + reply2.Id
+	reply2.Message = "as said in https://example.com/old-team/pl/" + reply1.Id
+
+	newIDs := make(map[string]string)
+	var created []*model.Post
+	api := &plugintest.API{}
+	api.On("GetTeam", targetTeam.Id).Return(targetTeam, nil)
+	api.On("GetConfig").Return(config)
+	api.On("GetReactions", mock.AnythingOfType("string")).Return(nil, nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+		post = post.Clone()
+		post.Id = model.NewId()
+		created = append(created, post)
+		return post
+	}, nil)
+	api.On("UpdatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+		return post
+	}, nil)
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	_, postIDs, err := plugin.copyWranglerPostlist(wpl, targetChannel, nil)
+	require.NoError(t, err)
+	require.Len(t, created, 3)
+	for _, pair := range postIDs {
+		newIDs[pair.OriginalID] = pair.NewID
+	}
+
+	assert.Equal(t, "see https://example.com/target-team/pl/"+newIDs[root.Id]+" and https://example.com/old-team/pl/"+reply2.Id, created[1].Message)
+	assert.Equal(t, "as said in https://example.com/target-team/pl/"+newIDs[reply1.Id], created[2].Message)
+
+	// The link to the later reply is rewritten once that reply exists.
+	api.AssertNumberOfCalls(t, "UpdatePost", 1)
+	api.AssertCalled(t, "UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.Id == newIDs[reply1.Id] &&
+			post.Message == "see https://example.com/target-team/pl/"+newIDs[root.Id]+" and https://example.com/target-team/pl/"+newIDs[reply2.Id]
+	}))
+}
@@ -21,6 +21,10 @@ type Plugin struct {
 	// configuration is the active plugin configuration. Consult getConfiguration and
 	// setConfiguration for usage.
 	configuration *configuration
+
+	// digestStop stops the loop sending notification digests when the plugin
+	// deactivates.
+	digestStop chan struct{}
 }
 
 // BuildHash is the full git hash of the build.
@@ -64,5 +68,23 @@ func (p *Plugin) OnActivate() error {
 		return errors.Wrap(err, "failed to register wrangler command")
 	}
 
+	err = p.interruptUnfinishedWranglerJobs()
+	if err != nil {
+		p.API.LogError("Unable to mark interrupted Wrangler jobs", "error", err.Error())
+	}
+
+	p.digestStop = make(chan struct{})
+	go p.runDigestLoop(p.digestStop)
+
+	return nil
+}
+
+// OnDeactivate stops the background work of the plugin.
+func (p *Plugin) OnDeactivate() error {
+	if p.digestStop != nil {
+		close(p.digestStop)
+		p.digestStop = nil
+	}
+
 	return nil
 }
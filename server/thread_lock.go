@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	kvThreadLockPrefix = "lock_thread_"
+
+	// threadLockExpiry limits how long a lock survives if the plugin instance
+	// holding it goes away. Background jobs refresh their locks as they make
+	// progress.
+	threadLockExpiry = 10 * time.Minute
+)
+
+// threadLockHolder is the value stored for a locked thread.
+type threadLockHolder struct {
+	UserID    string `json:"user_id"`
+	Operation string `json:"operation"`
+	CreateAt  int64  `json:"create_at"`
+}
+
+// threadLock is a cluster-wide lock on one or more threads held for the
+// duration of a Wrangler operation.
+type threadLock struct {
+	keys  []string
+	value []byte
+}
+
+func threadLockKey(rootID string) string {
+	return kvThreadLockPrefix + rootID
+}
+
+// acquireThreadLock locks the threads with the given root post IDs. If any of
+// the threads is already locked, no lock is taken and a command response
+// naming the holder of the existing lock is returned instead.
+func (p *Plugin) acquireThreadLock(operation string, extra *model.CommandArgs, rootIDs ...string) (*threadLock, *model.CommandResponse, error) {
+	value, err := json.Marshal(threadLockHolder{
+		UserID:    extra.UserId,
+		Operation: operation,
+		CreateAt:  model.GetMillis(),
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to marshal thread lock")
+	}
+
+	lock := &threadLock{value: value}
+	for _, rootID := range rootIDs {
+		key := threadLockKey(rootID)
+		acquired, appErr := p.API.KVSetWithOptions(key, value, model.PluginKVSetOptions{
+			Atomic:          true,
+			OldValue:        nil,
+			ExpireInSeconds: int64(threadLockExpiry / time.Second),
+		})
+		if appErr != nil {
+			p.releaseThreadLock(lock)
+			return nil, nil, errors.Wrapf(appErr, "unable to lock thread %s", rootID)
+		}
+		if !acquired {
+			p.releaseThreadLock(lock)
+			return nil, getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, p.getThreadLockedMessage(key)), nil
+		}
+		lock.keys = append(lock.keys, key)
+	}
+
+	return lock, nil, nil
+}
+
+// refreshThreadLock extends the expiry of a held lock.
+func (p *Plugin) refreshThreadLock(lock *threadLock) {
+	for _, key := range lock.keys {
+		_, appErr := p.API.KVSetWithOptions(key, lock.value, model.PluginKVSetOptions{
+			Atomic:          true,
+			OldValue:        lock.value,
+			ExpireInSeconds: int64(threadLockExpiry / time.Second),
+		})
+		if appErr != nil {
+			p.API.LogError("Unable to refresh thread lock",
+				"error", appErr.Error(),
+				"key", key,
+			)
+		}
+	}
+}
+
+// releaseThreadLock releases a held lock. Locks that have since expired and
+// been taken by another operation are left alone.
+func (p *Plugin) releaseThreadLock(lock *threadLock) {
+	if lock == nil {
+		return
+	}
+
+	for _, key := range lock.keys {
+		_, appErr := p.API.KVCompareAndDelete(key, lock.value)
+		if appErr != nil {
+			p.API.LogError("Unable to release thread lock",
+				"error", appErr.Error(),
+				"key", key,
+			)
+		}
+	}
+	lock.keys = nil
+}
+
+// getThreadLockedMessage returns a message telling the executor who is
+// currently wrangling a locked thread.
+func (p *Plugin) getThreadLockedMessage(key string) string {
+	var holder threadLockHolder
+	found, err := p.kvGetJSON(key, &holder)
+	if err != nil || !found {
+		return "Error: another Wrangler operation is in progress on this thread; please try again once it has finished"
+	}
+
+	return fmt.Sprintf("Error: @%s is currently running a %s operation on this thread (started %s); please try again once it has finished",
+		p.getUsernameOrID(holder.UserID),
+		holder.Operation,
+		time.Unix(0, holder.CreateAt*int64(time.Millisecond)).UTC().Format(time.RFC822),
+	)
+}
+
+// getLockedThread reads a thread again once it has been locked. Another
+// operation may have changed or removed the thread between the first read and
+// taking the lock, so commands wrangle the thread returned here.
+func (p *Plugin) getLockedThread(rootID string) (*WranglerPostList, *model.CommandResponse, bool, error) {
+	postList, appErr := p.API.GetPostThread(rootID)
+	if appErr != nil {
+		return nil, getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, getThreadChangedMessage(rootID)), true, nil
+	}
+
+	return buildWranglerPostList(postList), nil, false, nil
+}
+
+func getThreadChangedMessage(postID string) string {
+	return fmt.Sprintf("Error: the message with ID %s is no longer available; it may have been wrangled by another operation", postID)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThreadLock(t *testing.T) {
+	user := &model.User{
+		Id:       model.NewId(),
+		Username: "user",
+	}
+	otherUser := &model.User{
+		Id:       model.NewId(),
+		Username: "other-user",
+	}
+
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	api.On("GetUser", user.Id).Return(user, nil)
+	api.On("GetUser", otherUser.Id).Return(otherUser, nil)
+	api.On("GetUser", mock.AnythingOfType("string")).Return(nil, &model.AppError{})
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	rootID := model.NewId()
+	otherRootID := model.NewId()
+
+	t.Run("lock and release", func(t *testing.T) {
+		lock, response, err := plugin.acquireThreadLock(operationTypeMove, &model.CommandArgs{UserId: user.Id}, rootID)
+		require.NoError(t, err)
+		require.Nil(t, response)
+		require.NotNil(t, lock)
+		assert.Contains(t, store, threadLockKey(rootID))
+
+		plugin.releaseThreadLock(lock)
+		assert.NotContains(t, store, threadLockKey(rootID))
+	})
+
+	t.Run("second lock names the holder", func(t *testing.T) {
+		lock, response, err := plugin.acquireThreadLock(operationTypeMove, &model.CommandArgs{UserId: user.Id}, rootID)
+		require.NoError(t, err)
+		require.Nil(t, response)
+		defer plugin.releaseThreadLock(lock)
+
+		otherLock, response, err := plugin.acquireThreadLock(operationTypeCopy, &model.CommandArgs{UserId: otherUser.Id}, rootID)
+		require.NoError(t, err)
+		assert.Nil(t, otherLock)
+		require.NotNil(t, response)
+		assert.Contains(t, response.Text, "@user is currently running a move operation on this thread")
+	})
+
+	t.Run("partially acquired locks are released", func(t *testing.T) {
+		lock, response, err := plugin.acquireThreadLock(operationTypeMove, &model.CommandArgs{UserId: user.Id}, otherRootID)
+		require.NoError(t, err)
+		require.Nil(t, response)
+		defer plugin.releaseThreadLock(lock)
+
+		_, response, err = plugin.acquireThreadLock(operationTypeMerge, &model.CommandArgs{UserId: otherUser.Id}, rootID, otherRootID)
+		require.NoError(t, err)
+		require.NotNil(t, response)
+		assert.NotContains(t, store, threadLockKey(rootID))
+	})
+
+	t.Run("release does not remove a lock taken by another operation", func(t *testing.T) {
+		lock, response, err := plugin.acquireThreadLock(operationTypeMove, &model.CommandArgs{UserId: user.Id}, rootID)
+		require.NoError(t, err)
+		require.Nil(t, response)
+
+		// Simulate the lock expiring and being taken by another operation.
+		store[threadLockKey(rootID)] = []byte(`{"user_id":"` + otherUser.Id + `","operation":"copy"}`)
+
+		plugin.releaseThreadLock(lock)
+		assert.Contains(t, store, threadLockKey(rootID))
+	})
+}
+
+func TestGetLockedThread(t *testing.T) {
+	postList := mockGeneratePostList(3, model.NewId(), false)
+	rootID := postList.Order[0]
+	removedRootID := model.NewId()
+
+	api := &plugintest.API{}
+	api.On("GetPostThread", rootID).Return(postList, nil)
+	api.On("GetPostThread", removedRootID).Return(nil, &model.AppError{})
+
+	var plugin Plugin
+	plugin.SetAPI(api)
+
+	t.Run("thread still exists", func(t *testing.T) {
+		wpl, response, userErr, err := plugin.getLockedThread(rootID)
+		require.NoError(t, err)
+		assert.False(t, userErr)
+		assert.Nil(t, response)
+		assert.Equal(t, 3, wpl.NumPosts())
+	})
+
+	t.Run("thread was removed by another operation", func(t *testing.T) {
+		wpl, response, userErr, err := plugin.getLockedThread(removedRootID)
+		require.NoError(t, err)
+		assert.True(t, userErr)
+		assert.Nil(t, wpl)
+		assert.Contains(t, response.Text, "may have been wrangled by another operation")
+	})
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+const (
+	kvThreadRedirectPrefix = "redirect_"
+
+	// maxThreadRedirectHops bounds how many times a thread that was moved
+	// repeatedly is followed to its latest location.
+	maxThreadRedirectHops = 5
+	// threadRedirectExpiry is how long the new location of a thread is
+	// remembered, so that redirects don't pile up in the KV store.
+	threadRedirectExpiry = 90 * 24 * time.Hour
+)
+
+// threadRedirect records the new location of the root of a thread that was
+// moved or merged by Wrangler.
+type threadRedirect struct {
+	NewRootID string `json:"new_root_id"`
+	ChannelID string `json:"channel_id"`
+	CreateAt  int64  `json:"create_at"`
+}
+
+func threadRedirectKey(rootID string) string {
+	return kvThreadRedirectPrefix + rootID
+}
+
+// recordThreadRedirectOrLog remembers the new location of a thread root and
+// logs any error as the operation itself has already completed at this point.
+func (p *Plugin) recordThreadRedirectOrLog(originalRootID, newRootID, channelID string) {
+	err := p.recordThreadRedirect(originalRootID, newRootID, channelID)
+	if err != nil {
+		p.API.LogError("Unable to record thread redirect",
+			"error", err.Error(),
+			"original_root_id", originalRootID,
+			"new_root_id", newRootID,
+		)
+	}
+}
+
+// recordThreadRedirect remembers the new location of a thread root for
+// threadRedirectExpiry.
+func (p *Plugin) recordThreadRedirect(originalRootID, newRootID, channelID string) error {
+	value, err := json.Marshal(&threadRedirect{
+		NewRootID: newRootID,
+		ChannelID: channelID,
+		CreateAt:  model.GetMillis(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal thread redirect")
+	}
+
+	_, appErr := p.API.KVSetWithOptions(threadRedirectKey(originalRootID), value, model.PluginKVSetOptions{
+		ExpireInSeconds: int64(threadRedirectExpiry / time.Second),
+	})
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to store thread redirect")
+	}
+
+	return nil
+}
+
+// getThreadRedirect returns the latest location of a thread root that was
+// moved or merged, or nil if the root was never moved.
+func (p *Plugin) getThreadRedirect(rootID string) (*threadRedirect, error) {
+	var redirect *threadRedirect
+	for i := 0; i < maxThreadRedirectHops; i++ {
+		var next threadRedirect
+		found, err := p.kvGetJSON(threadRedirectKey(rootID), &next)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to get thread redirect")
+		}
+		if !found {
+			break
+		}
+		redirect = &next
+		rootID = next.NewRootID
+	}
+
+	return redirect, nil
+}
+
+// MessageWillBePosted rewrites permalinks to the roots of threads that were
+// moved or merged to point at their new location. Replies to the old thread
+// can't be redirected as the server rejects replies to a deleted root before
+// this hook runs. Only posts containing permalinks are looked up, so other
+// posts don't wait on the KV store.
+func (p *Plugin) MessageWillBePosted(c *plugin.Context, post *model.Post) (*model.Post, string) {
+	if post.UserId == p.BotUserID || post.IsSystemMessage() || post.GetProp(wrangerProp) != nil {
+		return nil, ""
+	}
+
+	rewritten, err := p.redirectPermalinks(post)
+	if err != nil {
+		p.API.LogError("Unable to rewrite permalinks to moved threads", "error", err.Error())
+	}
+	if !rewritten {
+		return nil, ""
+	}
+
+	p.API.SendEphemeralPost(post.UserId, &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: post.ChannelId,
+		Message:   "Links in your message to threads that were moved by Wrangler now point to their new location.",
+	})
+
+	return post, ""
+}
+
+// redirectPermalinks rewrites the permalinks of a post to the roots of threads
+// that were moved or merged. The returned bool is true if any permalink was
+// rewritten.
+func (p *Plugin) redirectPermalinks(post *model.Post) (bool, error) {
+	matches := permalinkIDRegex.FindAllStringSubmatch(post.Message, -1)
+	if len(matches) == 0 {
+		return false, nil
+	}
+
+	siteURL := *p.API.GetConfig().ServiceSettings.SiteURL
+	message := post.Message
+	seen := make(map[string]bool)
+	for _, match := range matches {
+		postID := match[1]
+		if seen[postID] {
+			continue
+		}
+		seen[postID] = true
+
+		redirect, err := p.getThreadRedirect(postID)
+		if err != nil {
+			return false, err
+		}
+		if redirect == nil {
+			continue
+		}
+
+		var teamName string
+		channel, appErr := p.API.GetChannel(redirect.ChannelID)
+		if appErr != nil {
+			return false, errors.Wrapf(appErr, "unable to get channel %s", redirect.ChannelID)
+		}
+		if len(channel.TeamId) != 0 {
+			team, appErr := p.API.GetTeam(channel.TeamId)
+			if appErr != nil {
+				return false, errors.Wrapf(appErr, "unable to get team with ID %s", channel.TeamId)
+			}
+			teamName = team.Name
+		}
+
+		rewriter := newPermalinkRewriter(siteURL, teamName)
+		rewriter.addPost(postID, redirect.NewRootID)
+		message = rewriter.rewrite(message)
+	}
+
+	if message == post.Message {
+		return false, nil
+	}
+	post.Message = message
+
+	return true, nil
+}
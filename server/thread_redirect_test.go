@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageWillBePosted(t *testing.T) {
+	siteURL := "https://example.com"
+	config := &model.Config{}
+	config.ServiceSettings.SiteURL = &siteURL
+
+	team := &model.Team{Id: model.NewId(), Name: "new-team"}
+	originalChannel := &model.Channel{Id: model.NewId(), TeamId: model.NewId()}
+	newChannel := &model.Channel{Id: model.NewId(), TeamId: team.Id}
+	userID := model.NewId()
+	botID := model.NewId()
+
+	movedRootID := model.NewId()
+	intermediateRootID := model.NewId()
+	newRootID := model.NewId()
+
+	setupPlugin := func() (*Plugin, *plugintest.API) {
+		api := &plugintest.API{}
+		mockKVStore(api)
+		api.On("GetConfig").Return(config)
+		api.On("GetChannel", originalChannel.Id).Return(originalChannel, nil)
+		api.On("GetChannel", newChannel.Id).Return(newChannel, nil)
+		api.On("GetTeam", team.Id).Return(team, nil)
+		api.On("SendEphemeralPost", userID, mock.AnythingOfType("*model.Post")).Return(nil)
+
+		plugin := &Plugin{BotUserID: botID}
+		plugin.SetAPI(api)
+
+		// The thread was moved twice.
+		plugin.recordThreadRedirectOrLog(movedRootID, intermediateRootID, originalChannel.Id)
+		plugin.recordThreadRedirectOrLog(intermediateRootID, newRootID, newChannel.Id)
+
+		return plugin, api
+	}
+
+	t.Run("redirects expire", func(t *testing.T) {
+		_, api := setupPlugin()
+
+		api.AssertCalled(t, "KVSetWithOptions", threadRedirectKey(movedRootID), mock.Anything, model.PluginKVSetOptions{
+			ExpireInSeconds: int64(threadRedirectExpiry / time.Second),
+		})
+	})
+
+	t.Run("reply to moved thread is left alone", func(t *testing.T) {
+		plugin, api := setupPlugin()
+
+		post, rejection := plugin.MessageWillBePosted(nil, &model.Post{
+			UserId:    userID,
+			ChannelId: originalChannel.Id,
+			RootId:    movedRootID,
+			ParentId:  movedRootID,
+			Message:   "reply",
+		})
+		assert.Nil(t, post)
+		assert.Empty(t, rejection)
+		api.AssertNotCalled(t, "KVGet", mock.Anything)
+		api.AssertNotCalled(t, "SendEphemeralPost", mock.Anything, mock.Anything)
+	})
+
+	t.Run("permalink to moved thread", func(t *testing.T) {
+		plugin, api := setupPlugin()
+		otherID := model.NewId()
+
+		post, rejection := plugin.MessageWillBePosted(nil, &model.Post{
+			UserId:    userID,
+			ChannelId: originalChannel.Id,
+			Message:   "see https://example.com/old-team/pl/" + movedRootID + " and https://example.com/old-team/pl/" + otherID,
+		})
+		require.NotNil(t, post)
+		assert.Empty(t, rejection)
+		assert.Equal(t, "see https://example.com/new-team/pl/"+newRootID+" and https://example.com/old-team/pl/"+otherID, post.Message)
+		assert.Equal(t, originalChannel.Id, post.ChannelId)
+		api.AssertCalled(t, "SendEphemeralPost", userID, mock.MatchedBy(func(post *model.Post) bool {
+			return post.Message == "Links in your message to threads that were moved by Wrangler now point to their new location."
+		}))
+	})
+
+	t.Run("posts by wrangler are ignored", func(t *testing.T) {
+		plugin, _ := setupPlugin()
+
+		post, rejection := plugin.MessageWillBePosted(nil, &model.Post{
+			UserId:    botID,
+			ChannelId: originalChannel.Id,
+			RootId:    movedRootID,
+		})
+		assert.Nil(t, post)
+		assert.Empty(t, rejection)
+
+		copied := &model.Post{UserId: userID, ChannelId: originalChannel.Id, RootId: movedRootID}
+		copied.AddProp(wrangerProp, true)
+		post, rejection = plugin.MessageWillBePosted(nil, copied)
+		assert.Nil(t, post)
+		assert.Empty(t, rejection)
+	})
+}
@@ -24,6 +24,17 @@ func makeBotDM(base, newPostLink, executor string) string {
 	return message
 }
 
+// makeTemplateMessage replaces the {name} variables of a configured message
+// with their values.
+func makeTemplateMessage(base string, variables map[string]string) string {
+	message := cleanMessageJSON(base)
+	for name, value := range variables {
+		message = strings.Replace(message, "{"+name+"}", value, -1)
+	}
+
+	return message
+}
+
 func cleanPost(post *model.Post) {
 	post.Id = ""
 	post.CreateAt = 0
@@ -73,6 +84,46 @@ func trimMessage(message string, trimLength int) string {
 	return fmt.Sprintf("%s...", message[:trimLength])
 }
 
+// formatBytes returns a human-readable representation of a byte count.
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
 func prettyPrintJSON(in string) string {
 	var out bytes.Buffer
 	err := json.Indent(&out, []byte(in), "", "\t")
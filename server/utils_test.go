@@ -74,6 +74,15 @@ func TestMakeBotDM(t *testing.T) {
 	}
 }
 
+func TestMakeTemplateMessage(t *testing.T) {
+	message := makeTemplateMessage(" Thread by @{author} moved to ~{channel}:\\n{postLink} by @{author}", map[string]string{
+		"author":   "alice",
+		"channel":  "incidents",
+		"postLink": "https://example.com/team/pl/id",
+	})
+	assert.Equal(t, "Thread by @alice moved to ~incidents:\nhttps://example.com/team/pl/id by @alice", message)
+}
+
 func TestCleanInputID(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -162,3 +171,45 @@ func TestCleanPost(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes    int64
+		expected string
+	}{
+		{bytes: 0, expected: "0 B"},
+		{bytes: 1023, expected: "1023 B"},
+		{bytes: 1024, expected: "1.0 KiB"},
+		{bytes: 1536, expected: "1.5 KiB"},
+		{bytes: 5 * 1024 * 1024, expected: "5.0 MiB"},
+		{bytes: 3 * 1024 * 1024 * 1024, expected: "3.0 GiB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatBytes(tt.bytes))
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected int
+	}{
+		{a: "", b: "", expected: 0},
+		{a: "move", b: "move", expected: 0},
+		{a: "", b: "move", expected: 4},
+		{a: "mvoe", b: "move", expected: 2},
+		{a: "mov", b: "move", expected: 1},
+		{a: "channel", b: "channels", expected: 1},
+		{a: "kitten", b: "sitting", expected: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"-"+tt.b, func(t *testing.T) {
+			assert.Equal(t, tt.expected, editDistance(tt.a, tt.b))
+		})
+	}
+}
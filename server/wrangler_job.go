@@ -0,0 +1,333 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	jobTypeMove  = "move"
+	jobTypeCopy  = "copy"
+	jobTypeMerge = "merge"
+
+	jobStatusQueued    = "queued"
+	jobStatusRunning   = "running"
+	jobStatusSucceeded = "succeeded"
+	jobStatusFailed    = "failed"
+	jobStatusCancelled = "cancelled"
+	// jobStatusInterrupted is set on jobs that were still queued or running
+	// when the plugin instance running them stopped.
+	jobStatusInterrupted = "interrupted"
+
+	kvJobPrefix         = "job_"
+	kvJobCancelPrefix   = "job_cancel_"
+	kvJobIndexKey       = "job_index"
+	maxJobHistory       = 100
+	jobProgressInterval = 25
+)
+
+var errJobCancelled = errors.New("the job was cancelled")
+
+// WranglerJob is a record of a move, copy or merge that is run in the
+// background.
+type WranglerJob struct {
+	ID             string `json:"id"`
+	Type           string `json:"type"`
+	UserID         string `json:"user_id"`
+	ChannelID      string `json:"channel_id"`
+	RootPostID     string `json:"root_post_id"`
+	Status         string `json:"status"`
+	TotalPosts     int    `json:"total_posts"`
+	CompletedPosts int    `json:"completed_posts"`
+	CreateAt       int64  `json:"create_at"`
+	UpdateAt       int64  `json:"update_at"`
+	Result         string `json:"result,omitempty"`
+}
+
+// IsFinished returns if the job is no longer queued or running.
+func (j *WranglerJob) IsFinished() bool {
+	return j.Status != jobStatusQueued && j.Status != jobStatusRunning
+}
+
+// jobRunFunc runs the work of a job. The provided progressFunc must be passed
+// on to the copy or merge of the post list, and the details of the wrangled
+// messages are added to the provided audit record of the job.
+type jobRunFunc func(progress progressFunc, audit *AuditRecord) (*model.CommandResponse, bool, error)
+
+func jobKey(id string) string {
+	return kvJobPrefix + id
+}
+
+func jobCancelKey(id string) string {
+	return kvJobCancelPrefix + id
+}
+
+// shouldRunAsJob returns if a post list is large enough that it should be
+// wrangled in the background.
+func (p *Plugin) shouldRunAsJob(wpl *WranglerPostList) bool {
+	threshold := p.getConfiguration().BackgroundJobThresholdInt()
+	if threshold == 0 {
+		return false
+	}
+
+	return int64(wpl.NumPosts())+wpl.FileAttachmentCount >= int64(threshold)
+}
+
+func (p *Plugin) updateWranglerJob(job *WranglerJob) error {
+	job.UpdateAt = model.GetMillis()
+
+	return p.kvSetJSON(jobKey(job.ID), job)
+}
+
+// getWranglerJob returns the job with the given ID or nil if no such job
+// exists.
+func (p *Plugin) getWranglerJob(id string) (*WranglerJob, error) {
+	var job WranglerJob
+	found, err := p.kvGetJSON(jobKey(id), &job)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+
+	return &job, nil
+}
+
+// getRecentWranglerJobs returns recent jobs, newest first.
+func (p *Plugin) getRecentWranglerJobs() ([]*WranglerJob, error) {
+	ids, err := p.kvGetIndex(kvJobIndexKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get job history")
+	}
+
+	var jobs []*WranglerJob
+	for _, id := range ids {
+		job, err := p.getWranglerJob(id)
+		if err != nil {
+			return nil, err
+		}
+		if job == nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// interruptUnfinishedWranglerJobs marks the jobs that were left queued or
+// running by a plugin instance that stopped as interrupted. Without clustering
+// no job can still be running when the plugin activates. With clustering,
+// another instance may be running a job, so only jobs that made no progress
+// for longer than the thread lock expiry are marked; such jobs can't still be
+// running as they refresh their lock whenever they make progress.
+func (p *Plugin) interruptUnfinishedWranglerJobs() error {
+	clusterEnabled := p.API.GetConfig().ClusterSettings.Enable
+	staleBefore := model.GetMillis() - threadLockExpiry.Milliseconds()
+
+	jobs, err := p.getRecentWranglerJobs()
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if job.IsFinished() {
+			continue
+		}
+		if clusterEnabled != nil && *clusterEnabled && job.UpdateAt > staleBefore {
+			continue
+		}
+
+		job.Status = jobStatusInterrupted
+		job.Result = "the plugin stopped before the job finished; messages that were already created may remain in the target location"
+		p.updateWranglerJobOrLog(job)
+		p.postWranglerJobMessage(job, fmt.Sprintf("Job %s\n%s", job.Status, job.Result))
+	}
+
+	return nil
+}
+
+// requestWranglerJobCancel flags a job to be cancelled. The flag is stored
+// separately from the job so that it isn't overwritten by progress updates
+// from the plugin instance running the job.
+func (p *Plugin) requestWranglerJobCancel(id string) error {
+	appErr := p.API.KVSet(jobCancelKey(id), []byte("true"))
+	if appErr != nil {
+		return errors.Wrap(appErr, "unable to request job cancellation")
+	}
+
+	return nil
+}
+
+func (p *Plugin) isWranglerJobCancelRequested(id string) (bool, error) {
+	data, appErr := p.API.KVGet(jobCancelKey(id))
+	if appErr != nil {
+		return false, errors.Wrap(appErr, "unable to check job cancellation")
+	}
+
+	return data != nil, nil
+}
+
+// startWranglerJob stores a new job and runs it in the background. The thread
+// lock is held until the job finishes. The job ID is added to the audit record
+// of the command and a copy of the record is stored once the job finishes. The
+// returned command response tells the executor that the job has started and
+// the result holds the job ID.
+func (p *Plugin) startWranglerJob(job *WranglerJob, lock *threadLock, audit *AuditRecord, run jobRunFunc) (*model.CommandResponse, *wranglerResult, bool, error) {
+	job.ID = model.NewId()
+	job.Status = jobStatusQueued
+	job.CreateAt = model.GetMillis()
+
+	err := p.updateWranglerJob(job)
+	if err != nil {
+		p.releaseThreadLock(lock)
+		return nil, nil, false, errors.Wrap(err, "unable to store job")
+	}
+	err = p.kvAddToIndex(kvJobIndexKey, job.ID, maxJobHistory)
+	if err != nil {
+		p.releaseThreadLock(lock)
+		return nil, nil, false, errors.Wrap(err, "unable to add job to history")
+	}
+
+	audit.JobID = job.ID
+	jobAudit := *audit
+	jobAudit.Command += " job"
+
+	go p.runWranglerJob(job, lock, &jobAudit, run)
+
+	msg := fmt.Sprintf("Job %s started: %d message(s) will be %s in the background. Progress updates will be sent to you by the Wrangler bot.\nTo cancel this run %s\n",
+		job.ID,
+		job.TotalPosts,
+		jobActionPastTense(job.Type),
+		inlineCode("/wrangler jobs cancel "+job.ID),
+	)
+
+	return getCommandResponse(model.COMMAND_RESPONSE_TYPE_EPHEMERAL, msg), &wranglerResult{JobID: job.ID}, false, nil
+}
+
+// runWranglerJob runs a job, keeps its record up to date and reports the
+// progress and the outcome to the executor.
+func (p *Plugin) runWranglerJob(job *WranglerJob, lock *threadLock, audit *AuditRecord, run jobRunFunc) {
+	defer p.releaseThreadLock(lock)
+	defer func() {
+		if r := recover(); r != nil {
+			p.finishWranglerJob(job, audit, jobStatusFailed, fmt.Sprintf("the job stopped unexpectedly: %v", r))
+		}
+	}()
+
+	job.Status = jobStatusRunning
+	p.updateWranglerJobOrLog(job)
+
+	var cancelled bool
+	progress := func(created int) error {
+		if created%jobProgressInterval != 0 || created == job.TotalPosts {
+			return nil
+		}
+
+		cancelRequested, err := p.isWranglerJobCancelRequested(job.ID)
+		if err != nil {
+			p.API.LogError("Unable to check if Wrangler job was cancelled",
+				"error", err.Error(),
+				"job_id", job.ID,
+			)
+		}
+		if cancelRequested {
+			cancelled = true
+			return errJobCancelled
+		}
+
+		if lock != nil {
+			p.refreshThreadLock(lock)
+		}
+		job.CompletedPosts = created
+		p.updateWranglerJobOrLog(job)
+		p.postWranglerJobMessage(job, fmt.Sprintf("%d of %d message(s) %s", created, job.TotalPosts, jobActionPastTense(job.Type)))
+
+		return nil
+	}
+
+	resp, userErr, err := run(progress, audit)
+	switch {
+	case cancelled:
+		p.finishWranglerJob(job, audit, jobStatusCancelled, responseText(resp))
+	case err != nil:
+		p.API.LogError("Wrangler job failed",
+			"error", err.Error(),
+			"job_id", job.ID,
+		)
+		p.finishWranglerJob(job, audit, jobStatusFailed, err.Error())
+	case userErr:
+		p.finishWranglerJob(job, audit, jobStatusFailed, responseText(resp))
+	default:
+		if resp != nil && resp.ResponseType == model.COMMAND_RESPONSE_TYPE_IN_CHANNEL {
+			// The summary is meant for the whole channel, but there is no
+			// command left to respond to so the bot posts it instead.
+			err = p.PostToChannelByIDAsBot(job.ChannelID, resp.Text)
+			if err != nil {
+				p.API.LogError("Unable to post Wrangler job summary",
+					"error", err.Error(),
+					"job_id", job.ID,
+				)
+			}
+		}
+		job.CompletedPosts = job.TotalPosts
+		p.finishWranglerJob(job, audit, jobStatusSucceeded, responseText(resp))
+	}
+}
+
+func (p *Plugin) finishWranglerJob(job *WranglerJob, audit *AuditRecord, status, result string) {
+	job.Status = status
+	job.Result = result
+	p.updateWranglerJobOrLog(job)
+
+	audit.Result = status
+	if status != jobStatusSucceeded {
+		audit.Error = result
+	}
+	p.recordAuditOrLog(audit)
+
+	p.postWranglerJobMessage(job, fmt.Sprintf("Job %s\n%s", status, result))
+}
+
+func (p *Plugin) updateWranglerJobOrLog(job *WranglerJob) {
+	err := p.updateWranglerJob(job)
+	if err != nil {
+		p.API.LogError("Unable to update Wrangler job",
+			"error", err.Error(),
+			"job_id", job.ID,
+		)
+	}
+}
+
+// postWranglerJobMessage sends a job update to the executor as a bot DM.
+func (p *Plugin) postWranglerJobMessage(job *WranglerJob, message string) {
+	err := p.PostBotDM(job.UserID, fmt.Sprintf("Wrangler %s job %s: %s", job.Type, job.ID, message))
+	if err != nil {
+		p.API.LogError("Unable to send Wrangler job update",
+			"error", err.Error(),
+			"job_id", job.ID,
+		)
+	}
+}
+
+func jobActionPastTense(jobType string) string {
+	switch jobType {
+	case jobTypeCopy:
+		return "copied"
+	case jobTypeMerge:
+		return "merged"
+	default:
+		return "moved"
+	}
+}
+
+func responseText(resp *model.CommandResponse) string {
+	if resp == nil {
+		return ""
+	}
+
+	return resp.Text
+}
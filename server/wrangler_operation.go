@@ -0,0 +1,141 @@
+package main
+
+import (
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	operationTypeMove   = "move"
+	operationTypeMerge  = "merge"
+	operationTypeAttach = "attach"
+	operationTypeSplit  = "split"
+	operationTypeDetach = "detach"
+
+	// Copies are not recorded as they leave the original messages in place,
+	// but the type is used when locking the thread being copied.
+	operationTypeCopy = "copy"
+	// operationTypeUndo is used when locking the threads of an operation that
+	// is being reverted.
+	operationTypeUndo = "undo"
+
+	kvOperationPrefix   = "operation_"
+	kvOperationIndexKey = "operation_index"
+	maxOperationHistory = 100
+)
+
+// postIDPair pairs the ID of an original post with the ID of the post that was
+// created from it by Wrangler.
+type postIDPair struct {
+	OriginalID string `json:"original_id"`
+	NewID      string `json:"new_id"`
+}
+
+// WranglerOperation is a record of a completed Wrangler operation containing
+// everything needed to revert it.
+type WranglerOperation struct {
+	ID                string       `json:"id"`
+	Type              string       `json:"type"`
+	UserID            string       `json:"user_id"`
+	CreateAt          int64        `json:"create_at"`
+	OriginalChannelID string       `json:"original_channel_id"`
+	TargetChannelID   string       `json:"target_channel_id"`
+	OriginalRootID    string       `json:"original_root_id"`
+	NewRootID         string       `json:"new_root_id"`
+	Posts             []postIDPair `json:"posts"`
+	TombstonePostID   string       `json:"tombstone_post_id,omitempty"`
+	RevertedAt        int64        `json:"reverted_at,omitempty"`
+	RevertedBy        string       `json:"reverted_by,omitempty"`
+}
+
+// IsReverted returns if the operation has already been reverted.
+func (o *WranglerOperation) IsReverted() bool {
+	return o.RevertedAt != 0
+}
+
+func operationKey(id string) string {
+	return kvOperationPrefix + id
+}
+
+// recordWranglerOperation assigns an ID to a new operation and stores it.
+func (p *Plugin) recordWranglerOperation(operation *WranglerOperation) error {
+	operation.ID = model.NewId()
+	operation.CreateAt = model.GetMillis()
+
+	err := p.kvSetJSON(operationKey(operation.ID), operation)
+	if err != nil {
+		return errors.Wrap(err, "unable to store operation")
+	}
+
+	err = p.kvAddToIndex(kvOperationIndexKey, operation.ID, maxOperationHistory)
+	if err != nil {
+		return errors.Wrap(err, "unable to add operation to history")
+	}
+
+	return nil
+}
+
+// recordWranglerOperationOrLog records an operation and logs any error as the
+// operation itself has already completed at this point. The operation ID is
+// cleared if it could not be recorded.
+func (p *Plugin) recordWranglerOperationOrLog(operation *WranglerOperation) {
+	err := p.recordWranglerOperation(operation)
+	if err != nil {
+		p.API.LogError("Unable to record Wrangler operation",
+			"error", err.Error(),
+			"user_id", operation.UserID,
+		)
+		operation.ID = ""
+	}
+}
+
+func (p *Plugin) updateWranglerOperation(operation *WranglerOperation) error {
+	return p.kvSetJSON(operationKey(operation.ID), operation)
+}
+
+// getWranglerOperation returns the operation with the given ID or nil if no
+// such operation exists.
+func (p *Plugin) getWranglerOperation(id string) (*WranglerOperation, error) {
+	var operation WranglerOperation
+	found, err := p.kvGetJSON(operationKey(id), &operation)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+
+	return &operation, nil
+}
+
+// getRecentWranglerOperations returns recent operations, newest first.
+func (p *Plugin) getRecentWranglerOperations() ([]*WranglerOperation, error) {
+	ids, err := p.kvGetIndex(kvOperationIndexKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get operation history")
+	}
+
+	var operations []*WranglerOperation
+	for _, id := range ids {
+		operation, err := p.getWranglerOperation(id)
+		if err != nil {
+			return nil, err
+		}
+		if operation == nil {
+			continue
+		}
+		operations = append(operations, operation)
+	}
+
+	return operations, nil
+}
+
+// operationSuffix returns the text appended to command responses telling the
+// executor how to revert the operation.
+func operationSuffix(operation *WranglerOperation) string {
+	if len(operation.ID) == 0 {
+		return ""
+	}
+
+	return "To revert this run " + inlineCode("/wrangler undo "+operation.ID) + "\n"
+}
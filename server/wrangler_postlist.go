@@ -33,13 +33,57 @@ func (wpl *WranglerPostList) ContainsFileAttachments() bool {
 	return wpl.FileAttachmentCount != 0
 }
 
-func buildWranglerPostList(postList *model.PostList) *WranglerPostList {
-	wpl := &WranglerPostList{}
+// ContainsInternalPermalinks returns if a message of the post list links to
+// another post of the list.
+func (wpl *WranglerPostList) ContainsInternalPermalinks() bool {
+	postIDs := make(map[string]bool)
+	for _, post := range wpl.Posts {
+		postIDs[post.Id] = true
+	}
+
+	for _, post := range wpl.Posts {
+		for _, match := range permalinkIDRegex.FindAllStringSubmatch(post.Message, -1) {
+			if match[1] != post.Id && postIDs[match[1]] {
+				return true
+			}
+		}
+	}
 
+	return false
+}
+
+// SplitAt returns a new post list containing the post with the given ID and
+// every post after it. The returned post list is empty if no post with the ID
+// is found.
+func (wpl *WranglerPostList) SplitAt(postID string) *WranglerPostList {
+	for i, post := range wpl.Posts {
+		if post.Id == postID {
+			return newWranglerPostList(wpl.Posts[i:])
+		}
+	}
+
+	return &WranglerPostList{}
+}
+
+func buildWranglerPostList(postList *model.PostList) *WranglerPostList {
 	postList.UniqueOrder()
 	postList.SortByCreateAt()
 	posts := postList.ToSlice()
 
+	// The post list is sorted newest first so reverse it.
+	sorted := make([]*model.Post, len(posts))
+	for i := range posts {
+		sorted[i] = posts[len(posts)-i-1]
+	}
+
+	return newWranglerPostList(sorted)
+}
+
+// newWranglerPostList creates a post list with metadata from posts that are
+// already sorted oldest first.
+func newWranglerPostList(posts []*model.Post) *WranglerPostList {
+	wpl := &WranglerPostList{}
+
 	if len(posts) == 0 {
 		// Something was sorted wrong or an empty PostList was provided.
 		return wpl
@@ -48,9 +92,7 @@ func buildWranglerPostList(postList *model.PostList) *WranglerPostList {
 	// A separate ID key map to ensure no duplicates.
 	idKeys := make(map[string]bool)
 
-	for i := range posts {
-		p := posts[len(posts)-i-1]
-
+	for _, p := range posts {
 		// Add UserID to metadata if it's new.
 		if _, ok := idKeys[p.UserId]; !ok {
 			idKeys[p.UserId] = true
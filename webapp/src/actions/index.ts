@@ -3,9 +3,8 @@ import {GlobalState} from 'mattermost-redux/types/store';
 import {Channel} from 'mattermost-redux/types/channels';
 import {Team} from 'mattermost-redux/types/teams';
 import {getTeam, getTeamMemberships} from 'mattermost-redux/selectors/entities/teams';
-import {Client4} from 'mattermost-redux/client';
 
-import {RECEIVED_PLUGIN_SETTINGS} from '../types/wrangler';
+import {RECEIVED_PLUGIN_SETTINGS, EligibleChannel} from '../types/wrangler';
 import {OPEN_MOVE_THREAD_MODAL, CLOSE_MOVE_THREAD_MODAL} from '../types/ui';
 import {INITIALIZE_ATTACH_POST, FINALIZE_ATTACH_POST, RichPost} from '../types/attach';
 import {INITIALIZE_MERGE_THREAD, FINALIZE_MERGE_THREAD} from '../types/merge';
@@ -127,6 +126,17 @@ export function getSettings(): ActionFunc {
     };
 }
 
+export function getThreadPreview(postID: string): ActionFunc {
+    return async () => {
+        const {data: preview, error} = await Client.getThreadPreview(postID);
+        if (error) {
+            return {error};
+        }
+
+        return {data: preview};
+    };
+}
+
 export function getMyTeams(): Function {
     return async (_: DispatchFunc, getState: GetStateFunc) => {
         const myTeamMemberships = getTeamMemberships(getState());
@@ -148,19 +158,16 @@ export function getMyTeams(): Function {
     };
 }
 
-export function getChannelsForTeam(teamID: string): Function {
+// getChannelsForTeam returns the channels in the team that the thread of the
+// given post can be moved or copied to.
+export function getChannelsForTeam(postID: string, teamID: string): Function {
     return async () => {
-        let allMyChannelsInTeam = Array<Channel>();
-        allMyChannelsInTeam = await Client4.getMyChannels(teamID);
-
-        const myOpenAndPrivateChannelsInTeam = Array<Channel>();
-        allMyChannelsInTeam.forEach((channel) => {
-            if (channel.type === 'O' || channel.type === 'P') {
-                myOpenAndPrivateChannelsInTeam.push(channel);
-            }
-        });
+        const {data: channels, error} = await Client.getEligibleChannels(postID, teamID);
+        if (error) {
+            return Array<EligibleChannel>();
+        }
 
-        return myOpenAndPrivateChannelsInTeam;
+        return channels as Array<EligibleChannel>;
     };
 }
 
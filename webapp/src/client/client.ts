@@ -13,6 +13,26 @@ export default class Client {
         );
     }
 
+    getThreadPreview = async (postID: string) => {
+        return this.doFetch(
+            `${this.getAPIV1BaseRoute()}/thread/${postID}/preview`,
+            {method: 'get'},
+        );
+    }
+
+    getEligibleChannels = async (sourcePostID: string, teamID: string) => {
+        const query = new URLSearchParams({
+            source_post_id: sourcePostID,
+            team_id: teamID,
+            per_page: '200',
+        });
+
+        return this.doFetch(
+            `${this.getAPIV1BaseRoute()}/channels/eligible?${query.toString()}`,
+            {method: 'get'},
+        );
+    }
+
     // Helpers
 
     getAPIV1BaseRoute() {
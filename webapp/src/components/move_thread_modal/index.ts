@@ -5,7 +5,7 @@ import {GlobalState} from 'mattermost-redux/types/store';
 import {getPost as getPostSel} from 'mattermost-redux/selectors/entities/posts';
 
 import {isMoveModalVisable, getMoveThreadPostID} from '../../selectors';
-import {closeMoveThreadModal, moveThread, copyThread, getMyTeams, getChannelsForTeam} from '../../actions';
+import {closeMoveThreadModal, moveThread, copyThread, getMyTeams, getChannelsForTeam, getThreadPreview} from '../../actions';
 
 import MoveThreadModal from './move_thread_modal';
 
@@ -37,6 +37,7 @@ function mapDispatchToProps(dispatch: Dispatch<Action>) {
         closeMoveThreadModal,
         getMyTeams,
         getChannelsForTeam,
+        getThreadPreview,
         moveThread,
         copyThread,
     }, dispatch);
@@ -4,9 +4,9 @@ import {Modal} from 'react-bootstrap';
 import Form from 'react-bootstrap/Form';
 
 import {Team} from 'mattermost-redux/types/teams';
-import {Channel} from 'mattermost-redux/types/channels';
 
 import {MessageActionType, MessageActionTypeMove, MessageActionTypeCopy} from '../../types/actions';
+import {EligibleChannel, ThreadPreview} from '../../types/wrangler';
 
 import '../style.scss';
 
@@ -19,12 +19,13 @@ interface Props {
     copyThread: Function;
     getMyTeams: Function;
     getChannelsForTeam: Function;
+    getThreadPreview: Function;
     closeMoveThreadModal: Function;
 }
 
 type State = {
     allTeams: Array<Team>;
-    channelsInTeam: Array<Channel>;
+    channelsInTeam: Array<EligibleChannel>;
     selectedTeam: string;
     selectedChannel: string;
     moveThreadButtonText: string;
@@ -33,6 +34,7 @@ type State = {
     moveShowRootMessage: boolean,
     moveSilent: boolean,
     processing: boolean,
+    preview: ThreadPreview | null,
 }
 
 export default class MoveThreadModal extends React.PureComponent<Props, State> {
@@ -41,7 +43,7 @@ export default class MoveThreadModal extends React.PureComponent<Props, State> {
 
         this.state = {
             allTeams: Array<Team>(),
-            channelsInTeam: Array<Channel>(),
+            channelsInTeam: Array<EligibleChannel>(),
             selectedTeam: '',
             selectedChannel: '',
             moveThreadButtonText: this.getMoveButtonText('Move'),
@@ -50,17 +52,33 @@ export default class MoveThreadModal extends React.PureComponent<Props, State> {
             moveShowRootMessage: true,
             moveSilent: false,
             processing: false,
+            preview: null,
         };
     }
 
     componentDidMount() {
         this.loadTeams();
+        this.loadPreview();
     }
 
     componentDidUpdate(prevProps: Props, prevState: State) {
         if (prevProps.threadCount !== this.props.threadCount || prevState.actionWord !== this.state.actionWord) {
             this.setButtonState();
         }
+        if (prevProps.postID !== this.props.postID || (this.props.visible && !prevProps.visible)) {
+            this.loadTeams();
+            this.loadPreview();
+        }
+    }
+
+    private loadPreview = async () => {
+        if (this.props.postID === '') {
+            this.setState({preview: null});
+            return;
+        }
+
+        const {data} = await this.props.getThreadPreview(this.props.postID);
+        this.setState({preview: data || null});
     }
 
     private loadTeams = async () => {
@@ -68,11 +86,11 @@ export default class MoveThreadModal extends React.PureComponent<Props, State> {
 
         let firstTeamID = '';
         let firstChannelID = '';
-        let channels = Array<Channel>();
-        if (myTeams.length > 0) {
+        let channels = Array<EligibleChannel>();
+        if (myTeams.length > 0 && this.props.postID !== '') {
             const firstTeam = myTeams[0];
             firstTeamID = firstTeam.id;
-            channels = await this.props.getChannelsForTeam(firstTeamID);
+            channels = await this.props.getChannelsForTeam(this.props.postID, firstTeamID);
             if (channels.length > 0) {
                 const firstChannel = channels[0];
                 firstChannelID = firstChannel.id;
@@ -89,7 +107,7 @@ export default class MoveThreadModal extends React.PureComponent<Props, State> {
 
     private handleTeamSelectChange = async (event: React.ChangeEvent<HTMLInputElement> | React.ChangeEvent<HTMLSelectElement>) => {
         const teamID = event.target.value;
-        const channels = await this.props.getChannelsForTeam(teamID);
+        const channels = await this.props.getChannelsForTeam(this.props.postID, teamID);
         let firstChannelID = '';
         if (channels.length > 0) {
             const firstChannel = channels[0];
@@ -142,6 +160,25 @@ export default class MoveThreadModal extends React.PureComponent<Props, State> {
         return actionWord + ' Thread';
     }
 
+    private getPreviewSummary() {
+        const preview = this.state.preview;
+        if (!preview) {
+            return null;
+        }
+
+        const participants = preview.participants.map((participant) => '@' + participant.username).join(', ');
+        const earliest = new Date(preview.earliest_post_at).toLocaleString();
+        const latest = new Date(preview.latest_post_at).toLocaleString();
+
+        return (
+            <div>
+                <p>{preview.message_count + ' message(s) from ' + earliest + ' to ' + latest}</p>
+                <p>{'Participants: ' + participants}</p>
+                <p>{'File attachments: ' + preview.attachment_count + ' (' + formatBytes(preview.attachment_bytes) + ')'}</p>
+            </div>
+        );
+    }
+
     private handleButtonOnMouseEnter() {
         this.setState({moveThreadButtonText: 'Yeehaw!'});
     }
@@ -296,6 +333,9 @@ export default class MoveThreadModal extends React.PureComponent<Props, State> {
                             />
                             {moveCheckboxes}
                         </Form.Group>
+                        <Form.Group>
+                            {this.getPreviewSummary()}
+                        </Form.Group>
                     </Form>
                     <p><span className='pull-right'>{moveMessage}</span></p>
                 </Modal.Body>
@@ -323,3 +363,15 @@ export default class MoveThreadModal extends React.PureComponent<Props, State> {
         );
     }
 }
+
+function formatBytes(bytes: number) {
+    const units = ['B', 'KB', 'MB', 'GB'];
+    let value = bytes;
+    let unit = 0;
+    while (value >= 1024 && unit < units.length - 1) {
+        value /= 1024;
+        unit++;
+    }
+
+    return value.toFixed(unit === 0 ? 0 : 1) + ' ' + units[unit];
+}
@@ -9,6 +9,34 @@ export type Settings = {
 
 export type Channels = Array<Channel>
 
+export type EligibleChannel = {
+    id: string;
+    name: string;
+    display_name: string;
+    type: string;
+    team_id: string;
+    team_name: string;
+    team_display_name: string;
+}
+
+export type ThreadPreviewParticipant = {
+    user_id: string;
+    username: string;
+}
+
+export type ThreadPreview = {
+    post_id: string;
+    channel_id: string;
+    root_message: string;
+    message_count: number;
+    participants: Array<ThreadPreviewParticipant>;
+    attachment_count: number;
+    attachment_bytes: number;
+    reaction_count: number;
+    earliest_post_at: number;
+    latest_post_at: number;
+}
+
 export const RECEIVED_PLUGIN_SETTINGS = `${id}_plugin_settings`;
 
 export type ReceivedPluginSettingsAction = {